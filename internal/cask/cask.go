@@ -8,10 +8,11 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/homegrew/grew/internal/deprecation"
+	"github.com/homegrew/grew/internal/schema"
 	"github.com/homegrew/grew/internal/validation"
-
-	"gopkg.in/yaml.v3"
 )
 
 type SourceSpec struct {
@@ -21,15 +22,18 @@ type SourceSpec struct {
 
 // Cask represents a macOS application package definition.
 type Cask struct {
-	Name        string            `yaml:"name"`
-	Version     string            `yaml:"version"`
-	Description string            `yaml:"description"`
-	Homepage    string            `yaml:"homepage"`
-	License     string            `yaml:"license"`
-	URL         map[string]string `yaml:"url"`
-	SHA256      map[string]string `yaml:"sha256"`
-	Artifacts   Artifacts         `yaml:"artifacts"`
-	Source      SourceSpec        `yaml:"source"`
+	Name        string              `yaml:"name"`
+	Version     string              `yaml:"version"`
+	Description string              `yaml:"description"`
+	Homepage    string              `yaml:"homepage"`
+	License     string              `yaml:"license"`
+	URL         map[string]string   `yaml:"url"`
+	SHA256      map[string]string   `yaml:"sha256"`
+	Artifacts   Artifacts           `yaml:"artifacts"`
+	Source      SourceSpec          `yaml:"source"`
+	Caveats     string              `yaml:"caveats"`
+	Deprecated  *deprecation.Notice `yaml:"deprecated"`
+	Disabled    *deprecation.Notice `yaml:"disabled"`
 }
 
 // Artifacts describes what to install from the downloaded archive.
@@ -100,13 +104,36 @@ func (c *Cask) Validate() error {
 			return fmt.Errorf("cask %q: app artifact %q must end with .app", c.Name, app)
 		}
 	}
+	if err := c.Deprecated.Validate("deprecated"); err != nil {
+		return fmt.Errorf("cask %q: %w", c.Name, err)
+	}
+	if err := c.Disabled.Validate("disabled"); err != nil {
+		return fmt.Errorf("cask %q: %w", c.Name, err)
+	}
 	return nil
 }
 
+// IsDeprecated reports whether c is deprecated now (and not disabled).
+func (c *Cask) IsDeprecated() bool {
+	return c.Deprecated.Active(time.Now()) && !c.IsDisabled()
+}
+
+// IsDisabled reports whether c is disabled now.
+func (c *Cask) IsDisabled() bool {
+	return c.Disabled.Active(time.Now())
+}
+
+// JSONSchema describes the cask file format for editors.
+func JSONSchema() map[string]any {
+	return schema.Generate(Cask{}, "grew cask", "name", "version", "url", "artifacts")
+}
+
+// Parse parses and validates a cask. Unknown keys are rejected; decoding
+// errors are *schema.Error values carrying the line and column.
 func Parse(data []byte) (*Cask, error) {
 	var c Cask
-	if err := yaml.Unmarshal(data, &c); err != nil {
-		return nil, fmt.Errorf("parse cask YAML: %w", err)
+	if err := schema.Decode(data, &c); err != nil {
+		return nil, err
 	}
 	if err := c.Validate(); err != nil {
 		return nil, err
@@ -135,27 +162,39 @@ func (l *Loader) LoadByName(name string) (*Cask, error) {
 }
 
 func (l *Loader) LoadAll() ([]*Cask, error) {
+	casks, problems, err := l.ReadAll()
+	for _, p := range problems {
+		l.debugf("failed to parse cask %v\n", p)
+	}
+	return casks, err
+}
+
+// ReadAll loads every cask and returns the ones that parse, plus one error
+// per file that does not, each naming the file (and the line and column
+// when known).
+func (l *Loader) ReadAll() ([]*Cask, []error, error) {
 	caskDir := filepath.Join(l.TapDir, "cask")
 	entries, err := os.ReadDir(caskDir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, nil
+			return nil, nil, nil
 		}
-		return nil, fmt.Errorf("read cask tap: %w", err)
+		return nil, nil, fmt.Errorf("read cask tap: %w", err)
 	}
 	var casks []*Cask
+	var problems []error
 	for _, e := range entries {
 		if e.IsDir() || !strings.HasSuffix(e.Name(), ".yaml") {
 			continue
 		}
 		c, err := l.loadFromFile(filepath.Join(caskDir, e.Name()))
 		if err != nil {
-			l.debugf("failed to parse cask %s: %v\n", e.Name(), err)
+			problems = append(problems, err)
 			continue
 		}
 		casks = append(casks, c)
 	}
-	return casks, nil
+	return casks, problems, nil
 }
 
 func (l *Loader) loadFromFile(path string) (*Cask, error) {
@@ -163,7 +202,11 @@ func (l *Loader) loadFromFile(path string) (*Cask, error) {
 	if err != nil {
 		return nil, err
 	}
-	return Parse(data)
+	c, err := Parse(data)
+	if err != nil {
+		return nil, schema.InFile(err, path)
+	}
+	return c, nil
 }
 
 // Caskroom manages installed cask metadata.
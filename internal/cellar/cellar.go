@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 
 	"github.com/homegrew/grew/internal/fsutil"
+	"github.com/homegrew/grew/internal/snapshot"
 	"github.com/homegrew/grew/internal/validation"
+	"github.com/homegrew/grew/internal/vercmp"
 )
 
 type InstalledPackage struct {
@@ -18,6 +21,10 @@ type InstalledPackage struct {
 
 type Cellar struct {
 	Path string
+	// Opt is the directory of opt/<formula> links (config.Paths.Opt),
+	// which mark the active version of a formula. When empty, the
+	// highest installed version is always the active one.
+	Opt string
 }
 
 func (c *Cellar) Install(name, version, stagingDir string) error {
@@ -49,6 +56,32 @@ func (c *Cellar) Uninstall(name string) error {
 	return os.RemoveAll(kegDir)
 }
 
+// Rename moves the kegs of a formula installed as oldName to newName, for
+// formulas renamed in the tap. A version already installed under newName
+// is kept and the old keg of that version is dropped.
+func (c *Cellar) Rename(oldName, newName string) error {
+	if !validation.IsValidName(oldName) || !validation.IsValidName(newName) {
+		return fmt.Errorf("invalid formula name")
+	}
+	versions, err := c.InstalledVersions(oldName)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(c.Path, newName), 0755); err != nil {
+		return fmt.Errorf("create cellar dir: %w", err)
+	}
+	for _, v := range versions {
+		dest := c.KegPath(newName, v)
+		if _, err := os.Stat(dest); err == nil {
+			continue
+		}
+		if err := os.Rename(c.KegPath(oldName, v), dest); err != nil {
+			return fmt.Errorf("move %s %s: %w", oldName, v, err)
+		}
+	}
+	return os.RemoveAll(filepath.Join(c.Path, oldName))
+}
+
 func (c *Cellar) IsInstalled(name string) bool {
 	if !validation.IsValidName(name) {
 		return false
@@ -58,24 +91,47 @@ func (c *Cellar) IsInstalled(name string) bool {
 	return err == nil && info.IsDir()
 }
 
+// InstalledVersion returns the active version of a formula: the keg its
+// opt link points to when several versions are installed side by side,
+// otherwise the highest installed version.
 func (c *Cellar) InstalledVersion(name string) (string, error) {
-	if !validation.IsValidName(name) {
-		return "", fmt.Errorf("invalid formula name: %q", name)
+	versions, err := c.InstalledVersions(name)
+	if err != nil {
+		return "", err
 	}
-	kegDir := filepath.Join(c.Path, name)
-	entries, err := os.ReadDir(kegDir)
+	if len(versions) == 0 {
+		return "", fmt.Errorf("formula %q has no installed version", name)
+	}
+	if ver, ok := c.LinkedVersion(name); ok && slices.Contains(versions, ver) {
+		return ver, nil
+	}
+	return versions[len(versions)-1], nil
+}
+
+// LinkedVersion returns the version of the keg the formula's opt link
+// points to.
+func (c *Cellar) LinkedVersion(name string) (string, bool) {
+	if c.Opt == "" {
+		return "", false
+	}
+	optLink := filepath.Join(c.Opt, name)
+	target, err := os.Readlink(optLink)
 	if err != nil {
-		return "", fmt.Errorf("formula %q is not installed", name)
+		return "", false
 	}
-	for _, e := range entries {
-		if e.IsDir() {
-			return e.Name(), nil
-		}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(optLink), target)
+	}
+	dir, ver := filepath.Split(filepath.Clean(target))
+	if filepath.Clean(dir) != filepath.Join(c.Path, name) {
+		return "", false
 	}
-	return "", fmt.Errorf("formula %q has no installed version", name)
+	return ver, true
 }
 
-// InstalledVersions returns all version directories for a formula, sorted ascending.
+// InstalledVersions returns all version directories for a formula, sorted
+// ascending by version order (so 1.10 comes after 1.9, and 1.9_1, the
+// first revision of 1.9, between them).
 func (c *Cellar) InstalledVersions(name string) ([]string, error) {
 	if !validation.IsValidName(name) {
 		return nil, fmt.Errorf("invalid formula name: %q", name)
@@ -86,15 +142,31 @@ func (c *Cellar) InstalledVersions(name string) ([]string, error) {
 		return nil, fmt.Errorf("formula %q is not installed", name)
 	}
 	var versions []string
+	parsed := map[string]vercmp.Version{}
 	for _, e := range entries {
 		if e.IsDir() {
 			versions = append(versions, e.Name())
+			parsed[e.Name()] = c.KegVersion(name, e.Name())
 		}
 	}
-	sort.Strings(versions)
+	sort.SliceStable(versions, func(i, j int) bool {
+		return parsed[versions[i]].Compare(parsed[versions[j]]) < 0
+	})
 	return versions, nil
 }
 
+// KegVersion parses the version of an installed keg. The formula revision
+// and version_scheme come from the keg manifest, since a version may
+// itself end in "_<digits>"; kegs without a manifest fall back to reading
+// the revision from the directory name.
+func (c *Cellar) KegVersion(name, version string) vercmp.Version {
+	m, err := snapshot.Load(c.KegPath(name, version))
+	if err != nil {
+		return vercmp.ParsePkg(version)
+	}
+	return vercmp.ParseKeg(version, m.Revision).WithScheme(m.VersionScheme)
+}
+
 func (c *Cellar) KegPath(name, version string) string {
 	// Not validating here since it's just path construction,
 	// but maybe good to return error? No, it's a string return.
@@ -4,6 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/homegrew/grew/internal/snapshot"
+	"github.com/homegrew/grew/internal/vercmp"
 )
 
 func setupTestCellar(t *testing.T) (*Cellar, string) {
@@ -11,7 +14,7 @@ func setupTestCellar(t *testing.T) (*Cellar, string) {
 	tmpDir := t.TempDir()
 	cellarPath := filepath.Join(tmpDir, "Cellar")
 	os.MkdirAll(cellarPath, 0755)
-	return &Cellar{Path: cellarPath}, tmpDir
+	return &Cellar{Path: cellarPath, Opt: filepath.Join(tmpDir, "opt")}, tmpDir
 }
 
 func createStagingDir(t *testing.T, tmpDir string) string {
@@ -92,6 +95,31 @@ func TestUninstall_NotInstalled(t *testing.T) {
 	}
 }
 
+func TestRename(t *testing.T) {
+	cel, tmpDir := setupTestCellar(t)
+	stage := createStagingDir(t, tmpDir)
+	cel.Install("oldpkg", "1.0.0", stage)
+	cel.Install("oldpkg", "1.1.0", stage)
+	cel.Install("newpkg", "1.1.0", stage)
+
+	if err := cel.Rename("oldpkg", "newpkg"); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	if cel.IsInstalled("oldpkg") {
+		t.Error("oldpkg should be gone after rename")
+	}
+	versions, err := cel.InstalledVersions("newpkg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(versions) != 2 || versions[0] != "1.0.0" || versions[1] != "1.1.0" {
+		t.Errorf("versions = %v, want [1.0.0 1.1.0]", versions)
+	}
+	if _, err := os.Stat(filepath.Join(cel.KegPath("newpkg", "1.0.0"), "bin", "mybin")); err != nil {
+		t.Errorf("moved keg is missing its files: %v", err)
+	}
+}
+
 func TestList(t *testing.T) {
 	cel, tmpDir := setupTestCellar(t)
 
@@ -125,3 +153,83 @@ func TestList_Empty(t *testing.T) {
 		t.Errorf("expected 0 packages, got %d", len(packages))
 	}
 }
+
+func TestInstalledVersion_PicksHighest(t *testing.T) {
+	cel, tmpDir := setupTestCellar(t)
+	stage := createStagingDir(t, tmpDir)
+	for _, v := range []string{"1.9", "1.10", "1.9_1", "1.10-rc1"} {
+		if err := cel.Install("mypkg", v, stage); err != nil {
+			t.Fatalf("install %s: %v", v, err)
+		}
+	}
+
+	ver, err := cel.InstalledVersion("mypkg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ver != "1.10" {
+		t.Errorf("version = %q, want %q", ver, "1.10")
+	}
+
+	versions, err := cel.InstalledVersions("mypkg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"1.9", "1.9_1", "1.10-rc1", "1.10"}
+	for i, v := range want {
+		if i >= len(versions) || versions[i] != v {
+			t.Fatalf("versions = %v, want %v", versions, want)
+		}
+	}
+}
+
+func TestInstalledVersion_FollowsOptLink(t *testing.T) {
+	cel, tmpDir := setupTestCellar(t)
+	stage := createStagingDir(t, tmpDir)
+	for _, v := range []string{"1.6", "1.7"} {
+		if err := cel.Install("mypkg", v, stage); err != nil {
+			t.Fatalf("install %s: %v", v, err)
+		}
+	}
+	if _, ok := cel.LinkedVersion("mypkg"); ok {
+		t.Error("LinkedVersion reported a version without an opt link")
+	}
+
+	optDir := filepath.Join(tmpDir, "opt")
+	os.MkdirAll(optDir, 0755)
+	os.Symlink(filepath.Join(cel.Path, "mypkg", "1.6"), filepath.Join(optDir, "mypkg"))
+
+	if ver, ok := cel.LinkedVersion("mypkg"); !ok || ver != "1.6" {
+		t.Errorf("LinkedVersion = %q, %v, want 1.6, true", ver, ok)
+	}
+	ver, err := cel.InstalledVersion("mypkg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ver != "1.6" {
+		t.Errorf("version = %q, want %q", ver, "1.6")
+	}
+}
+
+func TestKegVersion_UnderscoreVersion(t *testing.T) {
+	cel, tmpDir := setupTestCellar(t)
+	stage := createStagingDir(t, tmpDir)
+	for _, v := range []string{"1_84_1", "1_84_0_1"} {
+		if err := cel.Install("boost", v, stage); err != nil {
+			t.Fatalf("install %s: %v", v, err)
+		}
+	}
+	snapshot.Save(&snapshot.Manifest{Name: "boost", Version: "1_84_1"}, cel.KegPath("boost", "1_84_1"))
+	snapshot.Save(&snapshot.Manifest{Name: "boost", Version: "1_84_0_1", Revision: 1}, cel.KegPath("boost", "1_84_0_1"))
+
+	if got := cel.KegVersion("boost", "1_84_1"); got.Revision != 0 || got.Compare(vercmp.Parse("1.84.1")) != 0 {
+		t.Errorf("KegVersion(1_84_1) = %v revision %d, want 1.84.1 revision 0", got, got.Revision)
+	}
+	ver, err := cel.InstalledVersion("boost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ver != "1_84_1" {
+		t.Errorf("version = %q, want 1_84_1", ver)
+	}
+}
@@ -1,15 +1,20 @@
 package cmd
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"net/url"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/homegrew/grew/internal/cask"
+	"github.com/homegrew/grew/internal/cellar"
 	"github.com/homegrew/grew/internal/config"
 	"github.com/homegrew/grew/internal/depgraph"
+	"github.com/homegrew/grew/internal/deprecation"
+	"github.com/homegrew/grew/internal/downloader"
 	"github.com/homegrew/grew/internal/formula"
 	"github.com/homegrew/grew/internal/snapshot"
 	"github.com/homegrew/grew/internal/tap"
@@ -40,6 +45,7 @@ func runAudit(args []string) error {
 	strict := fs.Bool("strict", false, "Treat warnings as errors")
 	isCask := fs.Bool("cask", false, "Audit casks instead of formulas")
 	online := fs.Bool("online", false, "Include checks that require installed packages (snapshot verification)")
+	tapPath := fs.String("tap-path", "", "Also load formulas from this directory; with no formulas named, audit only those")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -55,14 +61,28 @@ func runAudit(args []string) error {
 	if *isCask {
 		return runAuditCasks(paths, targets, *strict)
 	}
-	return runAuditFormulas(paths, targets, *strict, *online)
+	return runAuditFormulas(paths, targets, *tapPath, *strict, *online)
 }
 
-func runAuditFormulas(paths config.Paths, targets []string, strict, online bool) error {
+func runAuditFormulas(paths config.Paths, targets []string, tapPath string, strict, online bool) error {
 	loader := newLoader(paths.Taps)
+	if err := addTapPath(loader, tapPath); err != nil {
+		return err
+	}
 
 	var formulas []*formula.Formula
-	if len(targets) == 0 {
+	if len(targets) == 0 && tapPath != "" {
+		for _, dir := range loader.TapPaths {
+			tapFormulas, problems, err := loader.ReadTap(dir)
+			if err != nil {
+				return err
+			}
+			for _, p := range problems {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", p)
+			}
+			formulas = append(formulas, tapFormulas...)
+		}
+	} else if len(targets) == 0 {
 		var err error
 		formulas, err = loader.LoadAll()
 		if err != nil {
@@ -70,7 +90,7 @@ func runAuditFormulas(paths config.Paths, targets []string, strict, online bool)
 		}
 	} else {
 		for _, name := range targets {
-			f, err := loader.LoadByName(name)
+			f, err := loadFormulaArg(loader, name)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: cannot load formula %s: %v\n", name, err)
 				continue
@@ -169,33 +189,101 @@ func auditFormula(f *formula.Formula, allNames map[string]bool, loader *formula.
 		}
 	}
 
+	// Patches and resources — only used by source builds. File patches are
+	// local, so their checksums can be verified here.
+	hasSource := f.Source.URL != "" || f.SourceURL != ""
+	if (len(f.Patches) > 0 || len(f.Resources) > 0) && !hasSource {
+		r.warnf("patches and resources are only used for source builds, but no source URL is defined")
+	}
+	for i, p := range f.Patches {
+		label := fmt.Sprintf("patches[%d]", i)
+		if err := validation.ValidateSHA256(p.SHA256); err != nil {
+			r.errorf("%s sha256: %v", label, err)
+		}
+		if p.URL != "" {
+			auditURL(r, label, "", p.URL)
+			continue
+		}
+		file, err := f.PatchPath(p)
+		if err != nil {
+			r.errorf("%s: %v", label, err)
+		} else if _, err := os.Stat(file); err != nil {
+			r.errorf("%s: patch file %s not found", label, p.File)
+		} else if err := downloader.VerifySHA256(file, p.SHA256); err != nil {
+			r.errorf("%s: %s: %v", label, p.File, err)
+		}
+	}
+	for _, res := range f.Resources {
+		label := fmt.Sprintf("resources[%s]", res.Name)
+		auditURL(r, label, "", res.URL)
+		if err := validation.ValidateSHA256(res.SHA256); err != nil {
+			r.errorf("%s sha256: %v", label, err)
+		}
+	}
+
+	// Livecheck needs a URL it can fetch.
+	if f.Livecheck != nil {
+		if u, _, err := f.Livecheck.Resolve(f); err != nil {
+			r.errorf("%v", err)
+		} else {
+			auditURL(r, "livecheck", "", u)
+		}
+	}
+
 	// No download URLs at all.
-	if len(f.URL) == 0 && len(f.Bottle) == 0 && f.Source.URL == "" && f.SourceURL == "" {
+	if len(f.URL) == 0 && len(f.Bottle) == 0 && f.Source.URL == "" && f.SourceURL == "" && f.Head == nil {
 		r.errorf("no download URLs defined")
 	}
 
-	// Dependency checks.
-	for _, dep := range f.Dependencies {
-		if !validation.IsValidName(dep) {
-			r.errorf("dependency %q has invalid name", dep)
-		} else if !allNames[dep] {
-			r.warnf("dependency %q not found in tap", dep)
+	// Dependency checks, circular dependencies and version constraints,
+	// on every supported platform so on_* blocks that do not apply here
+	// are checked too. Problems shared by several platforms are reported
+	// once.
+	reported := make(map[string]bool)
+	report := func(msg string) {
+		if !reported[msg] {
+			reported[msg] = true
+			r.errorf("%s", msg)
 		}
 	}
-	for _, dep := range f.BuildDependencies {
-		if !validation.IsValidName(dep) {
-			r.errorf("build_dependency %q has invalid name", dep)
+	missing := make(map[string]bool)
+	for _, platform := range formula.SupportedPlatforms {
+		pf, err := f.ForPlatform(platform)
+		if err != nil {
+			report(err.Error())
+			continue
+		}
+		for _, list := range []struct {
+			field string
+			deps  []formula.Dependency
+		}{
+			{"dependency", pf.Deps()},
+			{"build_dependency", pf.BuildDeps()},
+		} {
+			for _, dep := range list.deps {
+				key := list.field + " " + dep.Name
+				if !allNames[dep.Name] && !missing[key] {
+					missing[key] = true
+					r.warnf("%s %q not found in tap", list.field, dep.Name)
+				}
+			}
 		}
-	}
 
-	// Circular dependency check.
-	resolver := &depgraph.Resolver{Loader: loader}
-	if _, err := resolver.Resolve(f.Name); err != nil {
-		if strings.Contains(err.Error(), "cycle") {
-			r.errorf("circular dependency: %v", err)
-		} else {
-			// Missing dep or load error — already warned above likely.
-			Debugf("resolve %s: %v\n", f.Name, err)
+		resolver := &depgraph.Resolver{Loader: loader, Platform: platform}
+		if _, err := resolver.Resolve(f.Name); err != nil {
+			var cycle *depgraph.CycleError
+			var unsat *depgraph.UnsatisfiableError
+			var conflict *depgraph.ConflictError
+			if errors.As(err, &cycle) {
+				report(fmt.Sprintf("circular dependency: %v", err))
+			} else if errors.As(err, &unsat) {
+				report(err.Error())
+			} else if errors.As(err, &conflict) {
+				report(fmt.Sprintf("dependencies conflict on %s: %v", platform, err))
+			} else {
+				// Missing dep or load error — already warned above likely.
+				Debugf("resolve %s for %s: %v\n", f.Name, platform, err)
+			}
 		}
 	}
 
@@ -208,13 +296,48 @@ func auditFormula(f *formula.Formula, allNames map[string]bool, loader *formula.
 	}
 
 	// Self-dependency.
-	for _, dep := range f.Dependencies {
+	for _, dep := range f.DependencyNames() {
 		if dep == f.Name {
 			r.errorf("formula depends on itself")
 			break
 		}
 	}
 
+	// Conflicts must be declared on both sides so either install order
+	// is caught.
+	for _, other := range f.Conflicts {
+		if !allNames[other] {
+			r.warnf("conflicts_with %q not found in tap", other)
+			continue
+		}
+		o, err := loader.LoadByName(other)
+		if err != nil {
+			continue
+		}
+		if !slices.Contains(o.Conflicts, f.Name) && !o.ReplacesName(f.Name) {
+			r.warnf("conflicts_with %q is not symmetric: %s does not declare conflicts_with %s", other, other, f.Name)
+		}
+	}
+	for _, old := range f.Replaces {
+		if o, err := loader.LoadByName(old); err == nil && o.ReplacesName(f.Name) {
+			r.errorf("%s and %s replace each other", f.Name, old)
+		}
+	}
+
+	// Deprecation: replacements should exist, and installed kegs of
+	// deprecated or disabled formulas are flagged.
+	for _, d := range []*deprecation.Notice{f.Deprecated, f.Disabled} {
+		if d != nil && d.Replacement != "" && !allNames[d.Replacement] {
+			r.warnf("replacement %q not found in tap", d.Replacement)
+		}
+	}
+	if status, d := formulaStatus(f); d != nil {
+		cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
+		if cel.IsInstalled(f.Name) {
+			r.warnf("installed formula is %s: %s", status, d.Message(f.Name, status))
+		}
+	}
+
 	// Online checks (installed package verification).
 	if online {
 		auditFormulaInstalled(r, f, paths)
@@ -224,7 +347,7 @@ func auditFormula(f *formula.Formula, allNames map[string]bool, loader *formula.
 }
 
 func auditFormulaInstalled(r *auditResult, f *formula.Formula, paths config.Paths) {
-	kegPath := fmt.Sprintf("%s/%s/%s", paths.Cellar, f.Name, f.Version)
+	kegPath := fmt.Sprintf("%s/%s/%s", paths.Cellar, f.Name, f.PkgVersion())
 	if !snapshot.Exists(kegPath) {
 		return
 	}
@@ -280,7 +403,7 @@ func runAuditCasks(paths config.Paths, targets []string, strict bool) error {
 
 	var totalWarnings, totalErrors int
 	for _, c := range casks {
-		r := auditCask(c)
+		r := auditCask(c, paths)
 		totalWarnings += len(r.Warnings)
 		totalErrors += len(r.Errors)
 		printAuditResult(r)
@@ -289,7 +412,7 @@ func runAuditCasks(paths config.Paths, targets []string, strict bool) error {
 	return auditSummary(totalWarnings, totalErrors, strict)
 }
 
-func auditCask(c *cask.Cask) *auditResult {
+func auditCask(c *cask.Cask, paths config.Paths) *auditResult {
 	r := &auditResult{Name: c.Name}
 
 	// Metadata completeness.
@@ -343,6 +466,14 @@ func auditCask(c *cask.Cask) *auditResult {
 		}
 	}
 
+	// Installed casks that are deprecated or disabled.
+	if status, d := caskStatus(c); d != nil {
+		cr := &cask.Caskroom{Path: paths.Caskroom}
+		if cr.IsInstalled(c.Name) {
+			r.warnf("installed cask is %s: %s", status, d.Message(c.Name, status))
+		}
+	}
+
 	return r
 }
 
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/homegrew/grew/internal/formula"
+)
+
+func TestAuditFormula_DownloadURLs(t *testing.T) {
+	paths := setupTestPrefix(t, nil)
+	loader := newLoader(paths.Taps)
+
+	headOnly, err := formula.Parse([]byte("name: tool\nversion: \"1.0\"\nhead:\n  url: https://example.com/tool.git\nbuild:\n  system: make\n"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	tests := []struct {
+		name    string
+		f       *formula.Formula
+		wantErr bool
+	}{
+		{"head only", headOnly, false},
+		{"no sources", &formula.Formula{Name: "tool", Version: "1.0"}, true},
+	}
+	for _, tt := range tests {
+		r := auditFormula(tt.f, map[string]bool{"tool": true}, loader, paths, false)
+		got := false
+		for _, e := range r.Errors {
+			if strings.Contains(e, "no download URLs") {
+				got = true
+			}
+		}
+		if got != tt.wantErr {
+			t.Errorf("%s: errors = %v, want no download URLs error: %v", tt.name, r.Errors, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/homegrew/grew/internal/config"
+	"github.com/homegrew/grew/internal/downloader"
+	"github.com/homegrew/grew/internal/formula"
+	"github.com/homegrew/grew/internal/signing"
+	"github.com/homegrew/grew/internal/tap"
+	"github.com/homegrew/grew/internal/validation"
+)
+
+// runBumpFormula moves a formula to a new version: every download URL is
+// rewritten, fetched and hashed, optionally re-signed, and the formula
+// file is edited in place.
+func runBumpFormula(args []string) error {
+	fs := flag.NewFlagSet("bump-formula", flag.ContinueOnError)
+	version := fs.String("version", "", "New version")
+	urlTemplate := fs.String("url-template", "", "URL template for platform downloads ({name}, {version}, {platform})")
+	sourceTemplate := fs.String("source-url-template", "", "URL template for the source download ({name}, {version})")
+	keyArg := fs.String("key", "", "Ed25519 private key (hex seed or OpenSSH key path) to re-sign with")
+	dryRun := fs.Bool("dry-run", false, "Show the changes without writing them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// Accept flags after the formula name too.
+	remaining := fs.Args()
+	if len(remaining) > 1 {
+		if err := fs.Parse(remaining[1:]); err != nil {
+			return err
+		}
+		remaining = append(remaining[:1], fs.Args()...)
+	}
+	if len(remaining) != 1 || *version == "" {
+		return fmt.Errorf("usage: grew bump-formula <formula> --version <version> [--url-template <template>] [--key <key>] [--dry-run]")
+	}
+	if !validation.IsValidVersion(*version) {
+		return fmt.Errorf("invalid version %q", *version)
+	}
+
+	var privKey ed25519.PrivateKey
+	if *keyArg != "" {
+		var err error
+		privKey, err = signing.DecodePrivateKey(*keyArg)
+		if err != nil {
+			return fmt.Errorf("invalid private key: %w", err)
+		}
+	}
+
+	paths := config.Default()
+	if err := paths.Init(); err != nil {
+		return err
+	}
+	tapMgr := &tap.Manager{TapsDir: paths.Taps}
+	if err := tapMgr.InitCore(); err != nil {
+		return fmt.Errorf("init core tap: %w", err)
+	}
+
+	loader := newLoader(paths.Taps)
+	f, err := loader.LoadByName(remaining[0])
+	if err != nil {
+		return fmt.Errorf("formula not found: %s", remaining[0])
+	}
+	if f.Version == *version {
+		return fmt.Errorf("%s is already at version %s", f.Name, f.Version)
+	}
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return err
+	}
+
+	arts := f.Artifacts()
+	if len(arts) == 0 {
+		return fmt.Errorf("formula %q has no downloads to bump", f.Name)
+	}
+	fmt.Printf("==> Bumping %s %s -> %s\n", f.Name, f.Version, *version)
+
+	dl := &downloader.Downloader{TmpDir: paths.Tmp}
+	hashes := make(map[string]string)
+	dropped := false
+	for i, a := range arts {
+		tmpl := *urlTemplate
+		if a.Platform == "" {
+			tmpl = *sourceTemplate
+		}
+		newURL := strings.ReplaceAll(a.URL, f.Version, *version)
+		if tmpl != "" {
+			newURL = formula.ExpandURLTemplate(tmpl, f.Name, *version, a.Platform)
+		} else if newURL == a.URL {
+			return fmt.Errorf("%s: %s does not contain version %s; pass a URL template", a.Label(), a.URL, f.Version)
+		}
+		if !strings.HasPrefix(newURL, "https://") {
+			return fmt.Errorf("%s: refusing insecure URL %s", a.Label(), newURL)
+		}
+
+		sha, ok := hashes[newURL]
+		if !ok {
+			filename := fmt.Sprintf("%s-%s-bump-%d%s", f.Name, *version, i, urlExt(newURL))
+			file, err := dl.Download(newURL, filename)
+			if err != nil {
+				return fmt.Errorf("%s: %w", a.Label(), err)
+			}
+			sha, err = downloader.FileSHA256(file)
+			os.Remove(file)
+			if err != nil {
+				return err
+			}
+			hashes[newURL] = sha
+		}
+		Logf("    %s: %s\n", a.Label(), sha)
+
+		if a.Signature != "" && privKey == nil {
+			dropped = true
+		}
+		a.URL, a.SHA256, a.Signature = newURL, sha, ""
+		if privKey != nil && a.Field != "source_url" {
+			a.Signature = signing.Sign(privKey, sha)
+		}
+		arts[i] = a
+	}
+
+	out, err := formula.Bump(data, *version, arts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Path, err)
+	}
+	fmt.Print(unifiedDiff(f.Path, string(data), string(out)))
+
+	if *dryRun {
+		fmt.Println("==> Dry run: formula not written")
+		return nil
+	}
+	if err := os.WriteFile(f.Path, out, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", f.Path, err)
+	}
+	fmt.Printf("==> Updated %s\n", f.Path)
+	if dropped {
+		fmt.Printf("==> Warning: old signatures were removed; re-sign with --key or grew sign\n")
+	}
+	return nil
+}
+
+// unifiedDiff returns a unified diff of two texts with three lines of
+// context, or "" if they are equal.
+func unifiedDiff(path, a, b string) string {
+	if a == b {
+		return ""
+	}
+	al := splitLines(a)
+	bl := splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// al[i:] and bl[j:].
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type op struct {
+		kind   byte
+		line   string
+		ai, bi int // lines of a and b before this one
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(al) || j < len(bl) {
+		switch {
+		case i < len(al) && j < len(bl) && al[i] == bl[j]:
+			ops = append(ops, op{' ', al[i], i, j})
+			i, j = i+1, j+1
+		case j == len(bl) || (i < len(al) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', al[i], i, j})
+			i++
+		default:
+			ops = append(ops, op{'+', bl[j], i, j})
+			j++
+		}
+	}
+
+	const context = 3
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", path, path)
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		// Extend the hunk while the next change is within 2*context lines.
+		end := start
+		for k := start; k < len(ops) && k <= end+2*context; k++ {
+			if ops[k].kind != ' ' {
+				end = k
+			}
+		}
+		lo, hi := max(0, start-context), min(len(ops), end+context+1)
+		aLen, bLen := 0, 0
+		for _, o := range ops[lo:hi] {
+			if o.kind != '+' {
+				aLen++
+			}
+			if o.kind != '-' {
+				bLen++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", ops[lo].ai+1, aLen, ops[lo].bi+1, bLen)
+		for _, o := range ops[lo:hi] {
+			sb.WriteByte(o.kind)
+			sb.WriteString(o.line)
+			if !strings.HasSuffix(o.line, "\n") {
+				sb.WriteString("\n")
+			}
+		}
+		start = hi
+	}
+	return sb.String()
+}
+
+// splitLines splits s after each newline, without the empty string that
+// follows a final newline.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -29,7 +29,7 @@ func initCaskTap(paths config.Paths) error {
 	return tapMgr.InitCask()
 }
 
-func caskInstall(name string) error {
+func caskInstall(name string, force bool) error {
 	paths := config.Default()
 	if err := paths.Init(); err != nil {
 		return err
@@ -50,9 +50,17 @@ func caskInstall(name string) error {
 		return nil
 	}
 
+	status, d := caskStatus(c)
+	if status == "disabled" && !force {
+		return fmt.Errorf("%s\nUse --force to install it anyway", d.Message(c.Name, status))
+	}
+
 	defer TimeOp(fmt.Sprintf("install cask %s %s", c.Name, c.Version))()
 	Debugf("platform: %s\n", formula.PlatformKey())
 	fmt.Printf("==> Installing cask %s %s\n", c.Name, c.Version)
+	if d != nil {
+		fmt.Printf("==> Warning: %s\n", d.Message(c.Name, status))
+	}
 
 	dlURL, err := c.GetURL()
 	if err != nil {
@@ -128,6 +136,7 @@ func caskInstall(name string) error {
 	os.Remove(localFile)
 
 	fmt.Printf("==> %s %s installed\n", c.Name, c.Version)
+	printCaveats(c.Name, c.Caveats)
 	return nil
 }
 
@@ -206,6 +215,9 @@ func caskInfo(name string) error {
 	fmt.Printf("%s: %s %s (cask)\n", c.Name, c.Description, c.Version)
 	fmt.Printf("Homepage: %s\n", c.Homepage)
 	fmt.Printf("License:  %s\n", c.License)
+	if status, d := caskStatus(c); d != nil {
+		fmt.Printf("Warning: %s\n", d.Message(c.Name, status))
+	}
 
 	if cr.IsInstalled(c.Name) {
 		ver, _ := cr.InstalledVersion(c.Name)
@@ -226,6 +238,7 @@ func caskInfo(name string) error {
 		platforms = append(platforms, k)
 	}
 	fmt.Printf("Platforms: %s\n", strings.Join(platforms, ", "))
+	printCaveats(c.Name, c.Caveats)
 
 	return nil
 }
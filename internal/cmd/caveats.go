@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/homegrew/grew/internal/cask"
+	"github.com/homegrew/grew/internal/deprecation"
+	"github.com/homegrew/grew/internal/formula"
+)
+
+// deprecationStatus returns "disabled", "deprecated" or "" together with
+// the matching block.
+func deprecationStatus(isDeprecated, isDisabled bool, deprecated, disabled *deprecation.Notice) (string, *deprecation.Notice) {
+	switch {
+	case isDisabled:
+		return "disabled", disabled
+	case isDeprecated:
+		return "deprecated", deprecated
+	}
+	return "", nil
+}
+
+// formulaStatus is deprecationStatus for a formula.
+func formulaStatus(f *formula.Formula) (string, *deprecation.Notice) {
+	return deprecationStatus(f.IsDeprecated(), f.IsDisabled(), f.Deprecated, f.Disabled)
+}
+
+// caskStatus is deprecationStatus for a cask.
+func caskStatus(c *cask.Cask) (string, *deprecation.Notice) {
+	return deprecationStatus(c.IsDeprecated(), c.IsDisabled(), c.Deprecated, c.Disabled)
+}
+
+// warnDeprecated prints a warning if f is deprecated or disabled.
+func warnDeprecated(f *formula.Formula) {
+	if status, d := formulaStatus(f); d != nil {
+		fmt.Printf("==> Warning: %s\n", d.Message(f.Name, status))
+	}
+}
+
+// printCaveats prints the caveats block, if any, after an install.
+func printCaveats(name, caveats string) {
+	caveats = strings.TrimSpace(caveats)
+	if caveats == "" {
+		return
+	}
+	fmt.Printf("==> Caveats for %s\n%s\n", name, caveats)
+}
@@ -21,7 +21,7 @@ func runCleanup(args []string) error {
 	targets := fs.Args()
 
 	paths := config.Default()
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 
 	var totalBytes int64
 
@@ -51,8 +51,12 @@ func runCleanup(args []string) error {
 		if err != nil || len(versions) <= 1 {
 			continue
 		}
-		// Keep the latest (last after sort), remove the rest.
-		for _, ver := range versions[:len(versions)-1] {
+		// Keep the active version (the one opt points to, or else the
+		// latest), remove the rest.
+		for _, ver := range versions {
+			if ver == pkg.Version {
+				continue
+			}
 			kegPath := cel.KegPath(pkg.Name, ver)
 			size, _ := dirSize(kegPath)
 			totalBytes += size
@@ -28,7 +28,7 @@ func runConfig(_ []string) error {
 	fmt.Printf("Core tap formulas: %d\n", len(all))
 
 	// Installed packages
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 	installed, _ := cel.List()
 	fmt.Printf("Installed packages: %d\n", len(installed))
 
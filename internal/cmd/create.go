@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/homegrew/grew/internal/config"
+	"github.com/homegrew/grew/internal/downloader"
+	"github.com/homegrew/grew/internal/formula"
+	"github.com/homegrew/grew/internal/tap"
+	"github.com/homegrew/grew/internal/validation"
+)
+
+// runCreate downloads a source tarball and writes a draft formula for it
+// into a tap: name and version come from the URL, the build system from
+// the files at the top of the extracted tree.
+func runCreate(args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	tapName := fs.String("tap", "core", "Tap to write the formula into")
+	name := fs.String("name", "", "Formula name (default: inferred from the URL)")
+	version := fs.String("version", "", "Formula version (default: inferred from the URL)")
+	desc := fs.String("description", "", "Formula description")
+	license := fs.String("license", "", "SPDX license identifier")
+	force := fs.Bool("force", false, "Overwrite an existing formula file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: grew create [--tap <tap>] [--name <name>] [--version <version>] <url>")
+	}
+	srcURL := fs.Arg(0)
+	if !strings.HasPrefix(srcURL, "https://") {
+		return fmt.Errorf("refusing to create a formula for a non-HTTPS URL: %s", srcURL)
+	}
+
+	inferredName, inferredVersion, inferErr := formula.InferNameVersion(srcURL)
+	if *name == "" {
+		*name = inferredName
+	}
+	if *version == "" {
+		*version = inferredVersion
+	}
+	if *name == "" || *version == "" {
+		return fmt.Errorf("%v; pass --name and --version", inferErr)
+	}
+	if !validation.IsValidName(*name) {
+		return fmt.Errorf("invalid formula name %q", *name)
+	}
+	if !validation.IsValidVersion(*version) {
+		return fmt.Errorf("invalid version %q", *version)
+	}
+
+	paths := config.Default()
+	if err := paths.Init(); err != nil {
+		return err
+	}
+	tapMgr := &tap.Manager{TapsDir: paths.Taps}
+	if err := tapMgr.InitCore(); err != nil {
+		return fmt.Errorf("init core tap: %w", err)
+	}
+	tapDir := filepath.Join(paths.Taps, *tapName)
+	if !config.IsDir(tapDir) {
+		return fmt.Errorf("tap not found: %s", *tapName)
+	}
+	dest := filepath.Join(tapDir, *name+".yaml")
+	if _, err := os.Stat(dest); err == nil && !*force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", dest)
+	}
+
+	fmt.Printf("==> Creating %s %s\n", *name, *version)
+	dl := &downloader.Downloader{TmpDir: paths.Tmp}
+	localFile, err := dl.Download(srcURL, *name+"-"+*version+"-src"+urlExt(srcURL))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(localFile)
+
+	sha, err := downloader.FileSHA256(localFile)
+	if err != nil {
+		return err
+	}
+	Logf("    SHA256: %s\n", sha)
+
+	// Extract the way a source build does, so the markers are found at
+	// the top of the tree the build steps run in.
+	srcDir := filepath.Join(paths.Tmp, *name+"-"+*version+"-create")
+	os.RemoveAll(srcDir)
+	defer os.RemoveAll(srcDir)
+	system := ""
+	srcSpec := formula.InstallSpec{Type: "archive", StripComponents: 1}
+	if err := downloader.Extract(localFile, srcDir, srcSpec); err != nil {
+		fmt.Printf("==> Warning: cannot inspect source: %v\n", err)
+	} else {
+		system = formula.DetectBuildSystem(srcDir)
+	}
+	if system == "" {
+		system = formula.DefaultBuildSystem
+		fmt.Printf("==> Warning: no build system detected, using %s\n", system)
+	} else {
+		fmt.Printf("==> Detected build system: %s\n", system)
+	}
+
+	// Only suggest build dependencies the taps can provide; anything else
+	// is left to the host.
+	loader := newLoader(paths.Taps)
+	var buildDeps []string
+	for _, tool := range formula.BuildSystemTools(system) {
+		if _, err := loader.LoadByName(tool); err != nil {
+			Logf("    No formula for build tool %s, leaving it to the host\n", tool)
+			continue
+		}
+		buildDeps = append(buildDeps, tool)
+	}
+
+	draft := &formula.Draft{
+		Name:              *name,
+		Version:           *version,
+		Description:       *desc,
+		Homepage:          formula.InferHomepage(srcURL),
+		License:           *license,
+		SourceURL:         srcURL,
+		SourceSHA256:      sha,
+		BuildSystem:       system,
+		BuildDependencies: buildDeps,
+	}
+	data := draft.YAML()
+	f, err := formula.Parse(data)
+	if err != nil {
+		return fmt.Errorf("generated formula is invalid: %w", err)
+	}
+	if err := os.WriteFile(dest, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", dest, err)
+	}
+	fmt.Printf("==> Wrote %s\n", dest)
+	f.Path = dest
+
+	allNames := map[string]bool{f.Name: true}
+	for _, dep := range buildDeps {
+		allNames[dep] = true
+	}
+	r := auditFormula(f, allNames, loader, paths, false)
+	printAuditResult(r)
+	if len(r.Errors) > 0 {
+		return fmt.Errorf("draft formula failed audit")
+	}
+	fmt.Printf("Edit it to fill in anything missing, then try: grew install --build-from-source %s\n", f.Name)
+	return nil
+}
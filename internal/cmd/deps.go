@@ -17,10 +17,21 @@ func runDeps(args []string) error {
 	tree := fs.Bool("tree", false, "Show dependencies as a tree")
 	all := fs.Bool("all", false, "Show dependencies for all formulas")
 	installed := fs.Bool("installed", false, "Show dependencies for installed formulas")
+	includeBuild := fs.Bool("include-build", false, "Include build dependencies")
+	forPlatform := fs.String("for-platform", "", "Show dependencies for another platform (e.g. linux, darwin_arm64)")
+	tapPath := fs.String("tap-path", "", "Also load formulas from this directory")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
+	platform := formula.HostPlatform()
+	if *forPlatform != "" {
+		var err error
+		if platform, err = formula.ParsePlatform(*forPlatform); err != nil {
+			return err
+		}
+	}
+
 	targets := fs.Args()
 
 	paths := config.Default()
@@ -33,6 +44,9 @@ func runDeps(args []string) error {
 		return fmt.Errorf("init core tap: %w", err)
 	}
 	loader := newLoader(paths.Taps)
+	if err := addTapPath(loader, *tapPath); err != nil {
+		return err
+	}
 
 	if *all {
 		formulas, err := loader.LoadAll()
@@ -44,7 +58,7 @@ func runDeps(args []string) error {
 		}
 		sort.Strings(targets)
 	} else if *installed {
-		cel := &cellar.Cellar{Path: paths.Cellar}
+		cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 		pkgs, err := cel.List()
 		if err != nil {
 			return err
@@ -55,24 +69,34 @@ func runDeps(args []string) error {
 	}
 
 	if len(targets) == 0 {
-		return fmt.Errorf("usage: grew deps [--tree] [--all | --installed] <formula ...>")
+		return fmt.Errorf("usage: grew deps [--tree] [--include-build] [--for-platform <platform>] [--tap-path <dir>] [--all | --installed] <formula|file ...>")
 	}
 
 	for i, name := range targets {
-		f, err := loader.LoadByName(name)
+		f, err := loadForPlatform(loader, name, platform)
 		if err != nil {
-			return fmt.Errorf("formula not found: %s", name)
+			return err
+		}
+
+		// Build dependencies only apply to the named formula: everything
+		// below it is installed from bottles.
+		deps := f.Deps()
+		var buildDeps []formula.Dependency
+		if *includeBuild {
+			buildDeps = f.BuildDeps()
 		}
 
 		if *tree {
 			if len(targets) > 1 {
 				fmt.Println(f.Name)
 			}
-			printTree(loader, f.Dependencies, "", make(map[string]bool))
+			printTree(loader, platform, deps, buildDeps, "", make(map[string]bool))
 		} else {
 			allDeps := make(map[string]bool)
-			if err := collectDeps(loader, f.Dependencies, allDeps); err != nil {
-				return err
+			for _, list := range [][]formula.Dependency{deps, buildDeps} {
+				if err := collectDeps(loader, platform, formula.DepNames(list), allDeps); err != nil {
+					return err
+				}
 			}
 			sorted := make([]string, 0, len(allDeps))
 			for d := range allDeps {
@@ -81,9 +105,9 @@ func runDeps(args []string) error {
 			sort.Strings(sorted)
 
 			if len(targets) > 1 {
-				fmt.Printf("%s: %s\n", name, strings.Join(sorted, " "))
+				fmt.Printf("%s: %s\n", f.Name, strings.Join(sorted, " "))
 			} else if len(sorted) == 0 {
-				fmt.Printf("%s has no dependencies.\n", name)
+				fmt.Printf("%s has no dependencies.\n", f.Name)
 			} else {
 				for _, d := range sorted {
 					fmt.Println(d)
@@ -99,44 +123,80 @@ func runDeps(args []string) error {
 	return nil
 }
 
-func printTree(loader *formula.Loader, deps []string, prefix string, visited map[string]bool) {
-	sort.Strings(deps)
-	for i, dep := range deps {
-		isLast := i == len(deps)-1
+// printTree prints deps, then buildDeps marked "(build)", and recurses
+// into their runtime dependencies on platform.
+func printTree(loader *formula.Loader, platform formula.Platform, deps, buildDeps []formula.Dependency, prefix string, visited map[string]bool) {
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Name < deps[j].Name })
+	sort.Slice(buildDeps, func(i, j int) bool { return buildDeps[i].Name < buildDeps[j].Name })
+	all := append(append([]formula.Dependency(nil), deps...), buildDeps...)
+	for i, d := range all {
+		dep := d.Name
+		isLast := i == len(all)-1
 		connector := "├── "
 		childPrefix := "│   "
 		if isLast {
 			connector = "└── "
 			childPrefix = "    "
 		}
-		fmt.Printf("%s%s%s\n", prefix, connector, dep)
+		// Show the formula that satisfies a dependency under another
+		// name, e.g. the member of a versioned family (openssl -> openssl@3).
+		f, err := loadForPlatform(loader, dep, platform)
+		label := d.String()
+		if err == nil && f.Name != dep {
+			label += " -> " + f.Name
+		}
+		if i >= len(deps) {
+			label += " (build)"
+		}
+		fmt.Printf("%s%s%s\n", prefix, connector, label)
 
-		if visited[dep] {
+		if err != nil || visited[f.Name] {
 			continue
 		}
-		visited[dep] = true
+		visited[f.Name] = true
 
-		f, err := loader.LoadByName(dep)
-		if err != nil || len(f.Dependencies) == 0 {
-			continue
+		if children := f.Deps(); len(children) > 0 {
+			printTree(loader, platform, children, nil, prefix+childPrefix, visited)
 		}
-		printTree(loader, f.Dependencies, prefix+childPrefix, visited)
 	}
 }
 
-func collectDeps(loader *formula.Loader, deps []string, seen map[string]bool) error {
+// collectDeps adds the formulas that satisfy deps, and their runtime
+// dependencies, to seen. Dependencies are recorded under the name of the
+// formula that satisfies them, so a dependency on openssl shows up as the
+// family member installed for it.
+func collectDeps(loader *formula.Loader, platform formula.Platform, deps []string, seen map[string]bool) error {
 	for _, dep := range deps {
 		if seen[dep] {
 			continue
 		}
-		seen[dep] = true
-		f, err := loader.LoadByName(dep)
+		f, err := loadForPlatform(loader, dep, platform)
 		if err != nil {
-			return fmt.Errorf("dependency %q not found", dep)
+			return fmt.Errorf("dependency %q: %w", dep, err)
+		}
+		if seen[f.Name] {
+			continue
 		}
-		if err := collectDeps(loader, f.Dependencies, seen); err != nil {
+		seen[f.Name] = true
+		if err := collectDeps(loader, platform, formula.DepNames(f.Deps()), seen); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// loadForPlatform loads a formula and evaluates its on_macos, on_linux,
+// on_arm and on_intel blocks for platform.
+func loadForPlatform(loader *formula.Loader, name string, platform formula.Platform) (*formula.Formula, error) {
+	f, err := loadFormulaArg(loader, name)
+	if err != nil {
+		if isFormulaPath(name) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("formula not found: %s", name)
+	}
+	if f.Platform() == platform {
+		return f, nil
+	}
+	return f.ForPlatform(platform)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -9,10 +10,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/homegrew/grew/internal/cask"
 	"github.com/homegrew/grew/internal/cellar"
 	"github.com/homegrew/grew/internal/config"
 	"github.com/homegrew/grew/internal/formula"
 	"github.com/homegrew/grew/internal/linker"
+	"github.com/homegrew/grew/internal/requirements"
 	"github.com/homegrew/grew/internal/snapshot"
 	"github.com/homegrew/grew/internal/tap"
 )
@@ -65,11 +68,13 @@ func allChecks() []doctorCheck {
 		{"check_directories", "Check required directories exist", checkDirectories},
 		{"check_path", "Check grew bin/ is in PATH", checkPath},
 		{"check_core_tap", "Check core tap has formulas", checkCoreTap},
-		{"check_broken_symlinks", "Check for broken symlinks in bin/, lib/, include/", checkBrokenSymlinks},
+		{"check_broken_symlinks", "Check for broken symlinks in bin/, sbin/, lib/, include/, etc/, share/", checkBrokenSymlinks},
 		{"check_broken_opt_symlinks", "Check for broken opt/ symlinks", checkBrokenOptSymlinks},
 		{"check_unlinked_kegs", "Check installed formulas are linked", checkUnlinkedKegs},
 		{"check_orphaned_symlinks", "Check for orphaned symlinks", checkOrphanedSymlinks},
 		{"check_multiple_versions", "Check for multiple installed versions", checkMultipleVersions},
+		{"check_deprecated", "Check for installed formulas and casks that are deprecated or disabled", checkDeprecated},
+		{"check_requirements", "Check installed formulas' system requirements are still met", checkRequirements},
 		{"check_stale_tmp", "Check for stale files in tmp/", checkStaleTmp},
 	}
 	return append(base, extraChecks...)
@@ -130,7 +135,7 @@ func runDoctor(args []string) error {
 	loader := newLoader(paths.Taps)
 	formulas, _ := loader.LoadAll()
 
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 	lnk := &linker.Linker{Paths: paths}
 	packages, _ := cel.List()
 
@@ -237,28 +242,16 @@ func checkSymlinkTargets(ctx *doctorCtx) {
 	if err != nil {
 		return
 	}
-	for _, dir := range []string{ctx.paths.Bin, ctx.paths.Lib, ctx.paths.Include, ctx.paths.Opt} {
-		entries, err := os.ReadDir(dir)
-		if err != nil {
-			continue
-		}
-		for _, e := range entries {
-			fullPath := filepath.Join(dir, e.Name())
-			target, err := os.Readlink(fullPath)
-			if err != nil {
-				continue
-			}
-			if !filepath.IsAbs(target) {
-				target = filepath.Join(dir, target)
-			}
+	for _, dir := range append(ctx.paths.LinkDirs(), ctx.paths.Opt) {
+		linker.WalkLinks(dir, func(fullPath, target string) {
 			resolved, err := filepath.Abs(target)
 			if err != nil {
-				continue
+				return
 			}
 			if !strings.HasPrefix(resolved, absPrefix+string(filepath.Separator)) {
 				ctx.warn("symlink escapes grew prefix: %s -> %s (resolves to %s)", fullPath, target, resolved)
 			}
-		}
+		})
 	}
 }
 
@@ -328,8 +321,11 @@ func checkDirectories(ctx *doctorCtx) {
 		"Cellar":  ctx.paths.Cellar,
 		"opt":     ctx.paths.Opt,
 		"bin":     ctx.paths.Bin,
+		"sbin":    ctx.paths.Sbin,
 		"lib":     ctx.paths.Lib,
 		"include": ctx.paths.Include,
+		"etc":     ctx.paths.Etc,
+		"share":   ctx.paths.Share,
 		"Taps":    ctx.paths.Taps,
 		"CoreTap": ctx.paths.CoreTap,
 		"tmp":     ctx.paths.Tmp,
@@ -370,24 +366,12 @@ func checkCoreTap(ctx *doctorCtx) {
 }
 
 func checkBrokenSymlinks(ctx *doctorCtx) {
-	for _, dir := range []string{ctx.paths.Bin, ctx.paths.Lib, ctx.paths.Include} {
-		entries, err := os.ReadDir(dir)
-		if err != nil {
-			continue
-		}
-		for _, e := range entries {
-			fullPath := filepath.Join(dir, e.Name())
-			target, err := os.Readlink(fullPath)
-			if err != nil {
-				continue
-			}
-			if !filepath.IsAbs(target) {
-				target = filepath.Join(dir, target)
-			}
+	for _, dir := range ctx.paths.LinkDirs() {
+		linker.WalkLinks(dir, func(fullPath, target string) {
 			if _, err := os.Stat(target); os.IsNotExist(err) {
 				ctx.warn("broken symlink: %s -> %s", fullPath, target)
 			}
-		}
+		})
 	}
 }
 
@@ -425,33 +409,20 @@ func checkUnlinkedKegs(ctx *doctorCtx) {
 }
 
 func checkOrphanedSymlinks(ctx *doctorCtx) {
-	for _, dir := range []string{ctx.paths.Bin, ctx.paths.Lib, ctx.paths.Include} {
-		entries, err := os.ReadDir(dir)
-		if err != nil {
-			continue
-		}
-		for _, e := range entries {
-			fullPath := filepath.Join(dir, e.Name())
-			target, err := os.Readlink(fullPath)
-			if err != nil {
-				continue
-			}
-			if !filepath.IsAbs(target) {
-				target = filepath.Join(dir, target)
-			}
-			target = filepath.Clean(target)
+	for _, dir := range ctx.paths.LinkDirs() {
+		linker.WalkLinks(dir, func(fullPath, target string) {
 			if !strings.Contains(target, "Cellar") {
-				continue
+				return
 			}
 			rel, err := filepath.Rel(ctx.paths.Cellar, target)
 			if err != nil {
-				continue
+				return
 			}
 			name := strings.SplitN(rel, string(filepath.Separator), 2)[0]
 			if !ctx.cel.IsInstalled(name) {
 				ctx.warn("orphaned symlink: %s (formula %q not installed)", fullPath, name)
 			}
-		}
+		})
 	}
 }
 
@@ -466,6 +437,52 @@ func checkMultipleVersions(ctx *doctorCtx) {
 	}
 }
 
+func checkDeprecated(ctx *doctorCtx) {
+	for _, pkg := range ctx.packages {
+		f, err := ctx.loader.LoadByName(pkg.Name)
+		if err != nil {
+			continue
+		}
+		if status, d := formulaStatus(f); d != nil {
+			ctx.warn("%s %s is installed but %s: %s", pkg.Name, pkg.Version, status, d.Message(f.Name, status))
+		}
+	}
+
+	cr := &cask.Caskroom{Path: ctx.paths.Caskroom}
+	installed, err := cr.List()
+	if err != nil {
+		return
+	}
+	loader := newCaskLoader(ctx.paths.Taps)
+	for _, ic := range installed {
+		c, err := loader.LoadByName(ic.Name)
+		if err != nil {
+			continue
+		}
+		if status, d := caskStatus(c); d != nil {
+			ctx.warn("cask %s is installed but %s: %s", ic.Name, status, d.Message(c.Name, status))
+		}
+	}
+}
+
+// checkRequirements re-checks the requirements: block of installed
+// formulas, which an OS upgrade or hardware change can leave unmet.
+func checkRequirements(ctx *doctorCtx) {
+	checker := &requirements.Checker{}
+	for _, pkg := range ctx.packages {
+		f, err := ctx.loader.LoadByName(pkg.Name)
+		if err != nil {
+			continue
+		}
+		var re *requirements.Error
+		if err := checker.Check(f); errors.As(err, &re) {
+			for _, u := range re.Unmet {
+				ctx.warn("%s %s requires %s", pkg.Name, pkg.Version, u)
+			}
+		}
+	}
+}
+
 func checkStaleTmp(ctx *doctorCtx) {
 	entries, err := os.ReadDir(ctx.paths.Tmp)
 	if err == nil && len(entries) > 0 {
@@ -3,26 +3,41 @@ package cmd
 import "fmt"
 
 var commandHelp = map[string]string{
-	"install": `Usage: grew install [--cask] [-s] [--only-dependencies] [--ignore-dependencies] <formula>
+	"install": `Usage: grew install [--cask] [-s|--HEAD] [--with-<option>] [--without-<option>] [--only-dependencies] [--ignore-dependencies] [--overwrite|--skip-link] [--tap-path <dir>] <formula|file>
 
 Install a formula and its dependencies. Downloads the package, verifies
 its SHA256 checksum, extracts it to the Cellar, and creates symlinks.
+Version constraints, requirements, conflicts and link conflicts are all
+checked before anything is downloaded. The formula may also be a path
+to a formula file, or <formula>@<version> for an older version (see
+'grew switch').
 
 Flags:
   --cask                Install a macOS application cask instead of a formula.
                         Casks are .app bundles installed to ~/Applications.
   -s, --build-from-source
                         Build the formula from source instead of using the
-                        pre-built bottle. Downloads the source tarball and
-                        runs ./configure && make && make install. Dependencies
-                        are still installed from bottles.
+                        pre-built bottle, in the sandbox (see 'grew create'
+                        for the build steps). build_dependencies are
+                        installed too, unlinked.
+  --HEAD                Build from the formula's head: git source. The keg
+                        is versioned HEAD-<commit>.
+  --with-<option>, --without-<option>
+                        Turn a build option of the formula on or off (see
+                        grew info). Only for source and --HEAD builds.
   --only-dependencies   Install the dependencies but not the formula itself.
   --ignore-dependencies Skip installing dependencies; install only the formula.
   --skip-post-install   Do not run the post-install script.
   --skip-link           Install to the Cellar but do not create symlinks.
+  --overwrite           Replace files and other formulas' links that are
+                        in the way of linking.
   --require-sha         Refuse to install if a formula is missing a SHA256
                         checksum. Checks all formulas (including dependencies)
                         before downloading anything.
+  --force               Install a formula or cask even if it is disabled.
+                        Deprecated ones install with a warning.
+  --tap-path <dir>      Also load formulas from <dir>, searched ahead of
+                        the taps (e.g. a checkout of formulas in progress).
 
 If the formula/cask is already installed, the command is a no-op.
 
@@ -31,12 +46,16 @@ Examples:
   grew install -s ldns
   grew install --only-dependencies ldns
   grew install --ignore-dependencies jq
+  grew install ./jq.yaml
+  grew install jq@1.6
+  grew install --tap-path ~/src/my-formulas mytool
   grew install --cask firefox
   grew install --cask visual-studio-code`,
 
 	"uninstall": `Usage: grew uninstall [--cask] <formula>
 
-Uninstall a formula by removing its symlinks and Cellar directory.
+Uninstall a formula by removing its symlinks and Cellar directory, and
+its pin if it was pinned.
 With --cask, removes the .app from ~/Applications and the Caskroom entry.
 
 Aliases: remove
@@ -45,23 +64,48 @@ Examples:
   grew uninstall jq
   grew uninstall --cask firefox`,
 
-	"list": `Usage: grew list [--cask]
+	"list": `Usage: grew list [--cask] [--versions]
 
-List all installed formulas with their versions.
+List all installed formulas with their active versions.
 With --cask, list installed casks instead.
 
+Flags:
+  --versions   List every installed version of each formula, oldest
+               first. The active one, which opt/<formula> points to, is
+               marked with *; see 'grew switch'.
+
 Examples:
   grew list
+  grew list --versions
   grew list --cask`,
 
-	"info": `Usage: grew info [--cask] <formula>
+	"info": `Usage: grew info [--cask] [--tap-path <dir>] <formula|file>
 
 Show detailed information about a formula including its name, version,
 description, homepage, license, installed status, dependencies, and
 supported platforms. With --cask, show cask details including app artifacts.
+Deprecation or disable notices and caveats are shown when present.
+
+The formula may be named by a tap alias (e.g. python for python@3.12) or
+by a name it was renamed from; the aliases of a formula are listed.
+The other members of its versioned family (e.g. openssl@1.1 for
+openssl@3) are listed as other versions, marked when installed.
+It may also be a path to a formula file, or a formula in the directory
+given with --tap-path; the file is shown. An installed formula that came
+from a local file shows that file.
+
+Options are the formula's options: list plus its optional_dependencies
+(off by default) and recommended_dependencies (on by default); install
+--with-<option> and --without-<option> turn them on and off, and the
+options a keg was built with are shown. Requirements (min_kernel,
+min_glibc, a command, a cpu_feature, the arch, file_exists) must be met
+to install. A formula that conflicts with another (conflicts_with, or a
+name both provide) is refused while the other is installed, and one
+that replaces an installed formula removes it once installed.
 
 Examples:
   grew info jq
+  grew info ./jq.yaml
   grew info --cask firefox`,
 
 	"search": `Usage: grew search [--cask] <query>
@@ -76,17 +120,32 @@ Examples:
 
 	"link": `Usage: grew link [--overwrite] [--dry-run] [--force] <formula>
 
-Create symlinks for an installed formula. Symlinks binaries into bin/,
-libraries into lib/, and headers into include/. Also creates an opt/
-symlink pointing to the Cellar keg.
+Create symlinks for an installed formula. Symlinks binaries into bin/
+and sbin/, libraries into lib/, headers into include/, configuration
+into etc/, and data into share/, including man pages (share/man) and
+shell completions (etc/bash_completion.d, share/zsh/site-functions and
+share/fish/vendor_completions.d). Directories that several formulas
+contribute to, such as lib/pkgconfig or share/man/man1, are created as
+real directories holding per-file links. Also creates an opt/ symlink
+pointing to the Cellar keg.
 
 For keg-only formulas, only the opt/ symlink is created unless --force
 is used.
 
+Only one member of a versioned family (openssl, openssl@3, ...) can be
+linked at a time. Linking another member fails and names the linked one;
+with --overwrite, that member is unlinked from the prefix first (its
+opt/ link stays, so its dependents keep working). Installing a member
+while another is linked only creates its opt/ link.
+
+grew install predicts the paths a formula will link from its bottle's
+files: list, its binary_name, or a keg of it already in the Cellar, and
+stops before downloading if any is taken, listing the owners.
+
 Flags:
   --overwrite    Overwrite existing files or symlinks from other formulas
   -n, --dry-run  Show what would be linked without making changes
-  --force        Link a keg-only formula into bin/, lib/, include/
+  --force        Link a keg-only formula into the prefix
 
 Examples:
   grew link jq
@@ -98,7 +157,8 @@ Examples:
 
 Uninstall and then reinstall a formula. This is useful when an
 installation is corrupted or you want a clean slate. The formula
-must already be installed.
+must already be installed. A keg built with --HEAD or with build
+options is rebuilt from source the same way.
 
 Examples:
   grew reinstall jq`,
@@ -107,12 +167,44 @@ Examples:
 
 Remove symlinks for an installed formula without uninstalling it.
 
+The links removed are the ones recorded in the keg's manifest when it
+was linked, including those in shared directories such as lib/pkgconfig;
+a link another formula has since taken over is left alone. Shared
+directories left empty are removed. Kegs linked before links were
+recorded are unlinked by scanning the prefix for links into the keg.
+
 Flags:
   -n, --dry-run  Show what would be unlinked without making changes
 
 Examples:
   grew unlink jq`,
 
+	"switch": `Usage: grew switch [--force] <formula> <version>
+
+Make another installed version of a formula the active one. Several
+versions can be installed side by side (with 'grew install <formula>@<version>'
+or 'grew upgrade --keep-old'); the active one is the keg opt/<formula>
+points to, and the one linked into the prefix.
+
+opt/<formula> is repointed in a single rename, so it never goes missing,
+and the links of the previous version are replaced with those of the new
+one. If linking fails, the previous version is linked again. 'grew list
+--versions' shows the installed versions with the active one marked.
+
+A version outside the version constraints that installed dependents
+place on the formula (e.g. "jq >= 1.7") is refused unless --force is
+given.
+
+'grew install <formula>@<version>' (e.g. jq@1.6), where that is not a
+formula of its own, installs the version from the history of its tap
+and makes it active. The tap must be a git clone; its full history is
+fetched on first use and kept by 'grew update', and the commit read is
+signature-checked per HOMEGREW_TAP_VERIFY.
+
+Examples:
+  grew switch jq 1.6
+  grew switch jq 1.7.1`,
+
 	"reset-update": `Usage: grew reset-update
 
 Delete all tap definitions and re-fetch them from scratch. Use this when
@@ -136,13 +228,49 @@ using git(1). Equivalent to: git -C <taps-dir> pull
 The taps repository is cloned from:
   https://github.com/homegrew/homegrew-taps`,
 
-	"upgrade": `Usage: grew upgrade [formula ...]
+	"upgrade": `Usage: grew upgrade [--fetch-HEAD] [--keep-old] [--force] [formula ...]
 
 Upgrade outdated formulas to the latest version available in the tap.
 With no arguments, upgrades all outdated packages. Specify formula
 names to upgrade only those.
 
-The old version keg is removed after a successful upgrade.
+Flags:
+  --fetch-HEAD   Also check formulas installed with --HEAD: when their
+                 branch has moved past the recorded commit, they are
+                 rebuilt from the new head. Without it they are left
+                 alone.
+  --keep-old     Keep the old version installed next to the new one,
+                 to switch back to with 'grew switch'.
+  --force        Upgrade pinned formulas named on the command line. They
+                 stay pinned.
+
+Formulas pinned with 'grew pin' are held back: an upgrade of everything
+names each one it skips, and naming one is refused unless --force is
+given.
+
+Versions are compared numerically (1.10 is newer than 1.9), with
+pre-release tags such as -rc1 sorting before the release. A formula's
+version_scheme outranks the version string, and a bumped revision
+(kegs are stored as <version>_<revision>) makes the same version newer.
+If the installed keg is newer than the tap, it is reported and left
+alone rather than downgraded. With several versions installed, the
+newest is compared, whichever one is active.
+
+Installed formulas that a tap has renamed (listed in the tap's
+renames.json) are migrated first: their kegs move to the new name, the
+opt and prefix links are recreated, and keg manifests and lockfile
+entries are updated to the new name.
+
+Installed formulas that a tap formula lists under replaces are migrated:
+the successor is installed and the old formula is removed.
+
+Kegs built with options are upgraded by building the new version from
+source with the same options. The old version keg is removed after a
+successful upgrade, unless --keep-old is given. Formulas whose new
+version has requirements this machine does not meet, or falls outside
+a version constraint an installed dependent places on it, are skipped.
+Disabled formulas are skipped; deprecated ones are upgraded with a
+warning, and caveats are shown after each upgrade.
 
 Examples:
   grew upgrade
@@ -150,12 +278,44 @@ Examples:
 
 	"outdated": `Usage: grew outdated
 
-List installed formulas that have a newer version available in the tap.`,
+List installed formulas that have a newer version or formula revision
+available in the tap.
+Formulas the tap marks deprecated or disabled are annotated, and
+formulas replaced by another or renamed are listed with their successor
+or new name. Packages
+whose installed version is newer than the tap are reported as a warning
+on stderr. Formulas installed with --HEAD are not listed; see grew
+upgrade --fetch-HEAD. Formulas pinned with 'grew pin' are listed as
+pinned, not upgraded, and do not count as outdated.`,
+
+	"pin": `Usage: grew pin [<formula>]
+
+Pin an installed formula at its current version. 'grew upgrade' skips
+pinned formulas and says so, 'grew upgrade <formula>' refuses them
+without --force, and 'grew outdated' lists them apart. An install that
+would have to upgrade a pinned dependency to meet a version constraint
+is refused. 'grew lock generate' marks pinned entries.
+
+Pins are stored in the prefix, in <grew_root>/pinned.json; uninstalling
+a formula removes its pin. With no formula, lists the pinned formulas
+with their versions.
+
+Examples:
+  grew pin jq
+  grew pin`,
+
+	"unpin": `Usage: grew unpin <formula>
+
+Remove the pin of a formula so it is upgraded again.
+
+Examples:
+  grew unpin jq`,
 
 	"cleanup": `Usage: grew cleanup [-n] [formula ...]
 
 Remove old versions of installed formulas and clear the download cache.
-Only the latest version of each formula is kept.
+Only the active version of each formula is kept: the one its opt link
+points to (see 'grew switch'), or else the latest by version order.
 
 Flags:
   -n, --dry-run    Show what would be removed without deleting
@@ -185,7 +345,7 @@ Examples:
   grew alias show i
   grew alias edit`,
 
-	"audit": `Usage: grew audit [--strict] [--cask] [--online] [formula ...]
+	"audit": `Usage: grew audit [--strict] [--cask] [--online] [--tap-path <dir>] [formula|file ...]
 
 Audit formula or cask definitions for common problems and style issues.
 With no arguments, audits all formulas in the core tap.
@@ -197,9 +357,20 @@ Checks performed:
   - Version uses valid characters
   - All download URLs use HTTPS and are parseable
   - All SHA256 hashes are valid 64-character hex strings
-  - Dependencies exist in the tap and have valid names
+  - Patch and resource URLs are valid; file patches exist and match
+    their SHA256
+  - Dependencies, build_dependencies and linux_dependencies exist in the
+    tap and have valid names
+  - Dependency version constraints parse and can be satisfied by the tap
   - No circular dependencies
+  - The dependency checks above hold on every supported platform
+    (darwin_arm64, darwin_amd64, linux_amd64, linux_arm64), with the
+    on_macos, on_linux, on_arm and on_intel blocks applied for each
   - No self-dependencies
+  - conflicts_with is declared on both sides; formulas do not replace
+    each other; dependencies do not conflict
+  - Deprecation replacements exist; installed formulas and casks that
+    are deprecated or disabled are flagged
   - Install type is valid (binary or archive)
   - Binary installs have binary_name set
   - Cask artifacts are correctly defined
@@ -209,6 +380,9 @@ Flags:
   --cask      Audit cask definitions instead of formulas
   --online    Include checks that require installed packages
               (verifies snapshot integrity for installed formulas)
+  --tap-path <dir>
+              Also load formulas from <dir>. With no formulas named,
+              audit only the formulas in <dir>
 
 Exit code 0 if audit passes, 1 if errors are found (or warnings
 with --strict).
@@ -219,24 +393,187 @@ Examples:
   grew audit --strict
   grew audit --cask
   grew audit --cask firefox
-  grew audit --online jq`,
+  grew audit --online jq
+  grew audit ./jq.yaml
+  grew audit --tap-path ~/src/my-formulas`,
 
-	"deps": `Usage: grew deps [--tree] [--all | --installed] <formula ...>
+	"deps": `Usage: grew deps [--tree] [--include-build] [--for-platform <platform>] [--tap-path <dir>] [--all | --installed] <formula|file ...>
 
 Show dependencies for one or more formulas. By default shows all
-transitive dependencies. Use --tree for a visual tree view.
+transitive runtime dependencies for the current platform, including
+linux_dependencies on Linux and the dependencies of the on_macos,
+on_linux, on_arm and on_intel blocks that apply. Use --tree for a
+visual tree view.
+
+Formulas and dependencies may be named by tap aliases or old names of
+renamed formulas. A formula may also be a path to a formula file. A
+dependency satisfied by a formula of another name, such as a member of a
+versioned family, is listed under that formula; the tree shows both
+(openssl -> openssl@3).
+
+Dependencies may carry version constraints (e.g. "openssl@3 >= 3.1",
+"zlib ~> 1.3"). grew install keeps an installed dependency that
+satisfies every constraint and upgrades it otherwise; if no version
+satisfies all dependents, it lists the conflicting constraints with
+their dependency chains. A name no formula has is satisfied by a
+formula that provides it.
 
 Flags:
-  --tree        Show dependencies as a tree
-  --all         Show dependencies for all available formulas
-  --installed   Show dependencies for all installed formulas
+  --tree              Show dependencies as a tree
+  --include-build     Also show the formula's build_dependencies (and
+                      their dependencies), as needed by install -s
+  --for-platform P    Show the dependencies for another platform: darwin
+                      (or macos) or linux, optionally with an arch suffix
+                      such as linux_arm64. The arch defaults to this
+                      machine's
+  --all               Show dependencies for all available formulas
+  --installed         Show dependencies for all installed formulas
+  --tap-path <dir>    Also load formulas from <dir>, ahead of the taps
 
 Examples:
   grew deps jq
   grew deps --tree jq
+  grew deps --include-build --tree ldns
+  grew deps --for-platform linux ldns
   grew deps --all
   grew deps --installed`,
 
+	"readall": `Usage: grew readall [tap]
+
+Load every formula and cask of a tap (all taps by default) with strict
+parsing and report each file that fails, as file:line:column: message.
+Unknown keys (such as a misspelled dependancies:) are errors, as are
+values of the wrong type and formulas that fail validation. The tap's
+aliases.json and renames.json must parse and point at formulas that
+exist. Exits non-zero if any problem is found, so it can gate tap CI.
+
+Examples:
+  grew readall
+  grew readall core
+  grew readall cask`,
+
+	"create": `Usage: grew create [--tap <tap>] [--name <name>] [--version <version>] [--description <text>] [--license <spdx>] [--force] <url>
+
+Write a draft formula that builds the source tarball at <url>. The
+tarball is downloaded and hashed, and the name and version are inferred
+from the URL (e.g. foo-1.2.3.tar.gz, or the repository and tag of a
+GitHub archive or release URL). The archive is extracted to detect the
+build system from the files at the top of the tree: configure
+(autotools), CMakeLists.txt (cmake), meson.build (meson), Cargo.toml
+(cargo), go.mod (go) or a Makefile (make), falling back to autotools.
+Formulas for the build system's tools are added as build_dependencies
+when a tap has them.
+
+The draft is written to <tap>/<name>.yaml, checked with the same parser
+and audit as any other formula, and its audit findings are printed.
+Fill in the description, license and anything else the build needs,
+then install it with grew install --build-from-source.
+
+Source builds run build.configure then build.install, each step with
+sh -c; build.system supplies any phase the formula does not list, and
+autotools is the default. Steps may use {prefix} (the keg), {jobs},
+{name}, {version} and {with-<option>} ("yes" or "no"). resources: are
+extracted into subdirectories of the source tree named after them, and
+patches: are applied with patch -p<strip> (default 1), all verified
+before the build.
+
+Flags:
+  --tap <tap>           Tap to write the formula into (default: core)
+  --name <name>         Formula name, when it cannot be inferred
+  --version <version>   Formula version, when it cannot be inferred
+  --description <text> Formula description
+  --license <spdx>      SPDX license identifier
+  --force               Overwrite an existing formula file
+
+Examples:
+  grew create https://ftp.gnu.org/gnu/hello/hello-2.12.1.tar.gz
+  grew create --tap mytap https://github.com/jqlang/jq/archive/refs/tags/jq-1.7.1.tar.gz
+  grew create --name foo --version 1.0 https://example.com/download/latest.tar.gz`,
+
+	"bump-formula": `Usage: grew bump-formula <formula> --version <version> [--url-template <template>] [--source-url-template <template>] [--key <key>] [--dry-run]
+
+Move a formula to a new version. Every download (url, bottle and source
+entries) gets a new URL, which is fetched and hashed, and the formula
+file is edited in place: version, each url and sha256, and signatures.
+Comments and key order in the file are kept.
+
+New URLs replace the old version in the current URL with the new one.
+When the URL does not contain the version, or changes shape, pass a
+template: --url-template for platform downloads and --source-url-template
+for the source. Templates may use {name}, {version} and {platform} (the
+platform key, e.g. linux_amd64).
+
+With --key, each new SHA256 is signed like grew sign does; the key is a
+hex-encoded Ed25519 seed or the path to an OpenSSH private key.
+Without it, existing signatures are removed, since they no longer
+match.
+
+Flags:
+  --version <version>            The new version (required)
+  --url-template <template>      URL template for url and bottle entries
+  --source-url-template <template>
+                                 URL template for the source entry
+  --key <key>                    Private key to re-sign the new hashes with
+  --dry-run                      Download and hash, print the diff, but do
+                                 not write the formula
+
+Examples:
+  grew bump-formula jq --version 1.7.1
+  grew bump-formula jq --version 1.7.1 --dry-run
+  grew bump-formula --version 2.0 --url-template 'https://example.com/{version}/foo-{platform}.tar.gz' foo
+  grew bump-formula jq --version 1.7.1 --key ~/.ssh/grew_ed25519`,
+
+	"livecheck": `Usage: grew livecheck [--json] [--newer-only] [formula ...]
+
+Check upstream for releases newer than the formula version, using the
+formula's livecheck: block. With no formulas, every formula that has a
+livecheck block is checked. Versions are compared with the same
+ordering as upgrades (so 1.10 is newer than 1.9).
+
+Strategies:
+  page             Fetch url (an HTML or JSON page) and match regex
+                   against its body. Both url and regex are required.
+  github_releases  Read a GitHub releases feed: tag_name of each release
+                   that is not a draft or prerelease. url defaults to the
+                   API of the GitHub repository in homepage or source;
+                   regex defaults to the tag without a leading "v".
+  directory        Scrape the links of a directory listing. url defaults
+                   to the directory of the source URL; regex defaults to
+                   <name>-<version> archives and version subdirectories.
+
+The regex may have one capture group, which is the version; without one
+the whole match is. The highest match is the latest version.
+
+  livecheck:
+    strategy: page
+    url: https://example.com/download.html
+    regex: foo-(\d+(?:\.\d+)+)\.tar\.gz
+
+Flags:
+  --json        Output results as JSON: one object per formula with name,
+                current, latest, outdated, url and error
+  --newer-only  Only show formulas with a newer upstream version
+
+Exits non-zero if any check failed.
+
+Examples:
+  grew livecheck
+  grew livecheck jq ripgrep
+  grew livecheck --json --newer-only`,
+
+	"schema": `Usage: grew schema <formula|cask>
+
+Print a JSON Schema (draft 2020-12) describing the formula or cask YAML
+format. It is generated from the same definitions the parser uses and
+disallows unknown keys. Point an editor's YAML language server at it for
+completion and validation, e.g. with a modeline:
+
+  # yaml-language-server: $schema=formula.schema.json
+
+Examples:
+  grew schema formula > formula.schema.json
+  grew schema cask > cask.schema.json`,
+
 	"doctor": `Usage: grew doctor [flags] [check ...]
 
 Check your system for potential problems. Exits with non-zero status
@@ -262,11 +599,16 @@ Structural checks:
   check_directories             Required directories exist
   check_path                    grew bin/ in PATH
   check_core_tap                Core tap has formulas
-  check_broken_symlinks         Broken symlinks in bin/, lib/, include/
+  check_broken_symlinks         Broken symlinks in bin/, sbin/, lib/,
+                                include/, etc/ and share/
   check_broken_opt_symlinks     Broken opt/ symlinks
   check_unlinked_kegs           Installed but not linked formulas
   check_orphaned_symlinks       Symlinks to uninstalled formulas
   check_multiple_versions       Multiple versions (suggest cleanup)
+  check_requirements            Installed formulas whose requirements are
+                                no longer met (e.g. after an OS upgrade)
+  check_deprecated              Installed formulas/casks that are deprecated
+                                or disabled
   check_stale_tmp               Leftover files in tmp/
 
 Run specific checks by name:
@@ -293,6 +635,13 @@ output to your shell profile to make grew-installed tools available.
 Detects the current shell automatically, or specify one explicitly.
 Supported shells: bash, zsh, fish, sh.
 
+Puts bin/ and sbin/ on PATH, share/man on MANPATH, and share/ on
+XDG_DATA_DIRS, where fish finds its completions and bash-completion 2
+looks for share/bash-completion. For zsh, share/zsh/site-functions is
+added to fpath; run it before compinit. Completions in
+etc/bash_completion.d are for bash-completion 1, which sources that
+directory when configured to.
+
 Setup:
   # bash (~/.bashrc):
   eval "$(grew shellenv)"
@@ -372,7 +721,7 @@ Examples:
   grew services run postgresql
   grew services info postgresql`,
 
-	"lock": `Usage: grew lock [generate|check|show]
+	"lock": `Usage: grew lock [generate [--for-platform <platform>]|check|show]
 
 Manage the formula lockfile. The lockfile records the exact state of all
 installed formulas (versions, checksums, dependencies) so environments
@@ -382,7 +731,12 @@ The lockfile is NOT auto-generated on install. It is an explicit opt-in
 (like npm shrinkwrap or cargo generate-lockfile).
 
 Subcommands:
-  generate    Generate a lockfile from the current installed state (default)
+  generate    Generate a lockfile from the current installed state (default).
+              Formulas pinned with 'grew pin' are marked "pinned": true.
+              With --for-platform (e.g. linux_amd64), print the lockfile
+              installing the same formulas on that platform would give:
+              tap versions, that platform's downloads and dependencies.
+              It is printed rather than written.
   check       Compare the lockfile against installed packages and report
               discrepancies. Exits non-zero if any are found.
   show        Pretty-print the current lockfile
@@ -391,14 +745,52 @@ Discrepancy kinds reported by check:
   missing            In lockfile but not installed
   extra              Installed but not in lockfile
   version_mismatch   Installed version differs from locked version
+  revision_mismatch  Same version, but installed at another formula
+                     revision (the formula was rebuilt)
   hash_mismatch      Keg integrity hash differs from locked hash
 
 Examples:
   grew lock
   grew lock generate
+  grew lock generate --for-platform darwin_arm64
   grew lock check
   grew lock show`,
 
+	"test": `Usage: grew test [--json] [--tap-path <dir>] <formula|file ...>
+
+Run the test block of each installed formula against the keg its opt
+link points to (the active version; see 'grew switch'). Every step runs
+with sh -c in the post-install sandbox (network denied, keg read-only),
+from a fresh scratch directory that is also TMPDIR. The keg's bin
+directory and its dependencies' opt bin directories are put in front of
+PATH.
+
+A step passes when it exits with expect_exit (default 0) and, if
+expect_output is set, its combined stdout and stderr contain that
+string. Testing a formula stops at its first failing step.
+
+  test:
+    - run: jq --version
+      expect_output: "jq-1.7"
+    - run: echo '{"a":1}' | jq .a
+      expect_output: "1"
+
+Exits non-zero if any formula fails, is not installed, or has no opt
+link. Formulas with no test block are reported but do not fail.
+
+Flags:
+  --json    Output results as JSON: one object per formula with name,
+            version, status (passed, failed, no_test, not_installed,
+            not_linked) and per-step command, exit_code, failure and
+            output
+  --tap-path <dir>
+            Also load formulas from <dir>, ahead of the taps. A formula
+            may also be given as a path to its file
+
+Examples:
+  grew test jq
+  grew test --json jq ripgrep`,
+
 	"verify": `Usage: grew verify [--json] [formula ...]
 
 Verify the integrity of installed packages by comparing the filesystem
@@ -413,6 +805,9 @@ Each package is checked for:
   - Missing files (deleted after install)
   - Modified files (content changed since install)
   - Added files (unexpected files appeared in the keg)
+  - Missing links (a symlink the linker created in the prefix is gone)
+  - Hijacked links (such a symlink now points elsewhere, or was
+    replaced by a regular file)
 
 Exit code 0 if all packages pass, 1 if any discrepancies found.
 
@@ -8,18 +8,20 @@ import (
 	"github.com/homegrew/grew/internal/cellar"
 	"github.com/homegrew/grew/internal/config"
 	"github.com/homegrew/grew/internal/linker"
+	"github.com/homegrew/grew/internal/snapshot"
 	"github.com/homegrew/grew/internal/tap"
 )
 
 func runInfo(args []string) error {
 	fs := flag.NewFlagSet("info", flag.ContinueOnError)
 	isCask := fs.Bool("cask", false, "Show cask info")
+	tapPath := fs.String("tap-path", "", "Also load formulas from this directory")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
 	if fs.NArg() != 1 {
-		return fmt.Errorf("usage: grew info [--cask] <formula>")
+		return fmt.Errorf("usage: grew info [--cask] [--tap-path <dir>] <formula|file>")
 	}
 
 	if *isCask {
@@ -38,26 +40,67 @@ func runInfo(args []string) error {
 	}
 
 	loader := newLoader(paths.Taps)
-	f, err := loader.LoadByName(name)
+	if err := addTapPath(loader, *tapPath); err != nil {
+		return err
+	}
+	f, err := loadFormulaArg(loader, name)
 	if err != nil {
+		if isFormulaPath(name) {
+			return err
+		}
 		return fmt.Errorf("formula not found: %s", name)
 	}
 
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 	lnk := &linker.Linker{Paths: paths}
 
 	fmt.Printf("%s: %s %s\n", f.Name, f.Description, f.Version)
 	fmt.Printf("Homepage: %s\n", f.Homepage)
 	fmt.Printf("License:  %s\n", f.License)
+	if aliases := loader.AliasesOf(f.Name); len(aliases) > 0 {
+		fmt.Printf("Aliases:  %s\n", strings.Join(aliases, ", "))
+	}
+	if members, err := loader.Family(f.Name); err == nil && len(members) > 1 {
+		var others []string
+		for _, m := range members {
+			if m.Name == f.Name {
+				continue
+			}
+			if cel.IsInstalled(m.Name) {
+				others = append(others, m.Name+" (installed)")
+			} else {
+				others = append(others, m.Name)
+			}
+		}
+		fmt.Printf("Other versions: %s\n", strings.Join(others, ", "))
+	}
+	if loader.IsLocal(f.Path) {
+		fmt.Printf("From:     %s\n", f.Path)
+	} else if f.Name != name {
+		Logf("%s resolves to %s\n", name, f.Name)
+	}
+	if status, d := formulaStatus(f); d != nil {
+		fmt.Printf("Warning: %s\n", d.Message(f.Name, status))
+	}
 
 	if cel.IsInstalled(f.Name) {
 		ver, _ := cel.InstalledVersion(f.Name)
 		linked := "not linked"
-		if lnk.IsLinked(f.Name) {
+		if sibling := lnk.LinkedSibling(f.Name); sibling != "" {
+			linked = "not linked, " + sibling + " is linked"
+		} else if lnk.IsLinked(f.Name) {
 			linked = "linked"
 		}
 		fmt.Printf("Installed: %s (%s)\n", ver, linked)
 		Logf("Cellar:    %s\n", cel.KegPath(f.Name, ver))
+		if m, err := snapshot.Load(cel.KegPath(f.Name, ver)); err == nil {
+			if loader.IsLocal(m.FormulaPath) {
+				fmt.Printf("Installed from local formula: %s\n", m.FormulaPath)
+			}
+			if len(m.Options) > 0 {
+				fmt.Printf("Built with: --%s\n", strings.Join(m.Options, " --"))
+			}
+		}
 	} else {
 		fmt.Println("Installed: no")
 	}
@@ -69,12 +112,45 @@ func runInfo(args []string) error {
 	if len(f.Dependencies) > 0 {
 		fmt.Printf("Dependencies: %s\n", strings.Join(f.Dependencies, ", "))
 	}
+	if len(f.OptionalDependencies) > 0 {
+		fmt.Printf("Optional dependencies: %s\n", strings.Join(f.OptionalDependencies, ", "))
+	}
+	if len(f.RecommendedDependencies) > 0 {
+		fmt.Printf("Recommended dependencies: %s\n", strings.Join(f.RecommendedDependencies, ", "))
+	}
+	if opts := f.BuildOptions(); len(opts) > 0 {
+		fmt.Println("Options:")
+		for _, o := range opts {
+			flag := "--with-" + o.Name
+			if o.Default {
+				flag = "--without-" + o.Name
+			}
+			fmt.Printf("  %-24s %s\n", flag, o.Description)
+		}
+	}
+	if len(f.Requirements) > 0 {
+		reqs := make([]string, len(f.Requirements))
+		for i, r := range f.Requirements {
+			reqs[i] = r.String()
+		}
+		fmt.Printf("Requirements: %s\n", strings.Join(reqs, ", "))
+	}
+	if len(f.Conflicts) > 0 {
+		fmt.Printf("Conflicts with: %s\n", strings.Join(f.Conflicts, ", "))
+	}
+	if len(f.Provides) > 0 {
+		fmt.Printf("Provides: %s\n", strings.Join(f.Provides, ", "))
+	}
+	if len(f.Replaces) > 0 {
+		fmt.Printf("Replaces: %s\n", strings.Join(f.Replaces, ", "))
+	}
 
 	platforms := make([]string, 0, len(f.URL))
 	for k := range f.URL {
 		platforms = append(platforms, k)
 	}
 	fmt.Printf("Platforms: %s\n", strings.Join(platforms, ", "))
+	printCaveats(f.Name, f.Caveats)
 
 	return nil
 }
@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/homegrew/grew/internal/cellar"
@@ -14,10 +18,14 @@ import (
 	"github.com/homegrew/grew/internal/downloader"
 	"github.com/homegrew/grew/internal/formula"
 	"github.com/homegrew/grew/internal/linker"
+	"github.com/homegrew/grew/internal/pin"
+	"github.com/homegrew/grew/internal/requirements"
 	"github.com/homegrew/grew/internal/sandbox"
 	"github.com/homegrew/grew/internal/signing"
 	"github.com/homegrew/grew/internal/snapshot"
 	"github.com/homegrew/grew/internal/tap"
+	"github.com/homegrew/grew/internal/validation"
+	"github.com/homegrew/grew/internal/vercmp"
 )
 
 func runInstall(args []string) error {
@@ -25,11 +33,16 @@ func runInstall(args []string) error {
 	isCask := fs.Bool("cask", false, "Install a macOS application cask")
 	buildFromSource := fs.Bool("s", false, "Build from source")
 	fs.BoolVar(buildFromSource, "build-from-source", false, "Build from source")
+	head := fs.Bool("HEAD", false, "Build from the formula's head git source")
 	onlyDeps := fs.Bool("only-dependencies", false, "Install dependencies only")
 	ignoreDeps := fs.Bool("ignore-dependencies", false, "Skip dependency installation")
 	skipPostInstall := fs.Bool("skip-post-install", false, "Skip post-install steps")
 	skipLink := fs.Bool("skip-link", false, "Do not create symlinks")
+	overwrite := fs.Bool("overwrite", false, "Replace files and links that are in the way of linking")
 	requireSHA := fs.Bool("require-sha", false, "Refuse if SHA256 is missing")
+	force := fs.Bool("force", false, "Install even if a formula is disabled")
+	tapPath := fs.String("tap-path", "", "Also load formulas from this directory")
+	args, options := splitOptionFlags(args)
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -37,26 +50,39 @@ func runInstall(args []string) error {
 	if *onlyDeps && *ignoreDeps {
 		return fmt.Errorf("--only-dependencies and --ignore-dependencies are mutually exclusive")
 	}
+	if *skipLink && *overwrite {
+		return fmt.Errorf("--skip-link and --overwrite are mutually exclusive")
+	}
 
 	remaining := fs.Args()
 	if len(remaining) != 1 {
 		if *isCask {
 			return fmt.Errorf("usage: grew install --cask <cask>")
 		}
-		return fmt.Errorf("usage: grew install [-s] [--only-dependencies|--ignore-dependencies] <formula>")
+		return fmt.Errorf("usage: grew install [-s|--HEAD] [--with-<option>|--without-<option>] [--only-dependencies|--ignore-dependencies] [--overwrite|--skip-link] [--tap-path <dir>] <formula|file>")
 	}
 
 	if *isCask {
 		if *buildFromSource {
 			return fmt.Errorf("--build-from-source is not supported for casks")
 		}
+		if *head {
+			return fmt.Errorf("--HEAD is not supported for casks")
+		}
+		if len(options) > 0 {
+			return fmt.Errorf("build options are not supported for casks")
+		}
 		if *onlyDeps {
 			return fmt.Errorf("--only-dependencies is not supported for casks")
 		}
 		if *ignoreDeps {
 			return fmt.Errorf("--ignore-dependencies is not supported for casks")
 		}
-		return caskInstall(remaining[0])
+		return caskInstall(remaining[0], *force)
+	}
+
+	if len(options) > 0 && !*buildFromSource && !*head {
+		return fmt.Errorf("--%s only applies to source builds; add --build-from-source", options[0])
 	}
 
 	name := remaining[0]
@@ -72,22 +98,95 @@ func runInstall(args []string) error {
 	}
 
 	loader := newLoader(paths.Taps)
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 	lnk := &linker.Linker{Paths: paths}
 	dl := &downloader.Downloader{TmpDir: paths.Tmp}
 
-	var installOrder []*formula.Formula
+	if err := addTapPath(loader, *tapPath); err != nil {
+		return err
+	}
+
+	// Formula files, aliases and renamed formulas install under the
+	// formula's own name. A file is loaded once here; from then on the
+	// loader answers to its name, so it goes through the same resolver
+	// and checks as a tap formula.
+	if isFormulaPath(name) {
+		f, err := loader.LoadFile(name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("==> Using formula file %s\n", f.Path)
+		name = f.Name
+	} else if f, err := loader.LoadByName(name); err == nil && f.Name != name {
+		Logf("==> %s resolves to %s\n", name, f.Name)
+		name = f.Name
+	}
+
+	// name@version with no formula of that name is an older version of
+	// the formula, taken from the history of its tap and installed next
+	// to the versions already in the Cellar.
+	installed := installedLookup(cel)
+	if _, err := loader.LoadByName(name); err != nil && validation.FamilyVersion(name) != "" {
+		if *head {
+			return fmt.Errorf("--HEAD cannot be combined with a version (%s)", name)
+		}
+		f, ferr := loader.LoadByName(validation.FamilyName(name))
+		if ferr != nil {
+			return err
+		}
+		old, err := tap.FormulaVersion(f, validation.FamilyVersion(name))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("==> Using %s %s from the history of its tap\n", old.Name, old.PkgVersion())
+		loader.Use(old)
+		name = old.Name
+		version := old.PkgVersion()
+		installed = func(n string) (vercmp.Version, bool) {
+			if n != name {
+				return installedLookup(cel)(n)
+			}
+			if _, err := os.Stat(cel.KegPath(n, version)); err != nil {
+				return vercmp.Version{}, false
+			}
+			return vercmp.ParseKeg(version, old.Revision).WithScheme(old.VersionScheme), true
+		}
+	}
+
+	if *head {
+		f, err := loader.LoadByName(name)
+		if err != nil {
+			return fmt.Errorf("formula not found: %s", name)
+		}
+		if f.Head == nil {
+			return fmt.Errorf("formula %q has no head source", f.Name)
+		}
+	}
+
+	var installOrder []depgraph.Step
 	if *ignoreDeps {
 		f, err := loader.LoadByName(name)
 		if err != nil {
 			return fmt.Errorf("formula not found: %s", name)
 		}
-		installOrder = []*formula.Formula{f}
+		if f, err = f.WithOptions(options); err != nil {
+			return err
+		}
+		step := depgraph.Step{Formula: f}
+		if ver, ok := installed(f.Name); ok {
+			step.Installed = ver.String()
+		}
+		installOrder = []depgraph.Step{step}
 	} else {
-		resolver := &depgraph.Resolver{Loader: loader}
+		resolver := &depgraph.Resolver{
+			Loader:       loader,
+			Installed:    installed,
+			IncludeBuild: *buildFromSource || *head,
+			Options:      options,
+		}
 		Debugf("resolving dependencies for %s\n", name)
 		var err error
-		installOrder, err = resolver.Resolve(name)
+		installOrder, err = resolver.Plan(name)
 		if err != nil {
 			return err
 		}
@@ -96,18 +195,78 @@ func runInstall(args []string) error {
 
 	if Verbose && len(installOrder) > 1 {
 		names := make([]string, len(installOrder))
-		for i, f := range installOrder {
-			names[i] = f.Name
+		for i, s := range installOrder {
+			names[i] = s.Formula.Name
 		}
 		Logf("==> Install order: %s\n", fmt.Sprintf("%v", names))
 	}
 
+	// Refuse disabled formulas before anything is downloaded.
+	if !*force {
+		for _, s := range installOrder {
+			f := s.Formula
+			if s.Installed != "" || (*onlyDeps && f.Name == name) {
+				continue
+			}
+			if f.IsDisabled() {
+				return fmt.Errorf("%s\nUse --force to install it anyway", f.Disabled.Message(f.Name, "disabled"))
+			}
+		}
+	}
+
+	// A pinned formula is not upgraded to meet a version constraint.
+	pins, err := pin.Load(paths.Root)
+	if err != nil {
+		return err
+	}
+	for _, s := range installOrder {
+		if f := s.Formula; s.Replaces != "" && pins[f.Name] {
+			return fmt.Errorf("cannot install %s: it needs %s %s upgraded to %s, but %s is pinned\nRun 'grew unpin %s' to allow the upgrade",
+				name, f.Name, s.Replaces, f.PkgVersion(), f.Name, f.Name)
+		}
+	}
+	// Nor when the upgrade would break an installed dependent.
+	var dependents []*formula.Formula
+	for _, s := range installOrder {
+		if s.Replaces == "" {
+			continue
+		}
+		if dependents == nil {
+			dependents = installedFormulas(loader, cel)
+		}
+		if reqs := depgraph.Unsatisfied(s.Formula.Name, s.Formula.ParsedVersion(), dependents); len(reqs) > 0 {
+			return fmt.Errorf("cannot install %s: it needs %s %s upgraded to %s, which would break %s",
+				name, s.Formula.Name, s.Replaces, s.Formula.PkgVersion(), describeRequirements(reqs))
+		}
+	}
+
+	skip := ""
+	if *onlyDeps {
+		skip = name
+	}
+	if err := checkInstalledConflicts(installOrder, skip, cel, loader); err != nil {
+		return err
+	}
+	if err := checkPlanRequirements(installOrder, skip); err != nil {
+		return err
+	}
+	if !*skipLink && !*overwrite {
+		if err := checkLinkConflicts(installOrder, name, skip, cel, lnk); err != nil {
+			return err
+		}
+	}
+
 	if *requireSHA {
-		for _, f := range installOrder {
+		for _, s := range installOrder {
+			f := s.Formula
 			if *onlyDeps && f.Name == name {
 				continue
 			}
-			if cel.IsInstalled(f.Name) {
+			if s.Installed != "" {
+				continue
+			}
+			if *head && f.Name == name {
+				// A head build is pinned by the commit it clones.
 				continue
 			}
 			if *buildFromSource && f.Name == name {
@@ -122,25 +281,50 @@ func runInstall(args []string) error {
 		}
 	}
 
-	for _, f := range installOrder {
+	for _, s := range installOrder {
+		f := s.Formula
 		if *onlyDeps && f.Name == name {
 			continue
 		}
 
-		if cel.IsInstalled(f.Name) {
-			fmt.Printf("==> %s %s is already installed, skipping\n", f.Name, f.Version)
+		if s.Installed != "" {
+			fmt.Printf("==> %s %s is already installed, skipping\n", f.Name, s.Installed)
 			continue
 		}
 
-		if *buildFromSource && f.Name == name {
-			if err := installFormulaFromSource(f, paths, cel, lnk, dl, *skipPostInstall, *skipLink); err != nil {
+		if s.Replaces != "" {
+			fmt.Printf("==> %s %s does not satisfy version constraints\n", f.Name, s.Replaces)
+			if err := upgradeFormula(outdatedPkg{formula: f, installedVersion: s.Replaces, build: s.Build}, paths, cel, lnk, dl); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Formulas this one replaces are unlinked now so it can link,
+		// and removed once it is installed.
+		replaced := installedReplaced(f, cel)
+		for _, old := range replaced {
+			fmt.Printf("==> %s replaces %s, unlinking %s\n", f.Name, old, old)
+			lnk.Unlink(old)
+		}
+
+		if (*buildFromSource || *head) && f.Name == name {
+			depKegs := buildDepKegs(installOrder, name, cel)
+			if err := installFormulaFromSource(f, depKegs, paths, cel, lnk, dl, *head, *skipPostInstall, *skipLink, *overwrite); err != nil {
+				return err
+			}
+		} else if s.Build {
+			// Build-only dependencies stay in the Cellar, unlinked.
+			fmt.Printf("==> %s is a build dependency of %s\n", f.Name, name)
+			if err := installFormula(f, paths, cel, lnk, dl, *skipPostInstall, true, false); err != nil {
 				return err
 			}
 		} else {
-			if err := installFormula(f, paths, cel, lnk, dl, *skipPostInstall, *skipLink); err != nil {
+			if err := installFormula(f, paths, cel, lnk, dl, *skipPostInstall, *skipLink, *overwrite); err != nil {
 				return err
 			}
 		}
+		removeReplaced(replaced, cel)
 	}
 
 	return nil
@@ -148,10 +332,11 @@ func runInstall(args []string) error {
 
 // installFormula downloads, verifies, extracts, and links a single formula.
 // Shared by install and upgrade commands.
-func installFormula(f *formula.Formula, paths config.Paths, cel *cellar.Cellar, lnk *linker.Linker, dl *downloader.Downloader, skipPostInstall bool, skipLink bool) error {
-	defer TimeOp(fmt.Sprintf("install %s %s", f.Name, f.Version))()
+func installFormula(f *formula.Formula, paths config.Paths, cel *cellar.Cellar, lnk *linker.Linker, dl *downloader.Downloader, skipPostInstall bool, skipLink bool, overwrite bool) error {
+	defer TimeOp(fmt.Sprintf("install %s %s", f.Name, f.PkgVersion()))()
 	Debugf("platform: %s, install type: %s, keg_only: %v\n", formula.PlatformKey(), f.Install.Type, f.KegOnly)
-	fmt.Printf("==> Installing %s %s\n", f.Name, f.Version)
+	fmt.Printf("==> Installing %s %s\n", f.Name, f.PkgVersion())
+	warnDeprecated(f)
 
 	dlURL, err := f.GetURL()
 	if err != nil {
@@ -169,7 +354,7 @@ func installFormula(f *formula.Formula, paths config.Paths, cel *cellar.Cellar,
 	if ext == "" && f.Install.Format != "" {
 		ext = "." + f.Install.Format
 	}
-	filename := f.Name + "-" + f.Version + ext
+	filename := f.Name + "-" + f.PkgVersion() + ext
 	localFile, err := dl.Download(dlURL, filename)
 	if err != nil {
 		return fmt.Errorf("download %s: %w", f.Name, err)
@@ -187,7 +372,7 @@ func installFormula(f *formula.Formula, paths config.Paths, cel *cellar.Cellar,
 		return err
 	}
 
-	stageDir := filepath.Join(paths.Tmp, f.Name+"-"+f.Version+"-stage")
+	stageDir := filepath.Join(paths.Tmp, f.Name+"-"+f.PkgVersion()+"-stage")
 	os.RemoveAll(stageDir)
 
 	if err := downloader.Extract(localFile, stageDir, f.Install); err != nil {
@@ -197,16 +382,18 @@ func installFormula(f *formula.Formula, paths config.Paths, cel *cellar.Cellar,
 	}
 	Logf("    Extracted to staging: %s\n", stageDir)
 
-	kegPath := cel.KegPath(f.Name, f.Version)
-	if err := cel.Install(f.Name, f.Version, stageDir); err != nil {
+	kegPath := cel.KegPath(f.Name, f.PkgVersion())
+	if err := cel.Install(f.Name, f.PkgVersion(), stageDir); err != nil {
 		os.RemoveAll(stageDir)
 		os.Remove(localFile)
 		return fmt.Errorf("cellar install %s: %w", f.Name, err)
 	}
 	Logf("    Installed to cellar: %s\n", kegPath)
 
+	var sibling string
+	var links []snapshot.LinkEntry
 	if !skipLink {
-		if err := lnk.Link(f.Name, f.Version, f.KegOnly); err != nil {
+		if sibling, links, err = linkKeg(f, lnk, overwrite); err != nil {
 			return fmt.Errorf("link %s: %w", f.Name, err)
 		}
 		Logf("    Linked: opt/%s -> %s\n", f.Name, kegPath)
@@ -217,9 +404,14 @@ func installFormula(f *formula.Formula, paths config.Paths, cel *cellar.Cellar,
 		Platform:       formula.PlatformKey(),
 		DownloadURL:    dlURL,
 		DownloadSHA256: sha,
-		Dependencies:   f.Dependencies,
+		FormulaPath:    f.Path,
+		Dependencies:   f.DependencyNames(),
+		Links:          links,
+		VersionScheme:  f.VersionScheme,
+		Revision:       f.Revision,
+		BottleRebuild:  f.BottleRebuild(),
 	}
-	manifest, snapErr := snapshot.Capture(f.Name, f.Version, kegPath, meta)
+	manifest, snapErr := snapshot.Capture(f.Name, f.PkgVersion(), kegPath, meta)
 	if snapErr != nil {
 		Logf("    Warning: could not capture snapshot: %v\n", snapErr)
 	} else {
@@ -237,152 +429,537 @@ func installFormula(f *formula.Formula, paths config.Paths, cel *cellar.Cellar,
 	}
 
 	if f.KegOnly {
-		fmt.Printf("==> %s %s installed (keg-only, not linked)\n", f.Name, f.Version)
+		fmt.Printf("==> %s %s installed (keg-only, not linked)\n", f.Name, f.PkgVersion())
 	} else if skipLink {
-		fmt.Printf("==> %s %s installed (linking skipped)\n", f.Name, f.Version)
+		fmt.Printf("==> %s %s installed (linking skipped)\n", f.Name, f.PkgVersion())
+	} else if sibling != "" {
+		fmt.Printf("==> %s %s installed (not linked, %s is linked)\n", f.Name, f.PkgVersion(), sibling)
+		fmt.Printf("Run 'grew link --overwrite %s' to link it instead.\n", f.Name)
 	} else {
-		fmt.Printf("==> %s %s installed and linked\n", f.Name, f.Version)
+		fmt.Printf("==> %s %s installed and linked\n", f.Name, f.PkgVersion())
 	}
+	printCaveats(f.Name, f.Caveats)
 	return nil
 }
 
 // installFormulaFromSource downloads the source tarball and builds from source
 // inside a sandboxed environment (no network, restricted filesystem access).
-func installFormulaFromSource(f *formula.Formula, paths config.Paths, cel *cellar.Cellar, lnk *linker.Linker, dl *downloader.Downloader, skipPostInstall bool, skipLink bool) error {
-	defer TimeOp(fmt.Sprintf("build from source %s %s", f.Name, f.Version))()
-	fmt.Printf("==> Building %s %s from source\n", f.Name, f.Version)
-
-	srcURL, err := f.GetSourceURL()
-	if err != nil {
-		return err
+// depKegs are the installed kegs of its runtime and build dependencies; their
+// bin directories are put on the build's PATH. With head, the formula's head
+// branch is cloned instead and the keg is versioned HEAD-<commit>.
+func installFormulaFromSource(f *formula.Formula, depKegs []string, paths config.Paths, cel *cellar.Cellar, lnk *linker.Linker, dl *downloader.Downloader, head bool, skipPostInstall bool, skipLink bool, overwrite bool) error {
+	// The keg version of a head build is only known once it is cloned.
+	var commit, cloneDir string
+	if head {
+		if f.Head == nil {
+			return fmt.Errorf("formula %q has no head source", f.Name)
+		}
+		cloneDir = filepath.Join(paths.Tmp, f.Name+"-HEAD-clone")
+		os.RemoveAll(cloneDir)
+		fmt.Printf("==> Cloning %s\n", f.Head.URL)
+		var err error
+		commit, err = downloader.Clone(f.Head.URL, f.Head.Branch, cloneDir)
+		if err != nil {
+			return fmt.Errorf("clone %s: %w", f.Name, err)
+		}
+		Logf("    Commit: %s\n", commit)
+		hf := *f
+		hf.Version = formula.HeadVersion(commit)
+		hf.Revision = 0
+		f = &hf
 	}
-	Logf("    Source URL: %s\n", srcURL)
 
-	srcSHA, err := f.GetSourceSHA256()
-	if err != nil {
-		return err
+	defer TimeOp(fmt.Sprintf("build from source %s %s", f.Name, f.PkgVersion()))()
+	fmt.Printf("==> Building %s %s from source\n", f.Name, f.PkgVersion())
+	if used := f.UsedOptions(); len(used) > 0 {
+		fmt.Printf("==> Options: --%s\n", strings.Join(used, " --"))
 	}
-	Logf("    Expected SHA256: %s\n", srcSHA)
+	warnDeprecated(f)
 
-	ext := urlExt(srcURL)
-	filename := f.Name + "-" + f.Version + "-src" + ext
-	localFile, err := dl.Download(srcURL, filename)
+	buildSpec, err := f.Build.Resolve()
 	if err != nil {
-		return fmt.Errorf("download source %s: %w", f.Name, err)
+		os.RemoveAll(cloneDir)
+		return fmt.Errorf("formula %q: %w", f.Name, err)
 	}
-	Logf("    Saved to: %s\n", localFile)
 
-	if err := downloader.VerifySHA256(localFile, srcSHA); err != nil {
-		os.Remove(localFile)
-		return fmt.Errorf("verify source %s: %w", f.Name, err)
+	var srcURL, srcSHA, localFile string
+	if head {
+		srcURL = f.Head.URL
+	} else {
+		srcURL, err = f.GetSourceURL()
+		if err != nil {
+			return err
+		}
+		Logf("    Source URL: %s\n", srcURL)
+
+		srcSHA, err = f.GetSourceSHA256()
+		if err != nil {
+			return err
+		}
+		Logf("    Expected SHA256: %s\n", srcSHA)
+
+		ext := urlExt(srcURL)
+		filename := f.Name + "-" + f.Version + "-src" + ext
+		localFile, err = dl.Download(srcURL, filename)
+		if err != nil {
+			return fmt.Errorf("download source %s: %w", f.Name, err)
+		}
+		Logf("    Saved to: %s\n", localFile)
+
+		if err := downloader.VerifySHA256(localFile, srcSHA); err != nil {
+			os.Remove(localFile)
+			return fmt.Errorf("verify source %s: %w", f.Name, err)
+		}
+		fmt.Printf("==> SHA256 verified\n")
+
+		if err := verifySignature(f.Name, srcSHA, f.GetSourceSignature(), paths.Root); err != nil {
+			os.Remove(localFile)
+			return err
+		}
 	}
-	fmt.Printf("==> SHA256 verified\n")
 
-	if err := verifySignature(f.Name, srcSHA, f.GetSourceSignature(), paths.Root); err != nil {
-		os.Remove(localFile)
-		return err
+	// Fetch and verify resources and patches before touching the build dir.
+	downloads := []string{localFile}
+	if head {
+		downloads = []string{cloneDir}
+	}
+	removeDownloads := func() {
+		for _, p := range downloads {
+			os.RemoveAll(p)
+		}
+	}
+	resourceFiles := make([]string, len(f.Resources))
+	for i, r := range f.Resources {
+		label := fmt.Sprintf("%s resource %s", f.Name, r.Name)
+		filename := f.Name + "-" + f.Version + "-" + r.Name + urlExt(r.URL)
+		file, err := fetchVerified(dl, r.URL, filename, r.SHA256, r.Signature, label, paths.Root)
+		if err != nil {
+			removeDownloads()
+			return err
+		}
+		downloads = append(downloads, file)
+		resourceFiles[i] = file
+	}
+	patchFiles := make([]string, len(f.Patches))
+	for i, p := range f.Patches {
+		label := fmt.Sprintf("%s patch %s", f.Name, p.Location())
+		if p.File != "" {
+			file, err := f.PatchPath(p)
+			if err == nil {
+				err = verifyLocal(file, p.SHA256, p.Signature, label, paths.Root)
+			}
+			if err != nil {
+				removeDownloads()
+				return err
+			}
+			patchFiles[i] = file
+			continue
+		}
+		filename := fmt.Sprintf("%s-%s-patch%d%s", f.Name, f.Version, i, urlExt(p.URL))
+		file, err := fetchVerified(dl, p.URL, filename, p.SHA256, p.Signature, label, paths.Root)
+		if err != nil {
+			removeDownloads()
+			return err
+		}
+		downloads = append(downloads, file)
+		patchFiles[i] = file
 	}
 
-	// Extract source to a build directory.
-	buildDir := filepath.Join(paths.Tmp, f.Name+"-"+f.Version+"-build")
+	// Extract source to a build directory; a head clone becomes it.
+	buildDir := filepath.Join(paths.Tmp, f.Name+"-"+f.PkgVersion()+"-build")
 	os.RemoveAll(buildDir)
-	srcSpec := formula.InstallSpec{Type: "archive", StripComponents: 1, Format: f.Install.Format}
-	if err := downloader.Extract(localFile, buildDir, srcSpec); err != nil {
-		os.RemoveAll(buildDir)
-		os.Remove(localFile)
-		return fmt.Errorf("extract source %s: %w", f.Name, err)
+	if head {
+		if err := os.Rename(cloneDir, buildDir); err != nil {
+			removeDownloads()
+			return fmt.Errorf("stage clone of %s: %w", f.Name, err)
+		}
+	} else {
+		srcSpec := formula.InstallSpec{Type: "archive", StripComponents: 1, Format: f.Install.Format}
+		if err := downloader.Extract(localFile, buildDir, srcSpec); err != nil {
+			os.RemoveAll(buildDir)
+			removeDownloads()
+			return fmt.Errorf("extract source %s: %w", f.Name, err)
+		}
 	}
+	defer func() {
+		os.RemoveAll(buildDir)
+		removeDownloads()
+	}()
 	Logf("    Extracted source to: %s\n", buildDir)
 
+	// Stage resources into named subdirectories of the build tree.
+	for i, r := range f.Resources {
+		dest := filepath.Join(buildDir, r.Name)
+		if err := downloader.ExtractArchive(resourceFiles[i], dest, 1); err != nil {
+			return fmt.Errorf("extract resource %s for %s: %w", r.Name, f.Name, err)
+		}
+		Logf("    Staged resource %s in %s\n", r.Name, dest)
+	}
+
 	// Prepare keg directory.
-	kegPath := cel.KegPath(f.Name, f.Version)
+	kegPath := cel.KegPath(f.Name, f.PkgVersion())
 	if err := os.MkdirAll(kegPath, 0755); err != nil {
-		os.RemoveAll(buildDir)
-		os.Remove(localFile)
 		return fmt.Errorf("create keg dir: %w", err)
 	}
 
 	// Collect dependency paths for sandbox read-only access.
-	var depPaths []string
-	for _, dep := range f.Dependencies {
-		depPaths = append(depPaths, filepath.Join(paths.Cellar, dep))
-		depPaths = append(depPaths, filepath.Join(paths.Opt, dep))
+	var depPaths, binPaths []string
+	for _, keg := range depKegs {
+		depPaths = append(depPaths, keg)
+		depPaths = append(depPaths, filepath.Join(paths.Opt, filepath.Base(filepath.Dir(keg))))
+		binPaths = append(binPaths, filepath.Join(keg, "bin"))
 	}
 
 	sbCfg := sandbox.BuildConfig{
 		BuildDir: buildDir,
 		KegDir:   kegPath,
 		DepPaths: depPaths,
-	}
-
-	cleanup := func() {
-		os.RemoveAll(buildDir)
-		os.Remove(localFile)
-	}
-	cleanupAll := func() {
-		cleanup()
-		os.RemoveAll(kegPath)
+		BinPaths: binPaths,
 	}
 
 	fmt.Printf("==> Sandboxed build (network denied, filesystem restricted)\n")
 	Debugf("sandbox config: build=%s keg=%s deps=%v\n", buildDir, kegPath, depPaths)
 
-	// ./configure --prefix=<keg>
-	fmt.Printf("==> ./configure --prefix=%s\n", kegPath)
-	configure := sandbox.Command(sbCfg, "./configure", "--prefix="+kegPath)
-	configure.Dir = buildDir
-	configure.Stdout = os.Stdout
-	configure.Stderr = os.Stderr
-	if err := configure.Run(); err != nil {
-		cleanupAll()
-		return fmt.Errorf("configure %s: %w", f.Name, err)
-	}
-
-	// make
-	fmt.Printf("==> make\n")
-	makeCmd := sandbox.Command(sbCfg, "make")
-	makeCmd.Dir = buildDir
-	makeCmd.Stdout = os.Stdout
-	makeCmd.Stderr = os.Stderr
-	if err := makeCmd.Run(); err != nil {
-		cleanupAll()
-		return fmt.Errorf("make %s: %w", f.Name, err)
-	}
-
-	// make install
-	fmt.Printf("==> make install\n")
-	makeInstall := sandbox.Command(sbCfg, "make", "install")
-	makeInstall.Dir = buildDir
-	makeInstall.Stdout = os.Stdout
-	makeInstall.Stderr = os.Stderr
-	if err := makeInstall.Run(); err != nil {
-		cleanupAll()
-		return fmt.Errorf("make install %s: %w", f.Name, err)
+	for i, p := range f.Patches {
+		level := strconv.Itoa(p.StripLevel())
+		fmt.Printf("==> Applying patch %s\n", p.Location())
+		cmd := sandbox.Command(sbCfg, "patch", "-p"+level, "--batch", "-i", patchFiles[i])
+		cmd.Dir = buildDir
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			os.RemoveAll(kegPath)
+			return fmt.Errorf("apply patch %s to %s: %w", p.Location(), f.Name, err)
+		}
+	}
+
+	// Run the configure then install phases, each step via sh -c.
+	vars := map[string]string{
+		"prefix":  kegPath,
+		"jobs":    strconv.Itoa(runtime.NumCPU()),
+		"name":    f.Name,
+		"version": f.Version,
+	}
+	for k, v := range f.OptionVars() {
+		vars[k] = v
+	}
+	phases := []struct {
+		name  string
+		steps []string
+	}{
+		{"configure", buildSpec.Configure},
+		{"install", buildSpec.Install},
+	}
+	for _, phase := range phases {
+		for _, step := range phase.steps {
+			line := formula.ExpandBuildStep(step, vars)
+			fmt.Printf("==> %s\n", line)
+			cmd := sandbox.Command(sbCfg, "sh", "-c", line)
+			cmd.Dir = buildDir
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				os.RemoveAll(kegPath)
+				return fmt.Errorf("%s %s: %s: %w", phase.name, f.Name, line, err)
+			}
+		}
 	}
 
+	var sibling string
+	var links []snapshot.LinkEntry
 	if !skipLink {
-		if err := lnk.Link(f.Name, f.Version, f.KegOnly); err != nil {
+		if sibling, links, err = linkKeg(f, lnk, overwrite); err != nil {
 			return fmt.Errorf("link %s: %w", f.Name, err)
 		}
 		Logf("    Linked: opt/%s -> %s\n", f.Name, kegPath)
 	}
 
-	cleanup()
+	// Build dependencies are deliberately not recorded: only runtime
+	// dependencies matter once the keg exists.
+	meta := snapshot.InstallMeta{
+		Platform:       formula.PlatformKey(),
+		DownloadURL:    srcURL,
+		DownloadSHA256: srcSHA,
+		FormulaPath:    f.Path,
+		HeadCommit:     commit,
+		Dependencies:   f.DependencyNames(),
+		Links:          links,
+		Options:        f.UsedOptions(),
+		VersionScheme:  f.VersionScheme,
+		Revision:       f.Revision,
+	}
+	if manifest, err := snapshot.Capture(f.Name, f.PkgVersion(), kegPath, meta); err != nil {
+		Logf("    Warning: could not capture snapshot: %v\n", err)
+	} else if err := snapshot.Save(manifest, kegPath); err != nil {
+		Logf("    Warning: could not save snapshot: %v\n", err)
+	}
 
 	if err := runPostInstall(f, kegPath, skipPostInstall); err != nil {
 		return err
 	}
 
 	if f.KegOnly {
-		fmt.Printf("==> %s %s built from source and installed (keg-only, not linked)\n", f.Name, f.Version)
+		fmt.Printf("==> %s %s built from source and installed (keg-only, not linked)\n", f.Name, f.PkgVersion())
 	} else if skipLink {
-		fmt.Printf("==> %s %s built from source and installed (linking skipped)\n", f.Name, f.Version)
+		fmt.Printf("==> %s %s built from source and installed (linking skipped)\n", f.Name, f.PkgVersion())
+	} else if sibling != "" {
+		fmt.Printf("==> %s %s built from source and installed (not linked, %s is linked)\n", f.Name, f.PkgVersion(), sibling)
+		fmt.Printf("Run 'grew link --overwrite %s' to link it instead.\n", f.Name)
 	} else {
-		fmt.Printf("==> %s %s built from source and installed\n", f.Name, f.Version)
+		fmt.Printf("==> %s %s built from source and installed\n", f.Name, f.PkgVersion())
+	}
+	printCaveats(f.Name, f.Caveats)
+	return nil
+}
+
+// fetchVerified downloads url and checks its SHA256 and signature. The file
+// is removed again if either check fails.
+func fetchVerified(dl *downloader.Downloader, url, filename, sha, signature, label, grewRoot string) (string, error) {
+	file, err := dl.Download(url, filename)
+	if err != nil {
+		return "", fmt.Errorf("download %s: %w", label, err)
+	}
+	Logf("    Saved to: %s\n", file)
+	if err := verifyLocal(file, sha, signature, label, grewRoot); err != nil {
+		os.Remove(file)
+		return "", err
+	}
+	return file, nil
+}
+
+// verifyLocal checks the SHA256 and signature of a file already on disk.
+func verifyLocal(file, sha, signature, label, grewRoot string) error {
+	if err := downloader.VerifySHA256(file, sha); err != nil {
+		return fmt.Errorf("verify %s: %w", label, err)
+	}
+	Logf("    SHA256 verified: %s\n", label)
+	return verifySignature(label, sha, signature, grewRoot)
+}
+
+// checkInstalledConflicts fails if a formula the plan would install
+// conflicts with an installed formula outside the plan. Installed formulas
+// that are no longer in any tap still count through the new formula's own
+// conflicts_with. skip names a plan entry that will not be installed.
+func checkInstalledConflicts(plan []depgraph.Step, skip string, cel *cellar.Cellar, loader *formula.Loader) error {
+	pkgs, err := cel.List()
+	if err != nil {
+		return err
+	}
+	inPlan := make(map[string]bool, len(plan))
+	for _, s := range plan {
+		inPlan[s.Formula.Name] = true
+	}
+	var installed []*formula.Formula
+	for _, pkg := range pkgs {
+		if inPlan[pkg.Name] {
+			continue
+		}
+		f, err := loader.LoadByName(pkg.Name)
+		if err != nil {
+			f = &formula.Formula{Name: pkg.Name}
+		}
+		installed = append(installed, f)
+	}
+
+	for _, s := range plan {
+		if s.Installed != "" || s.Formula.Name == skip {
+			continue
+		}
+		for _, other := range installed {
+			if ok, reason := s.Formula.ConflictsWith(other); ok {
+				return fmt.Errorf("cannot install %s: it conflicts with the installed %s (%s)\nUninstall %s first", s.Formula.Name, other.Name, reason, other.Name)
+			}
+		}
 	}
 	return nil
 }
 
+// checkPlanRequirements checks the requirements: block of every formula the
+// plan would install against this machine, and reports all the unmet ones
+// at once. skip names a plan entry that will not be installed.
+func checkPlanRequirements(plan []depgraph.Step, skip string) error {
+	checker := &requirements.Checker{}
+	var errs []error
+	for _, s := range plan {
+		if s.Installed != "" || s.Formula.Name == skip {
+			continue
+		}
+		Debugf("checking requirements of %s\n", s.Formula.Name)
+		if err := checker.Check(s.Formula); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkLinkConflicts predicts the links each formula the plan would
+// install and link is going to create, and reports every path already
+// taken, with what holds it, before anything is downloaded. Formulas
+// without a known file list (see predictLinks) are checked when they
+// are linked. skip names a plan entry that will not be installed.
+func checkLinkConflicts(plan []depgraph.Step, name, skip string, cel *cellar.Cellar, lnk *linker.Linker) error {
+	var conflicts []string
+	planned := make(map[string]string) // path -> plan formula linking it
+	families := lnk.LinkedFamilies()
+	for _, s := range plan {
+		f := s.Formula
+		if s.Installed != "" || s.Build || f.Name == skip || f.KegOnly || families.Sibling(f.Name) != "" {
+			continue
+		}
+		files := predictLinks(f, cel)
+		if files == nil {
+			Debugf("no file list for %s, checking its links at link time\n", f.Name)
+			continue
+		}
+		replaced := installedReplaced(f, cel)
+		for _, c := range lnk.Conflicts(f.Name, files) {
+			if !slices.Contains(replaced, c.Owner) {
+				conflicts = append(conflicts, fmt.Sprintf("%s: %s", f.Name, c))
+			}
+		}
+		for _, file := range files {
+			if !lnk.Linkable(file) {
+				continue
+			}
+			if other, ok := planned[file]; ok && other != f.Name {
+				conflicts = append(conflicts, fmt.Sprintf("%s: %s is also linked by %s, installed before it", f.Name, file, other))
+			}
+			planned[file] = f.Name
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("cannot link %s: %d path(s) in the way, nothing was downloaded\n  %s\nRun 'grew install --overwrite %s' to replace them, or 'grew install --skip-link %s' to install without linking",
+		name, len(conflicts), strings.Join(conflicts, "\n  "), name, name)
+}
+
+// predictLinks returns the keg paths installing f is expected to link:
+// the files its bottle lists, the binary of an install of type binary,
+// or the files of a keg of f already in the Cellar. It returns nil when
+// there is nothing to go on.
+func predictLinks(f *formula.Formula, cel *cellar.Cellar) []string {
+	if files := f.BottleFiles(); len(files) > 0 {
+		return files
+	}
+	if f.Install.Type == "binary" && f.Install.BinaryName != "" {
+		return []string{"bin/" + f.Install.BinaryName}
+	}
+	versions, _ := cel.InstalledVersions(f.Name)
+	for i := len(versions) - 1; i >= 0; i-- {
+		m, err := snapshot.Load(cel.KegPath(f.Name, versions[i]))
+		if err != nil {
+			continue
+		}
+		var files []string
+		for _, e := range m.Files {
+			if !e.Mode.IsDir() {
+				files = append(files, filepath.ToSlash(e.Path))
+			}
+		}
+		return files
+	}
+	return nil
+}
+
+// linkKeg links an installed keg and returns the links created, for its
+// manifest. When another member of its versioned family is linked, only
+// the opt link is created, and that member is returned so the caller can
+// say why. With overwrite, files and other formulas' links in the way are
+// replaced.
+func linkKeg(f *formula.Formula, lnk *linker.Linker, overwrite bool) (string, []snapshot.LinkEntry, error) {
+	sibling := lnk.LinkedSibling(f.Name)
+	links, err := lnk.LinkWithOpts(f.Name, f.PkgVersion(), linker.LinkOpts{
+		KegOnly:   f.KegOnly || sibling != "",
+		Overwrite: overwrite,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return sibling, links, nil
+}
+
+// buildDepKegs returns the keg of every formula in plan other than root,
+// using the installed version where the plan keeps one.
+func buildDepKegs(plan []depgraph.Step, root string, cel *cellar.Cellar) []string {
+	var kegs []string
+	for _, s := range plan {
+		if s.Formula.Name == root {
+			continue
+		}
+		ver := s.Formula.PkgVersion()
+		if s.Installed != "" {
+			ver = s.Installed
+		}
+		kegs = append(kegs, cel.KegPath(s.Formula.Name, ver))
+	}
+	return kegs
+}
+
+// splitOptionFlags separates the --with-<option> and --without-<option>
+// flags, which cannot be declared up front, from the other arguments.
+// Options are returned without their leading dashes.
+func splitOptionFlags(args []string) ([]string, []string) {
+	var rest, options []string
+	for _, a := range args {
+		if _, _, ok := formula.ParseOptionFlag(a); ok && strings.HasPrefix(a, "-") {
+			options = append(options, strings.TrimLeft(a, "-"))
+			continue
+		}
+		rest = append(rest, a)
+	}
+	return rest, options
+}
+
+// buildWithDependencies builds f from source, or from its head with head,
+// with the given option flags, after installing the runtime and build
+// dependencies it lacks. Reinstall and upgrade use it to reproduce a keg
+// that was built with --HEAD or with options.
+func buildWithDependencies(f *formula.Formula, head bool, options []string, loader *formula.Loader, paths config.Paths, cel *cellar.Cellar, lnk *linker.Linker, dl *downloader.Downloader) error {
+	resolver := &depgraph.Resolver{
+		Loader:       loader,
+		Installed:    installedLookup(cel),
+		IncludeBuild: true,
+		Options:      options,
+	}
+	plan, err := resolver.Plan(f.Name)
+	if err != nil {
+		return err
+	}
+	if err := checkPlanRequirements(plan, ""); err != nil {
+		return err
+	}
+	root := f
+	for _, s := range plan {
+		switch {
+		case s.Formula.Name == f.Name:
+			root = s.Formula
+			continue
+		case s.Installed != "":
+			continue
+		case s.Replaces != "":
+			err = upgradeFormula(outdatedPkg{formula: s.Formula, installedVersion: s.Replaces, build: s.Build}, paths, cel, lnk, dl)
+		default:
+			err = installFormula(s.Formula, paths, cel, lnk, dl, false, s.Build, false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return installFormulaFromSource(root, buildDepKegs(plan, f.Name, cel), paths, cel, lnk, dl, head, false, false, false)
+}
+
+// installedLookup adapts the cellar for depgraph.Resolver.Installed.
+func installedLookup(cel *cellar.Cellar) func(string) (vercmp.Version, bool) {
+	return func(name string) (vercmp.Version, bool) {
+		ver, err := cel.InstalledVersion(name)
+		if err != nil {
+			return vercmp.Version{}, false
+		}
+		return cel.KegVersion(name, ver), true
+	}
+}
+
 // urlExt extracts the file extension from a URL path (e.g. ".tar.gz", ".zip").
 func urlExt(rawURL string) string {
 	u, err := url.Parse(rawURL)
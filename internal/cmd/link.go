@@ -7,6 +7,7 @@ import (
 	"github.com/homegrew/grew/internal/cellar"
 	"github.com/homegrew/grew/internal/config"
 	"github.com/homegrew/grew/internal/linker"
+	"github.com/homegrew/grew/internal/snapshot"
 	"github.com/homegrew/grew/internal/tap"
 )
 
@@ -15,7 +16,7 @@ func runLink(args []string) error {
 	overwrite := fs.Bool("overwrite", false, "Overwrite existing files")
 	dryRun := fs.Bool("dry-run", false, "Show what would be linked")
 	fs.BoolVar(dryRun, "n", false, "Show what would be linked")
-	force := fs.Bool("force", false, "Link keg-only formula into bin/, lib/, include/")
+	force := fs.Bool("force", false, "Link keg-only formula into the prefix")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -26,7 +27,7 @@ func runLink(args []string) error {
 	name := fs.Arg(0)
 
 	paths := config.Default()
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 
 	if !cel.IsInstalled(name) {
 		return fmt.Errorf("formula %q is not installed", name)
@@ -61,15 +62,28 @@ func runLink(args []string) error {
 		DryRun:    *dryRun,
 		Force:     *force,
 	}
-	if err := lnk.LinkWithOpts(name, ver, opts); err != nil {
+	// With --overwrite, a linked member of the same versioned family is
+	// unlinked to make way.
+	sibling := ""
+	if !kegOnly || *force {
+		sibling = lnk.LinkedSibling(name)
+	}
+	links, err := lnk.LinkWithOpts(name, ver, opts)
+	if err != nil {
 		return err
 	}
 	Logf("    opt/%s -> %s\n", name, cel.KegPath(name, ver))
 	if !kegOnly || *force {
-		Logf("    Symlinked bin/, lib/, include/ contents\n")
+		Logf("    Symlinked bin/, sbin/, lib/, include/, etc/, share/ contents\n")
+	}
+	if !*dryRun && snapshot.Exists(cel.KegPath(name, ver)) {
+		Logf("    Recorded %d links in the keg manifest\n", len(links))
 	}
 
 	if !*dryRun {
+		if sibling != "" {
+			fmt.Printf("==> %s unlinked\n", sibling)
+		}
 		fmt.Printf("==> %s %s linked\n", name, ver)
 	}
 	return nil
@@ -89,7 +103,7 @@ func runUnlink(args []string) error {
 	name := fs.Arg(0)
 
 	paths := config.Default()
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 
 	if !cel.IsInstalled(name) {
 		return fmt.Errorf("formula %q is not installed", name)
@@ -103,7 +117,7 @@ func runUnlink(args []string) error {
 	if *dryRun {
 		Logf("    (dry run, no changes made)\n")
 	} else {
-		Logf("    Removed symlinks from bin/, lib/, include/, opt/\n")
+		Logf("    Removed symlinks from bin/, sbin/, lib/, include/, etc/, share/, opt/\n")
 		fmt.Printf("==> %s unlinked\n", name)
 	}
 	return nil
@@ -3,6 +3,7 @@ package cmd
 import (
 	"flag"
 	"fmt"
+	"strings"
 
 	"github.com/homegrew/grew/internal/cellar"
 	"github.com/homegrew/grew/internal/config"
@@ -11,6 +12,7 @@ import (
 func runList(args []string) error {
 	fs := flag.NewFlagSet("list", flag.ContinueOnError)
 	isCask := fs.Bool("cask", false, "List installed casks")
+	showVersions := fs.Bool("versions", false, "List every installed version, marking the active one")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -20,7 +22,7 @@ func runList(args []string) error {
 	}
 
 	paths := config.Default()
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 
 	packages, err := cel.List()
 	if err != nil {
@@ -33,7 +35,23 @@ func runList(args []string) error {
 	}
 
 	for _, p := range packages {
-		fmt.Printf("%-20s %s\n", p.Name, p.Version)
+		if !*showVersions {
+			fmt.Printf("%-20s %s\n", p.Name, p.Version)
+			continue
+		}
+		versions, err := cel.InstalledVersions(p.Name)
+		if err != nil {
+			continue
+		}
+		// The active version is the one opt points to; an unlinked
+		// formula has none.
+		active, _ := cel.LinkedVersion(p.Name)
+		for i, v := range versions {
+			if v == active {
+				versions[i] = v + "*"
+			}
+		}
+		fmt.Printf("%-20s %s\n", p.Name, strings.Join(versions, " "))
 	}
 	return nil
 }
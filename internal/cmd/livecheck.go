@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/homegrew/grew/internal/config"
+	"github.com/homegrew/grew/internal/formula"
+	"github.com/homegrew/grew/internal/livecheck"
+	"github.com/homegrew/grew/internal/tap"
+)
+
+func runLivecheck(args []string) error {
+	fs := flag.NewFlagSet("livecheck", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "Output results as JSON")
+	newerOnly := fs.Bool("newer-only", false, "Only show formulas with a newer upstream version")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	paths := config.Default()
+	tapMgr := &tap.Manager{TapsDir: paths.Taps}
+	if err := tapMgr.InitCore(); err != nil {
+		return fmt.Errorf("init core tap: %w", err)
+	}
+	loader := newLoader(paths.Taps)
+
+	// With no names, check every formula that has a livecheck block.
+	var formulas []*formula.Formula
+	if fs.NArg() == 0 {
+		all, err := loader.LoadAll()
+		if err != nil {
+			return fmt.Errorf("load formulas: %w", err)
+		}
+		for _, f := range all {
+			if f.Livecheck != nil {
+				formulas = append(formulas, f)
+			}
+		}
+	} else {
+		for _, name := range fs.Args() {
+			f, err := loader.LoadByName(name)
+			if err != nil {
+				return fmt.Errorf("formula not found: %s", name)
+			}
+			formulas = append(formulas, f)
+		}
+	}
+	if len(formulas) == 0 && !*jsonOutput {
+		fmt.Println("No formulas with a livecheck block.")
+		return nil
+	}
+
+	checker := &livecheck.Checker{}
+	results := []*livecheck.Result{}
+	failed := 0
+	for _, f := range formulas {
+		r := checker.Check(f)
+		if r.Error != "" {
+			failed++
+		}
+		// Errors are always shown, so the failure count below names
+		// its formulas.
+		if *newerOnly && !r.Outdated && r.Error == "" {
+			continue
+		}
+		results = append(results, r)
+		if *jsonOutput {
+			continue
+		}
+		switch {
+		case r.Error != "":
+			fmt.Fprintf(os.Stderr, "%s: error: %s\n", r.Name, r.Error)
+		case r.Outdated:
+			fmt.Printf("%-20s %s ==> %s (newer upstream)\n", r.Name, r.Current, r.Latest)
+		default:
+			fmt.Printf("%-20s %s ==> %s\n", r.Name, r.Current, r.Latest)
+		}
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("livecheck failed for %d formula(s)", failed)
+	}
+	return nil
+}
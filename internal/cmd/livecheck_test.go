@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/homegrew/grew/internal/livecheck"
+)
+
+// captureStdout returns what fn writes to os.Stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	fn()
+	w.Close()
+	return string(<-done)
+}
+
+func TestLivecheck_NewerOnlyKeepsErrors(t *testing.T) {
+	// A page livecheck without a url fails before any request is made.
+	setupTestPrefix(t, map[string]string{
+		"tool": "name: tool\nversion: \"1.0\"\nurl:\n  linux_amd64: \"https://example.com/tool\"\ninstall:\n  type: binary\nlivecheck:\n  strategy: page\n  regex: tool-([0-9.]+)\n",
+	})
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runLivecheck([]string{"--json", "--newer-only", "tool"})
+	})
+	if err == nil {
+		t.Error("expected livecheck to fail")
+	}
+	var results []livecheck.Result
+	if jerr := json.Unmarshal([]byte(out), &results); jerr != nil {
+		t.Fatalf("decode %q: %v", out, jerr)
+	}
+	if len(results) != 1 || results[0].Name != "tool" || results[0].Error == "" {
+		t.Errorf("results = %+v, want the failed check of tool", results)
+	}
+}
@@ -2,22 +2,26 @@ package cmd
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 
+	"github.com/homegrew/grew/internal/cellar"
 	"github.com/homegrew/grew/internal/config"
+	"github.com/homegrew/grew/internal/formula"
 	"github.com/homegrew/grew/internal/lockfile"
+	"github.com/homegrew/grew/internal/tap"
 )
 
 func runLock(args []string) error {
 	sub := "generate"
 	if len(args) > 0 {
-		sub = args[0]
+		sub, args = args[0], args[1:]
 	}
 
 	switch sub {
 	case "generate":
-		return lockGenerate()
+		return lockGenerate(args)
 	case "check":
 		return lockCheck()
 	case "show":
@@ -27,10 +31,23 @@ func runLock(args []string) error {
 	}
 }
 
-func lockGenerate() error {
+func lockGenerate(args []string) error {
+	fs := flag.NewFlagSet("lock generate", flag.ContinueOnError)
+	forPlatform := fs.String("for-platform", "", "Print a lockfile for another platform instead of writing one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
 	paths := config.Default()
+	if *forPlatform != "" {
+		platform, err := formula.ParsePlatform(*forPlatform)
+		if err != nil {
+			return err
+		}
+		return lockGenerateFor(paths, platform)
+	}
 
-	lf, err := lockfile.Generate(paths.Root, paths.Cellar)
+	lf, err := lockfile.Generate(paths.Root, &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt})
 	if err != nil {
 		return fmt.Errorf("generate lockfile: %w", err)
 	}
@@ -39,10 +56,43 @@ func lockGenerate() error {
 		return fmt.Errorf("save lockfile: %w", err)
 	}
 
-	fmt.Printf("Lockfile written to %s (%d entries)\n", lockfile.LockFilePath(paths.Root), len(lf.Entries))
+	pinned := 0
+	for _, e := range lf.Entries {
+		if e.Pinned {
+			pinned++
+		}
+	}
+	if pinned > 0 {
+		fmt.Printf("Lockfile written to %s (%d entries, %d pinned)\n", lockfile.LockFilePath(paths.Root), len(lf.Entries), pinned)
+	} else {
+		fmt.Printf("Lockfile written to %s (%d entries)\n", lockfile.LockFilePath(paths.Root), len(lf.Entries))
+	}
 	return nil
 }
 
+// lockGenerateFor prints the lockfile that installing the current formulas
+// on platform would produce. It describes another machine, so it is not
+// written to the grew root.
+func lockGenerateFor(paths config.Paths, platform formula.Platform) error {
+	tapMgr := &tap.Manager{TapsDir: paths.Taps}
+	if err := tapMgr.InitCore(); err != nil {
+		return fmt.Errorf("init core tap: %w", err)
+	}
+	loader := newLoader(paths.Taps)
+
+	lf, skipped, err := lockfile.GenerateFor(&cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}, platform, loader.LoadProvider)
+	if err != nil {
+		return fmt.Errorf("generate lockfile for %s: %w", platform, err)
+	}
+	for _, name := range skipped {
+		fmt.Fprintf(os.Stderr, "Warning: %s has no download for %s; left out\n", name, platform)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(lf)
+}
+
 func lockCheck() error {
 	paths := config.Default()
 
@@ -55,7 +105,7 @@ func lockCheck() error {
 		return fmt.Errorf("no lockfile found; run 'grew lock generate' first")
 	}
 
-	discs, err := lockfile.Check(lf, paths.Cellar)
+	discs, err := lockfile.Check(lf, &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt})
 	if err != nil {
 		return fmt.Errorf("check lockfile: %w", err)
 	}
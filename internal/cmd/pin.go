@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/homegrew/grew/internal/cellar"
+	"github.com/homegrew/grew/internal/config"
+	"github.com/homegrew/grew/internal/pin"
+)
+
+func runPin(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: grew pin [<formula>]")
+	}
+
+	paths := config.Default()
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
+	pins, err := pin.Load(paths.Root)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		names := pins.Names()
+		if len(names) == 0 {
+			fmt.Println("No formulas pinned.")
+			return nil
+		}
+		for _, name := range names {
+			ver, err := cel.InstalledVersion(name)
+			if err != nil {
+				ver = "(not installed)"
+			}
+			fmt.Printf("%-20s %s\n", name, ver)
+		}
+		return nil
+	}
+
+	name := args[0]
+	if !cel.IsInstalled(name) {
+		return fmt.Errorf("formula %q is not installed", name)
+	}
+	ver, err := cel.InstalledVersion(name)
+	if err != nil {
+		return err
+	}
+	if pins[name] {
+		fmt.Printf("==> %s is already pinned at %s\n", name, ver)
+		return nil
+	}
+	pins[name] = true
+	if err := pin.Save(pins, paths.Root); err != nil {
+		return fmt.Errorf("save pins: %w", err)
+	}
+	Logf("    Recorded in %s\n", pin.Path(paths.Root))
+	fmt.Printf("==> %s pinned at %s\n", name, ver)
+	return nil
+}
+
+func runUnpin(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: grew unpin <formula>")
+	}
+	name := args[0]
+
+	paths := config.Default()
+	pins, err := pin.Load(paths.Root)
+	if err != nil {
+		return err
+	}
+	if !pins[name] {
+		return fmt.Errorf("%s is not pinned", name)
+	}
+	delete(pins, name)
+	if err := pin.Save(pins, paths.Root); err != nil {
+		return fmt.Errorf("save pins: %w", err)
+	}
+	fmt.Printf("==> %s unpinned\n", name)
+	return nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/homegrew/grew/internal/cask"
+	"github.com/homegrew/grew/internal/config"
+	"github.com/homegrew/grew/internal/formula"
+	"github.com/homegrew/grew/internal/tap"
+)
+
+// runReadall loads every formula and cask of the named taps (all taps by
+// default) and reports each file that fails strict parsing or
+// validation, plus broken aliases and renames.
+func runReadall(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: grew readall [tap]")
+	}
+
+	paths := config.Default()
+	tapMgr := &tap.Manager{TapsDir: paths.Taps}
+	if err := tapMgr.InitCore(); err != nil {
+		return fmt.Errorf("init core tap: %w", err)
+	}
+
+	var taps []string
+	if len(args) == 1 {
+		if !config.IsDir(filepath.Join(paths.Taps, args[0])) {
+			return fmt.Errorf("tap not found: %s", args[0])
+		}
+		taps = args
+	} else {
+		entries, err := os.ReadDir(paths.Taps)
+		if err != nil {
+			return fmt.Errorf("read taps directory: %w", err)
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				taps = append(taps, e.Name())
+			}
+		}
+	}
+
+	loader := newLoader(paths.Taps)
+	var problems []error
+	formulas, casks := 0, 0
+	for _, name := range taps {
+		// Casks live in their own tap directory.
+		if name == filepath.Base(paths.CaskTap) {
+			loaded, errs, err := newCaskLoader(paths.Taps).ReadAll()
+			if err != nil {
+				return err
+			}
+			casks += len(loaded)
+			problems = append(problems, errs...)
+			continue
+		}
+		loaded, errs, err := loader.ReadTap(filepath.Join(paths.Taps, name))
+		if err != nil {
+			return err
+		}
+		formulas += len(loaded)
+		problems = append(problems, errs...)
+		problems = append(problems, loader.CheckAliases(filepath.Join(paths.Taps, name))...)
+	}
+
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "%v\n", p)
+	}
+	fmt.Printf("Read %d formula(s) and %d cask(s)\n", formulas, casks)
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problem(s) found", len(problems))
+	}
+	return nil
+}
+
+// runSchema prints the JSON Schema of the formula or cask file format.
+func runSchema(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: grew schema <formula|cask>")
+	}
+	var s map[string]any
+	switch args[0] {
+	case "formula":
+		s = formula.JSONSchema()
+	case "cask":
+		s = cask.JSONSchema()
+	default:
+		return fmt.Errorf("unknown schema %q (expected formula or cask)", args[0])
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
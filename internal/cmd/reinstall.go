@@ -6,6 +6,7 @@ import (
 	"github.com/homegrew/grew/internal/cellar"
 	"github.com/homegrew/grew/internal/config"
 	"github.com/homegrew/grew/internal/downloader"
+	"github.com/homegrew/grew/internal/formula"
 	"github.com/homegrew/grew/internal/linker"
 	"github.com/homegrew/grew/internal/tap"
 )
@@ -27,7 +28,7 @@ func runReinstall(args []string) error {
 	}
 
 	loader := newLoader(paths.Taps)
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 	lnk := &linker.Linker{Paths: paths}
 	dl := &downloader.Downloader{TmpDir: paths.Tmp}
 
@@ -40,7 +41,12 @@ func runReinstall(args []string) error {
 		return fmt.Errorf("formula not found: %s", name)
 	}
 
-	fmt.Printf("==> Reinstalling %s %s\n", f.Name, f.Version)
+	fmt.Printf("==> Reinstalling %s %s\n", f.Name, f.PkgVersion())
+
+	// Kegs built with --HEAD or with options are rebuilt the same way.
+	ver, _ := cel.InstalledVersion(name)
+	head := formula.IsHeadVersion(ver)
+	options := kegOptions(cel, name, ver)
 
 	// Unlink and remove existing installation
 	lnk.Unlink(name)
@@ -52,7 +58,10 @@ func runReinstall(args []string) error {
 	Logf("    Removed old cellar entry\n")
 
 	// Fresh install
-	if err := installFormula(f, paths, cel, lnk, dl, false, false); err != nil {
+	if head || len(options) > 0 {
+		return buildWithDependencies(f, head, options, loader, paths, cel, lnk, dl)
+	}
+	if err := installFormula(f, paths, cel, lnk, dl, false, false, false); err != nil {
 		return err
 	}
 
@@ -2,8 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/homegrew/grew/internal/config"
 	"github.com/homegrew/grew/internal/formula"
 	"github.com/homegrew/grew/internal/version"
 )
@@ -44,7 +48,8 @@ func TimeOp(label string) func() {
 
 func Run(args []string) error {
 	// Global flags are parsed manually before dispatch because they
-	// can appear anywhere (e.g. "grew -v install jq").
+	// can appear anywhere (e.g. "grew -v install jq"). --version only
+	// counts before the command, so commands can take a --version flag.
 	var filtered []string
 	for _, a := range args {
 		switch a {
@@ -54,6 +59,10 @@ func Run(args []string) error {
 			Debug = true
 			Verbose = true // debug implies verbose
 		case "--version":
+			if len(filtered) > 0 {
+				filtered = append(filtered, a)
+				continue
+			}
 			fmt.Printf("grew %s\n", version.Version())
 			return nil
 		default:
@@ -81,6 +90,9 @@ func Run(args []string) error {
 		"search":       runSearch,
 		"link":         runLink,
 		"unlink":       runUnlink,
+		"switch":       runSwitch,
+		"pin":          runPin,
+		"unpin":        runUnpin,
 		"update":       runUpdate,
 		"reset-update": runResetUpdate,
 		"upgrade":      runUpgrade,
@@ -90,6 +102,11 @@ func Run(args []string) error {
 		"deps":         runDeps,
 		"alias":        runAlias,
 		"audit":        runAudit,
+		"readall":      runReadall,
+		"create":       runCreate,
+		"bump-formula": runBumpFormula,
+		"livecheck":    runLivecheck,
+		"schema":       runSchema,
 		"doctor":       runDoctor,
 		"dr":           runDoctor,
 		"config":       runConfig,
@@ -97,6 +114,7 @@ func Run(args []string) error {
 		"services":     runServices,
 		"setup":        runSetup,
 		"verify":       runVerify,
+		"test":         runTest,
 		"lock":         runLock,
 		"sign":         runSign,
 		"help":         runHelp,
@@ -130,6 +148,46 @@ func newLoader(tapDir string) *formula.Loader {
 	return l
 }
 
+// loadFormulaArg loads the formula a command-line argument names: a path
+// to a formula file is loaded from disk, anything else is looked up by
+// name in the taps.
+func loadFormulaArg(loader *formula.Loader, arg string) (*formula.Formula, error) {
+	if isFormulaPath(arg) {
+		return loader.LoadFile(arg)
+	}
+	return loader.LoadByName(arg)
+}
+
+// isFormulaPath reports whether arg is a path to a formula file rather
+// than a name: it contains a path separator, or is an existing .yaml file.
+func isFormulaPath(arg string) bool {
+	if strings.ContainsRune(arg, '/') || strings.ContainsRune(arg, filepath.Separator) {
+		return true
+	}
+	if strings.HasSuffix(arg, ".yaml") {
+		info, err := os.Stat(arg)
+		return err == nil && !info.IsDir()
+	}
+	return false
+}
+
+// addTapPath adds the directory given with --tap-path, if any, to the
+// directories the loader searches.
+func addTapPath(loader *formula.Loader, dir string) error {
+	if dir == "" {
+		return nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if !config.IsDir(abs) {
+		return fmt.Errorf("tap path is not a directory: %s", dir)
+	}
+	loader.TapPaths = append(loader.TapPaths, abs)
+	return nil
+}
+
 func printUsage() {
 	fmt.Print(`grew - a package manager written in Go
 
@@ -144,11 +202,14 @@ Flags:
 Commands:
   install [-s] [--skip-post-install] [--skip-link] <formula>  Install a formula (use --cask for apps, -s for sandboxed source build)
   uninstall <formula>  Uninstall a formula or cask (--cask)
-  list                 List installed formulas or casks (--cask)
+  list                 List installed formulas or casks (--cask, --versions)
   info <formula>       Show formula or cask info (--cask)
   search <query>       Search formulas or casks (--cask)
   link <formula>       Create symlinks for a formula
   unlink <formula>     Remove symlinks for a formula
+  switch <formula> <version>  Make another installed version active
+  pin [formula]        Hold a formula back from upgrades (no args: list pins)
+  unpin <formula>      Allow a pinned formula to be upgraded again
   update               Update formula definitions
   reset-update         Wipe and re-fetch all tap definitions
   reinstall <formula>  Reinstall a formula from scratch
@@ -157,6 +218,11 @@ Commands:
   cleanup [-n]         Remove old versions and temp files (-n for dry run)
   deps [flags] <formula>  Show dependencies for a formula
   audit [formula]      Audit formula/cask definitions for problems
+  readall [tap]        Strictly parse every formula and cask in the taps
+  schema <kind>        Print the JSON Schema of the formula or cask format
+  create <url>         Write a draft formula for a source tarball
+  bump-formula <formula> --version <v>  Update a formula's version, URLs and hashes
+  livecheck [formula]  Check for newer upstream releases
   alias [subcommand]   Manage command aliases
   services [sub]       Manage background services (start, stop, list, ...)
   setup                One-time setup of the grew prefix
@@ -164,6 +230,7 @@ Commands:
   config               Show grew and system configuration
   shellenv [shell]     Print shell environment setup
   verify [formula]     Verify installed package integrity
+  test <formula>       Run a formula's test block against its installed keg
   lock [subcommand]    Manage the formula lockfile (generate, check, show)
   sign <formula> <key> Sign formula SHA256 hashes with an Ed25519 key
   help [command]       Show help for a command
@@ -43,7 +43,7 @@ func runSearch(args []string) error {
 		return err
 	}
 
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 	found := false
 
 	for _, f := range all {
@@ -82,7 +82,7 @@ func newServicesCtx() (*servicesCtx, error) {
 	}
 
 	loader := newLoader(paths.Taps)
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 
 	mgr, err := service.DefaultManager(paths.Cellar, paths.Opt, loader)
 	if err != nil {
@@ -17,11 +17,20 @@ func runShellenv(args []string) error {
 	case "fish":
 		fmt.Printf("set -gx HOMEGREW_PREFIX \"%s\";\n", paths.Root)
 		fmt.Printf("set -gx HOMEGREW_CELLAR \"%s\";\n", paths.Cellar)
-		fmt.Printf("set -q PATH; or set PATH ''; set -gx PATH \"%s\" $PATH;\n", paths.Bin)
+		fmt.Printf("set -q PATH; or set PATH ''; set -gx PATH \"%s\" \"%s\" $PATH;\n", paths.Bin, paths.Sbin)
+		fmt.Printf("set -q MANPATH; or set MANPATH ''; set -gx MANPATH \"%s\" $MANPATH;\n", paths.Man)
+		// fish finds vendor_completions.d through XDG_DATA_DIRS.
+		fmt.Printf("set -q XDG_DATA_DIRS; or set XDG_DATA_DIRS /usr/local/share /usr/share; set -gx --path XDG_DATA_DIRS \"%s\" $XDG_DATA_DIRS;\n", paths.Share)
 	default: // bash, zsh, sh
 		fmt.Printf("export HOMEGREW_PREFIX=\"%s\";\n", paths.Root)
 		fmt.Printf("export HOMEGREW_CELLAR=\"%s\";\n", paths.Cellar)
-		fmt.Printf("export PATH=\"%s:${PATH}\";\n", paths.Bin)
+		fmt.Printf("export PATH=\"%s:%s:${PATH}\";\n", paths.Bin, paths.Sbin)
+		// With MANPATH unset, the trailing colon keeps man's default path.
+		fmt.Printf("export MANPATH=\"%s:${MANPATH#:}\";\n", paths.Man)
+		fmt.Printf("export XDG_DATA_DIRS=\"%s:${XDG_DATA_DIRS:-/usr/local/share:/usr/share}\";\n", paths.Share)
+		if shell == "zsh" {
+			fmt.Printf("fpath[1,0]=\"%s\";\n", paths.ZshCompletion)
+		}
 	}
 
 	return nil
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/homegrew/grew/internal/cellar"
+	"github.com/homegrew/grew/internal/config"
+	"github.com/homegrew/grew/internal/depgraph"
+	"github.com/homegrew/grew/internal/linker"
+	"github.com/homegrew/grew/internal/tap"
+)
+
+func runSwitch(args []string) error {
+	fs := flag.NewFlagSet("switch", flag.ContinueOnError)
+	force := fs.Bool("force", false, "Switch even if installed dependents' version constraints exclude the version")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: grew switch [--force] <formula> <version>")
+	}
+	name, version := fs.Arg(0), fs.Arg(1)
+
+	paths := config.Default()
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
+
+	if !cel.IsInstalled(name) {
+		return fmt.Errorf("formula %q is not installed", name)
+	}
+	versions, err := cel.InstalledVersions(name)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(versions, version) {
+		return fmt.Errorf("%s %s is not installed (installed: %s)\nRun 'grew install %s@%s' to install it",
+			name, version, strings.Join(versions, ", "), name, version)
+	}
+	previous, linked := cel.LinkedVersion(name)
+	if linked && previous == version {
+		fmt.Printf("==> %s %s is already active\n", name, version)
+		return nil
+	}
+
+	tapMgr := &tap.Manager{TapsDir: paths.Taps}
+	if err := tapMgr.InitCore(); err != nil {
+		return fmt.Errorf("init core tap: %w", err)
+	}
+	loader := newLoader(paths.Taps)
+	kegOnly := false
+	if f, err := loader.LoadByName(name); err == nil {
+		kegOnly = f.KegOnly
+	}
+
+	// Installed dependents may constrain which version they work with.
+	reqs := depgraph.Unsatisfied(name, cel.KegVersion(name, version), installedFormulas(loader, cel))
+	if len(reqs) > 0 {
+		if !*force {
+			return fmt.Errorf("cannot switch %s to %s: it would break %s\nUse --force to switch anyway", name, version, describeRequirements(reqs))
+		}
+		fmt.Fprintf(os.Stderr, "Warning: switching anyway (--force); this breaks %s\n", describeRequirements(reqs))
+	}
+
+	// Linking repoints opt/<formula> in one rename and replaces the
+	// previous version's links. If that fails, the previous version is
+	// linked again. While another member of the family is linked, only
+	// the opt link moves, as when the keg was installed.
+	lnk := &linker.Linker{Paths: paths}
+	if sibling := lnk.LinkedSibling(name); sibling != "" {
+		Logf("    %s is linked, linking %s keg-only\n", sibling, name)
+		kegOnly = true
+	}
+	Logf("    Keg: %s\n", cel.KegPath(name, version))
+	if _, err := lnk.Link(name, version, kegOnly); err != nil {
+		if linked {
+			if _, rerr := lnk.Link(name, previous, kegOnly); rerr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not relink %s %s: %v\n", name, previous, rerr)
+			}
+		}
+		return fmt.Errorf("switch %s to %s: %w", name, version, err)
+	}
+
+	if linked {
+		fmt.Printf("==> %s %s is now active (was %s)\n", name, version, previous)
+	} else {
+		fmt.Printf("==> %s %s is now active\n", name, version)
+	}
+	return nil
+}
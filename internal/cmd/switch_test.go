@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/homegrew/grew/internal/cellar"
+	"github.com/homegrew/grew/internal/config"
+	"github.com/homegrew/grew/internal/linker"
+)
+
+// setupTestPrefix creates a prefix with a core tap holding the given
+// formula files and returns its paths.
+func setupTestPrefix(t *testing.T, formulas map[string]string) config.Paths {
+	t.Helper()
+	return setupTestPrefixAt(t, t.TempDir(), formulas)
+}
+
+// setupTestPrefixAt is setupTestPrefix with the prefix at root.
+func setupTestPrefixAt(t *testing.T, root string, formulas map[string]string) config.Paths {
+	t.Helper()
+	t.Setenv("HOMEGREW_PREFIX", root)
+	paths := config.FromRoot(root, filepath.Join(root, "Applications"))
+	if err := paths.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for name, yaml := range formulas {
+		if err := os.WriteFile(filepath.Join(paths.CoreTap, name+".yaml"), []byte(yaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+// installKeg creates a keg with one executable in bin.
+func installKeg(t *testing.T, paths config.Paths, name, version, bin string) {
+	t.Helper()
+	dir := filepath.Join(paths.Cellar, name, version, "bin")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, bin), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSwitch_WithLinkedFamilyMember(t *testing.T) {
+	paths := setupTestPrefix(t, map[string]string{
+		"tool@1": "name: tool@1\nversion: \"1.1\"\nurl:\n  linux_amd64: \"https://example.com/tool\"\ninstall:\n  type: binary\n",
+	})
+	installKeg(t, paths, "tool@1", "1.0", "tool")
+	installKeg(t, paths, "tool@1", "1.1", "tool")
+	installKeg(t, paths, "tool@2", "2.0", "tool")
+
+	lnk := &linker.Linker{Paths: paths}
+	if _, err := lnk.Link("tool@2", "2.0", false); err != nil {
+		t.Fatalf("link tool@2: %v", err)
+	}
+	if _, err := lnk.Link("tool@1", "1.1", true); err != nil {
+		t.Fatalf("link tool@1 keg-only: %v", err)
+	}
+
+	if err := runSwitch([]string{"tool@1", "1.0"}); err != nil {
+		t.Fatalf("switch: %v", err)
+	}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
+	if ver, ok := cel.LinkedVersion("tool@1"); !ok || ver != "1.0" {
+		t.Errorf("tool@1 opt link = %q, %v, want 1.0", ver, ok)
+	}
+	target, err := os.Readlink(filepath.Join(paths.Bin, "tool"))
+	if err != nil || filepath.Base(filepath.Dir(filepath.Dir(target))) != "2.0" {
+		t.Errorf("bin/tool = %q, %v, want it to stay with tool@2", target, err)
+	}
+}
+
+func TestSwitch_RefusesVersionDependentsExclude(t *testing.T) {
+	paths := setupTestPrefix(t, map[string]string{
+		"jq":   "name: jq\nversion: \"1.7.1\"\nurl:\n  linux_amd64: \"https://example.com/jq\"\ninstall:\n  type: binary\n",
+		"tool": "name: tool\nversion: \"1.0\"\nurl:\n  linux_amd64: \"https://example.com/tool\"\ninstall:\n  type: binary\ndependencies:\n  - \"jq >= 1.7\"\n",
+	})
+	installKeg(t, paths, "jq", "1.6", "jq")
+	installKeg(t, paths, "jq", "1.7.1", "jq")
+	installKeg(t, paths, "tool", "1.0", "tool")
+	lnk := &linker.Linker{Paths: paths}
+	if _, err := lnk.Link("jq", "1.7.1", false); err != nil {
+		t.Fatalf("link jq: %v", err)
+	}
+
+	err := runSwitch([]string{"jq", "1.6"})
+	if err == nil || !strings.Contains(err.Error(), "tool requires jq >= 1.7") {
+		t.Fatalf("switch = %v, want it refused for tool's constraint", err)
+	}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
+	if ver, _ := cel.LinkedVersion("jq"); ver != "1.7.1" {
+		t.Errorf("jq opt link = %q after a refused switch, want 1.7.1", ver)
+	}
+
+	if err := runSwitch([]string{"--force", "jq", "1.6"}); err != nil {
+		t.Fatalf("switch --force: %v", err)
+	}
+	if ver, _ := cel.LinkedVersion("jq"); ver != "1.6" {
+		t.Errorf("jq opt link = %q after switch --force, want 1.6", ver)
+	}
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/homegrew/grew/internal/cellar"
+	"github.com/homegrew/grew/internal/config"
+	"github.com/homegrew/grew/internal/formula"
+	"github.com/homegrew/grew/internal/sandbox"
+	"github.com/homegrew/grew/internal/tap"
+)
+
+// testResult is the outcome of testing one formula. Status is one of
+// passed, failed, no_test, not_installed or not_linked.
+type testResult struct {
+	Name    string           `json:"name"`
+	Version string           `json:"version,omitempty"`
+	Status  string           `json:"status"`
+	Steps   []testStepResult `json:"steps,omitempty"`
+}
+
+type testStepResult struct {
+	Command      string `json:"command"`
+	ExitCode     int    `json:"exit_code"`
+	ExpectExit   int    `json:"expect_exit"`
+	ExpectOutput string `json:"expect_output,omitempty"`
+	Passed       bool   `json:"passed"`
+	Failure      string `json:"failure,omitempty"`
+	Output       string `json:"output,omitempty"` // only kept for failed steps
+}
+
+func runTest(args []string) error {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "Output results as JSON")
+	tapPath := fs.String("tap-path", "", "Also load formulas from this directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	targets := fs.Args()
+	if len(targets) == 0 {
+		return fmt.Errorf("usage: grew test [--json] [--tap-path <dir>] <formula|file ...>")
+	}
+
+	paths := config.Default()
+	if err := paths.Init(); err != nil {
+		return err
+	}
+	tapMgr := &tap.Manager{TapsDir: paths.Taps}
+	if err := tapMgr.InitCore(); err != nil {
+		return fmt.Errorf("init core tap: %w", err)
+	}
+	loader := newLoader(paths.Taps)
+	if err := addTapPath(loader, *tapPath); err != nil {
+		return err
+	}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
+
+	var results []testResult
+	failed := 0
+	for _, name := range targets {
+		f, err := loadFormulaArg(loader, name)
+		if err != nil {
+			if isFormulaPath(name) {
+				return err
+			}
+			return fmt.Errorf("formula not found: %s", name)
+		}
+		r := testFormula(f, cel, paths, !*jsonOutput)
+		if r.Status == "failed" || r.Status == "not_installed" || r.Status == "not_linked" {
+			failed++
+		}
+		results = append(results, r)
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("tests failed for %d formula(s)", failed)
+	}
+	return nil
+}
+
+// testFormula runs the test block of f against the keg opt/<formula>
+// points to, the active version. Each step runs in the post-install
+// sandbox from a fresh scratch directory under paths.Tmp, with the keg's
+// bin and its dependencies' opt bins on PATH. Testing stops at the first
+// failing step.
+func testFormula(f *formula.Formula, cel *cellar.Cellar, paths config.Paths, verbose bool) testResult {
+	r := testResult{Name: f.Name}
+	if !cel.IsInstalled(f.Name) {
+		r.Status = "not_installed"
+		if verbose {
+			fmt.Printf("%s: FAILED (not installed)\n", f.Name)
+		}
+		return r
+	}
+	ver, ok := cel.LinkedVersion(f.Name)
+	if !ok {
+		r.Status = "not_linked"
+		if verbose {
+			fmt.Printf("%s: FAILED (no opt link; run 'grew link %s')\n", f.Name, f.Name)
+		}
+		return r
+	}
+	r.Version = ver
+	if len(f.Test) == 0 {
+		r.Status = "no_test"
+		if verbose {
+			fmt.Printf("%s %s: no test defined\n", f.Name, ver)
+		}
+		return r
+	}
+
+	kegPath := cel.KegPath(f.Name, ver)
+	binPaths := []string{filepath.Join(kegPath, "bin")}
+	for _, dep := range f.DependencyNames() {
+		binPaths = append(binPaths, filepath.Join(paths.Opt, dep, "bin"))
+	}
+
+	if verbose {
+		fmt.Printf("==> Testing %s %s\n", f.Name, ver)
+	}
+	r.Status = "passed"
+	for _, step := range f.Test {
+		sr := runTestStep(f.Name, step, kegPath, binPaths, paths.Tmp)
+		r.Steps = append(r.Steps, sr)
+		if verbose {
+			printTestStep(sr)
+		}
+		if !sr.Passed {
+			r.Status = "failed"
+			break
+		}
+	}
+	if verbose {
+		fmt.Printf("%s %s: %s\n", f.Name, ver, strings.ToUpper(r.Status))
+	}
+	return r
+}
+
+func runTestStep(name string, step formula.TestStep, kegPath string, binPaths []string, tmpDir string) testStepResult {
+	sr := testStepResult{
+		Command:      step.Run,
+		ExpectExit:   step.ExpectExit,
+		ExpectOutput: step.ExpectOutput,
+	}
+
+	scratch, err := os.MkdirTemp(tmpDir, fmt.Sprintf("grew-test-%s-*", name))
+	if err != nil {
+		sr.ExitCode = -1
+		sr.Failure = fmt.Sprintf("create scratch dir: %v", err)
+		return sr
+	}
+	defer os.RemoveAll(scratch)
+
+	cfg := sandbox.PostInstallConfig{KegDir: kegPath, TmpDir: scratch, BinPaths: binPaths}
+	var out bytes.Buffer
+	cmd := sandbox.PostInstallCommand(cfg, "sh", "-c", step.Run)
+	cmd.Dir = scratch
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	Debugf("test %s: %s (scratch %s)\n", name, step.Run, scratch)
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		sr.ExitCode = exitErr.ExitCode()
+	default:
+		sr.ExitCode = -1
+		sr.Failure = fmt.Sprintf("run: %v", err)
+	}
+
+	output := out.String()
+	switch {
+	case sr.Failure != "":
+	case sr.ExitCode != step.ExpectExit:
+		sr.Failure = fmt.Sprintf("exit status %d, expected %d", sr.ExitCode, step.ExpectExit)
+	case step.ExpectOutput != "" && !strings.Contains(output, step.ExpectOutput):
+		sr.Failure = fmt.Sprintf("output does not contain %q", step.ExpectOutput)
+	default:
+		sr.Passed = true
+	}
+	if !sr.Passed {
+		sr.Output = output
+	}
+	return sr
+}
+
+func printTestStep(sr testStepResult) {
+	if sr.Passed {
+		fmt.Printf("  ok:     %s\n", sr.Command)
+		return
+	}
+	fmt.Printf("  failed: %s\n", sr.Command)
+	fmt.Printf("          %s\n", sr.Failure)
+	for _, line := range strings.Split(strings.TrimRight(sr.Output, "\n"), "\n") {
+		if line != "" {
+			fmt.Printf("          | %s\n", line)
+		}
+	}
+}
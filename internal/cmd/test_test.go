@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/homegrew/grew/internal/cellar"
+	"github.com/homegrew/grew/internal/config"
+	"github.com/homegrew/grew/internal/formula"
+	"github.com/homegrew/grew/internal/linker"
+)
+
+const testToolYAML = "name: tool\nversion: \"1.0\"\nurl:\n  linux_amd64: \"https://example.com/tool\"\ninstall:\n  type: binary\n"
+
+// setupSandboxPrefix is setupTestPrefix for commands that run in the
+// post-install sandbox. The sandbox mounts a fresh /tmp, which would hide
+// a prefix under the system temp dir, so the prefix is made in the package
+// directory instead.
+func setupSandboxPrefix(t *testing.T, formulas map[string]string) config.Paths {
+	t.Helper()
+	root, err := os.MkdirTemp(".", ".grew-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return setupTestPrefixAt(t, abs, formulas)
+}
+
+// installTestTool installs and links a tool 1.0 keg whose tool
+// executable prints "tool 1.0".
+func installTestTool(t *testing.T, paths config.Paths) {
+	t.Helper()
+	installKeg(t, paths, "tool", "1.0", "tool")
+	bin := filepath.Join(paths.Cellar, "tool", "1.0", "bin", "tool")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\necho tool 1.0\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&linker.Linker{Paths: paths}).Link("tool", "1.0", false); err != nil {
+		t.Fatalf("link tool: %v", err)
+	}
+}
+
+func TestTestFormula_Steps(t *testing.T) {
+	paths := setupSandboxPrefix(t, nil)
+	installTestTool(t, paths)
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
+
+	tests := []struct {
+		name       string
+		steps      []formula.TestStep
+		wantStatus string
+		wantSteps  int
+		wantExit   int
+		wantReason string
+	}{
+		{"passes", []formula.TestStep{{Run: "tool", ExpectOutput: "tool 1.0"}}, "passed", 1, 0, ""},
+		{"no test", nil, "no_test", 0, 0, ""},
+		{"non-zero exit", []formula.TestStep{{Run: "exit 3"}}, "failed", 1, 3, "exit status 3, expected 0"},
+		{"expected exit", []formula.TestStep{{Run: "exit 3", ExpectExit: 3}}, "passed", 1, 3, ""},
+		{"output mismatch", []formula.TestStep{{Run: "tool", ExpectOutput: "tool 2.0"}}, "failed", 1, 0, `output does not contain "tool 2.0"`},
+		{"stops at first failure", []formula.TestStep{{Run: "false"}, {Run: "tool"}}, "failed", 1, 1, "exit status 1, expected 0"},
+	}
+	for _, tt := range tests {
+		f := &formula.Formula{Name: "tool", Version: "1.0", Test: tt.steps}
+		r := testFormula(f, cel, paths, false)
+		if r.Status != tt.wantStatus || len(r.Steps) != tt.wantSteps {
+			t.Errorf("%s: status %q with %d steps, want %q with %d", tt.name, r.Status, len(r.Steps), tt.wantStatus, tt.wantSteps)
+			continue
+		}
+		if tt.wantSteps == 0 {
+			continue
+		}
+		last := r.Steps[len(r.Steps)-1]
+		if last.ExitCode != tt.wantExit || last.Failure != tt.wantReason {
+			t.Errorf("%s: exit %d, failure %q, want %d, %q", tt.name, last.ExitCode, last.Failure, tt.wantExit, tt.wantReason)
+		}
+		if last.Passed != (tt.wantReason == "") || (last.Passed && last.Output != "") {
+			t.Errorf("%s: passed %v with output %q", tt.name, last.Passed, last.Output)
+		}
+	}
+}
+
+func TestTestFormula_NotInstalledOrLinked(t *testing.T) {
+	paths := setupTestPrefix(t, nil)
+	installKeg(t, paths, "tool", "1.0", "tool")
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
+	steps := []formula.TestStep{{Run: "true"}}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"missing", "not_installed"},
+		{"tool", "not_linked"},
+	}
+	for _, tt := range tests {
+		r := testFormula(&formula.Formula{Name: tt.name, Version: "1.0", Test: steps}, cel, paths, false)
+		if r.Status != tt.want || len(r.Steps) != 0 {
+			t.Errorf("%s: status %q with %d steps, want %q and none run", tt.name, r.Status, len(r.Steps), tt.want)
+		}
+	}
+}
+
+func TestTest_JSON(t *testing.T) {
+	paths := setupSandboxPrefix(t, map[string]string{
+		"tool": testToolYAML + "test:\n  - run: tool\n    expect_output: \"tool 1.0\"\n  - run: exit 2\n",
+	})
+	installTestTool(t, paths)
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runTest([]string{"--json", "tool"})
+	})
+	if err == nil {
+		t.Error("expected the failing step to fail the command")
+	}
+	var results []map[string]any
+	if jerr := json.Unmarshal([]byte(out), &results); jerr != nil {
+		t.Fatalf("decode %q: %v", out, jerr)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	r := results[0]
+	if r["name"] != "tool" || r["version"] != "1.0" || r["status"] != "failed" {
+		t.Errorf("result = %v", r)
+	}
+	steps, _ := r["steps"].([]any)
+	if len(steps) != 2 {
+		t.Fatalf("steps = %v, want 2", r["steps"])
+	}
+	ok, _ := steps[0].(map[string]any)
+	if ok["command"] != "tool" || ok["passed"] != true || ok["exit_code"] != 0.0 || ok["output"] != nil {
+		t.Errorf("passing step = %v", ok)
+	}
+	bad, _ := steps[1].(map[string]any)
+	if bad["passed"] != false || bad["exit_code"] != 2.0 || bad["expect_exit"] != 0.0 || bad["failure"] != "exit status 2, expected 0" {
+		t.Errorf("failing step = %v", bad)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/homegrew/grew/internal/cellar"
 	"github.com/homegrew/grew/internal/config"
 	"github.com/homegrew/grew/internal/linker"
+	"github.com/homegrew/grew/internal/pin"
 )
 
 func runUninstall(args []string) error {
@@ -26,7 +27,7 @@ func runUninstall(args []string) error {
 
 	name := fs.Arg(0)
 	paths := config.Default()
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 
 	if !cel.IsInstalled(name) {
 		return fmt.Errorf("formula %q is not installed", name)
@@ -39,12 +40,20 @@ func runUninstall(args []string) error {
 
 	fmt.Printf("==> Unlinking %s...\n", name)
 	lnk.Unlink(name)
-	Logf("    Removed symlinks from bin/, lib/, include/, opt/\n")
+	Logf("    Removed symlinks from bin/, sbin/, lib/, include/, etc/, share/, opt/\n")
 
 	fmt.Printf("==> Removing %s...\n", name)
 	if err := cel.Uninstall(name); err != nil {
 		return err
 	}
+	if pins, err := pin.Load(paths.Root); err == nil && pins[name] {
+		delete(pins, name)
+		if err := pin.Save(pins, paths.Root); err != nil {
+			fmt.Printf("==> Warning: could not remove the pin of %s: %v\n", name, err)
+		} else {
+			Logf("    Unpinned %s\n", name)
+		}
+	}
 
 	fmt.Printf("==> %s uninstalled\n", name)
 	return nil
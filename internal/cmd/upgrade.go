@@ -1,23 +1,48 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/homegrew/grew/internal/cellar"
 	"github.com/homegrew/grew/internal/config"
+	"github.com/homegrew/grew/internal/depgraph"
 	"github.com/homegrew/grew/internal/downloader"
 	"github.com/homegrew/grew/internal/formula"
 	"github.com/homegrew/grew/internal/linker"
+	"github.com/homegrew/grew/internal/lockfile"
+	"github.com/homegrew/grew/internal/pin"
+	"github.com/homegrew/grew/internal/requirements"
+	"github.com/homegrew/grew/internal/snapshot"
 	"github.com/homegrew/grew/internal/tap"
 )
 
 type outdatedPkg struct {
 	formula          *formula.Formula
 	installedVersion string
+	// options are the build options the installed keg was built with;
+	// when set, the upgrade builds from source with them.
+	options []string
+	// keepOld keeps the old keg installed next to the new one.
+	keepOld bool
+	// build marks a build-only dependency upgraded for a source build.
+	// Its new keg is left unlinked unless the old one was linked.
+	build bool
 }
 
 func runUpgrade(args []string) error {
+	fs := flag.NewFlagSet("upgrade", flag.ContinueOnError)
+	fetchHead := fs.Bool("fetch-HEAD", false, "Rebuild --HEAD installs whose branch has moved")
+	keepOld := fs.Bool("keep-old", false, "Keep the old version installed next to the new one")
+	force := fs.Bool("force", false, "Upgrade pinned formulas named on the command line")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+
 	paths := config.Default()
 	if err := paths.Init(); err != nil {
 		return err
@@ -29,11 +54,38 @@ func runUpgrade(args []string) error {
 	}
 
 	loader := newLoader(paths.Taps)
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 	lnk := &linker.Linker{Paths: paths}
 	dl := &downloader.Downloader{TmpDir: paths.Tmp}
 
-	var targets []outdatedPkg
+	// Formulas the tap has renamed move to their new name first, so the
+	// checks below see them under the name the tap uses.
+	args, err := migrateRenames(args, loader, paths, cel, lnk)
+	if err != nil {
+		return err
+	}
+
+	// Pinned formulas are left alone by an upgrade of everything, and
+	// refused when named unless --force is given.
+	pins, err := pin.Load(paths.Root)
+	if err != nil {
+		return err
+	}
+	for _, name := range args {
+		if !pins[name] {
+			continue
+		}
+		if !*force {
+			return fmt.Errorf("%s is pinned\nRun 'grew unpin %s' to allow upgrades, or 'grew upgrade --force %s' to upgrade it once", name, name, name)
+		}
+		fmt.Printf("==> %s is pinned, upgrading it anyway (--force)\n", name)
+	}
+	held := 0
+
+	var targets, heads []outdatedPkg
+	// Installed formulas that a tap formula replaces are migrated to it.
+	succ := successors(loader)
+	var migrations []string
 
 	if len(args) > 0 {
 		// Upgrade specific formulas
@@ -41,16 +93,39 @@ func runUpgrade(args []string) error {
 			if !cel.IsInstalled(name) {
 				return fmt.Errorf("formula %q is not installed", name)
 			}
+			if succ[name] != nil {
+				migrations = append(migrations, name)
+				continue
+			}
 			f, err := loader.LoadByName(name)
 			if err != nil {
 				return fmt.Errorf("formula not found: %s", name)
 			}
-			curVer, _ := cel.InstalledVersion(name)
-			if curVer == f.Version {
-				fmt.Printf("==> %s %s already up-to-date\n", name, curVer)
+			curVer := newestVersion(cel, name)
+			if formula.IsHeadVersion(curVer) {
+				if !*fetchHead {
+					fmt.Printf("==> %s %s was built from HEAD; use --fetch-HEAD to rebuild it\n", name, curVer)
+					continue
+				}
+				moved, err := headMoved(f, cel, curVer)
+				if err != nil {
+					return err
+				}
+				if moved {
+					heads = append(heads, outdatedPkg{formula: f, installedVersion: curVer, options: kegOptions(cel, name, curVer)})
+				} else {
+					fmt.Printf("==> %s %s already up-to-date\n", name, curVer)
+				}
 				continue
 			}
-			targets = append(targets, outdatedPkg{formula: f, installedVersion: curVer})
+			switch cmp := compareToTap(f, cel, curVer); {
+			case cmp == 0:
+				fmt.Printf("==> %s %s already up-to-date\n", name, curVer)
+			case cmp < 0:
+				fmt.Printf("==> %s %s is newer than the tap version %s, not downgrading\n", name, curVer, f.PkgVersion())
+			default:
+				targets = append(targets, outdatedPkg{formula: f, installedVersion: curVer, options: kegOptions(cel, name, curVer)})
+			}
 		}
 	} else {
 		// Upgrade all outdated packages
@@ -63,45 +138,396 @@ func runUpgrade(args []string) error {
 			return nil
 		}
 		for _, pkg := range installed {
+			if succ[pkg.Name] != nil {
+				if pins[pkg.Name] {
+					fmt.Printf("==> Not migrating %s to %s: it is pinned\n", pkg.Name, succ[pkg.Name].Name)
+					held++
+					continue
+				}
+				migrations = append(migrations, pkg.Name)
+				continue
+			}
+			pkg.Version = newestVersion(cel, pkg.Name)
 			f, err := loader.LoadByName(pkg.Name)
 			if err != nil {
 				Debugf("skipping %s: no longer in any tap (%v)\n", pkg.Name, err)
 				continue
 			}
-			if pkg.Version != f.Version {
-				targets = append(targets, outdatedPkg{formula: f, installedVersion: pkg.Version})
+			if formula.IsHeadVersion(pkg.Version) {
+				if !*fetchHead {
+					Debugf("skipping %s: built from HEAD\n", pkg.Name)
+					continue
+				}
+				moved, err := headMoved(f, cel, pkg.Version)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", pkg.Name, err)
+					continue
+				}
+				switch {
+				case moved && pins[pkg.Name]:
+					fmt.Printf("==> Not upgrading %s %s -> HEAD: it is pinned\n", pkg.Name, pkg.Version)
+					held++
+				case moved:
+					heads = append(heads, outdatedPkg{formula: f, installedVersion: pkg.Version, options: kegOptions(cel, pkg.Name, pkg.Version)})
+				}
+				continue
+			}
+			switch cmp := compareToTap(f, cel, pkg.Version); {
+			case cmp < 0:
+				fmt.Printf("==> %s %s is newer than the tap version %s, not downgrading\n", pkg.Name, pkg.Version, f.PkgVersion())
+			case cmp > 0 && pins[pkg.Name]:
+				fmt.Printf("==> Not upgrading %s %s -> %s: it is pinned\n", pkg.Name, pkg.Version, f.PkgVersion())
+				held++
+			case cmp > 0:
+				targets = append(targets, outdatedPkg{formula: f, installedVersion: pkg.Version, options: kegOptions(cel, pkg.Name, pkg.Version)})
 			}
 		}
 	}
 
-	if len(targets) == 0 {
-		fmt.Println("All packages are up-to-date.")
+	if len(targets) == 0 && len(heads) == 0 && len(migrations) == 0 {
+		if held > 0 {
+			fmt.Println("All unpinned packages are up-to-date.")
+		} else {
+			fmt.Println("All packages are up-to-date.")
+		}
 		return nil
 	}
 
+	for _, old := range migrations {
+		if err := migrateFormula(old, succ[old], loader, paths, cel, lnk, dl); err != nil {
+			return err
+		}
+	}
+
+	checker := &requirements.Checker{}
+	dependents := installedFormulas(loader, cel)
 	for _, t := range targets {
-		fmt.Printf("==> Upgrading %s %s -> %s\n", t.formula.Name, t.installedVersion, t.formula.Version)
+		t.keepOld = *keepOld
+		if f := t.formula; f.IsDisabled() {
+			fmt.Printf("==> Skipping %s: %s\n", f.Name, f.Disabled.Message(f.Name, "disabled"))
+			continue
+		}
+		if err := checker.Check(t.formula); err != nil {
+			fmt.Printf("==> Skipping %s: %v\n", t.formula.Name, err)
+			continue
+		}
+		if reqs := depgraph.Unsatisfied(t.formula.Name, t.formula.ParsedVersion(), dependents); len(reqs) > 0 {
+			fmt.Printf("==> Skipping %s: %s would break %s\n", t.formula.Name, t.formula.PkgVersion(), describeRequirements(reqs))
+			continue
+		}
+		var err error
+		if len(t.options) > 0 {
+			err = upgradeFromSource(t, false, loader, paths, cel, lnk, dl)
+		} else {
+			err = upgradeFormula(t, paths, cel, lnk, dl)
+		}
+		if err != nil {
+			return err
+		}
+	}
 
-		// Unlink old version
-		lnk.Unlink(t.formula.Name)
-		Logf("    Unlinked old version %s\n", t.installedVersion)
+	for _, t := range heads {
+		t.keepOld = *keepOld
+		if err := upgradeFromSource(t, true, loader, paths, cel, lnk, dl); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// headMoved reports whether the branch a --HEAD keg was built from has
+// moved on. The keg's commit comes from its manifest, or failing that from
+// the HEAD-<commit> version; a manifest commit that is not a full hash is
+// ignored.
+func headMoved(f *formula.Formula, cel *cellar.Cellar, installed string) (bool, error) {
+	if f.Head == nil {
+		return false, fmt.Errorf("formula %q no longer has a head source", f.Name)
+	}
+	remote, err := downloader.RemoteCommit(f.Head.URL, f.Head.Ref())
+	if err != nil {
+		return false, fmt.Errorf("fetch HEAD of %s: %w", f.Name, err)
+	}
+	if m, err := snapshot.Load(cel.KegPath(f.Name, installed)); err == nil && formula.IsCommit(m.HeadCommit) {
+		return m.HeadCommit != remote, nil
+	}
+	return formula.HeadVersion(remote) != installed, nil
+}
+
+// upgradeFromSource rebuilds an installed keg from source with the
+// options it was built with: from the current head of its branch with
+// head, otherwise at the tap version. The old keg is removed afterwards
+// unless t.keepOld is set or the new keg took its place.
+func upgradeFromSource(t outdatedPkg, head bool, loader *formula.Loader, paths config.Paths, cel *cellar.Cellar, lnk *linker.Linker, dl *downloader.Downloader) error {
+	f := t.formula
+	target := f.PkgVersion()
+	if head {
+		target = "HEAD"
+	}
+	fmt.Printf("==> Upgrading %s %s -> %s\n", f.Name, t.installedVersion, target)
+
+	lnk.Unlink(f.Name)
+	Logf("    Unlinked old version %s\n", t.installedVersion)
+	if err := buildWithDependencies(f, head, t.options, loader, paths, cel, lnk, dl); err != nil {
+		return err
+	}
+
+	// A rebuild at the same version (new options, a version_scheme
+	// bump) reuses the old keg path, which must then stay.
+	newVersion := f.PkgVersion()
+	if head {
+		newVersion, _ = cel.LinkedVersion(f.Name)
+	}
+	oldKeg := cel.KegPath(f.Name, t.installedVersion)
+	if t.keepOld {
+		Logf("    Kept old keg: %s\n", oldKeg)
+	} else if t.installedVersion != newVersion {
+		if err := removeDir(oldKeg); err != nil {
+			Logf("    Warning: could not remove old keg %s: %v\n", oldKeg, err)
+		} else {
+			Logf("    Removed old keg: %s\n", oldKeg)
+		}
+	}
+	return nil
+}
 
-		// Install new version (old keg stays until we confirm success)
-		if err := installFormula(t.formula, paths, cel, lnk, dl, false, false); err != nil {
+// migrateRenames renames the installed formulas among names (all installed
+// formulas when names is empty) that the tap lists in its renames, and
+// returns names with the new names substituted.
+func migrateRenames(names []string, loader *formula.Loader, paths config.Paths, cel *cellar.Cellar, lnk *linker.Linker) ([]string, error) {
+	candidates := names
+	if len(names) == 0 {
+		installed, err := cel.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range installed {
+			candidates = append(candidates, pkg.Name)
+		}
+	}
+	renamed := make(map[string]string)
+	for _, old := range candidates {
+		newName := loader.RenamedTo(old)
+		if newName == "" || !cel.IsInstalled(old) {
+			continue
+		}
+		if err := renameFormula(old, newName, loader, paths, cel, lnk); err != nil {
+			return nil, err
+		}
+		renamed[old] = newName
+	}
+	out := make([]string, len(names))
+	for i, name := range names {
+		if newName, ok := renamed[name]; ok {
+			name = newName
+		}
+		out[i] = name
+	}
+	return out, nil
+}
+
+// renameFormula moves a formula installed under a name the tap has since
+// renamed: its kegs move to the new name, its opt and prefix links are
+// recreated, and manifests, pins and the lockfile follow the new name.
+func renameFormula(old, newName string, loader *formula.Loader, paths config.Paths, cel *cellar.Cellar, lnk *linker.Linker) error {
+	fmt.Printf("==> %s has been renamed to %s\n", old, newName)
+	f, err := loader.LoadByName(newName)
+	if err != nil {
+		return fmt.Errorf("formula not found: %s", newName)
+	}
+
+	wasLinked := lnk.IsLinked(old)
+	lnk.Unlink(old)
+	if err := cel.Rename(old, newName); err != nil {
+		return fmt.Errorf("migrate %s to %s: %w", old, newName, err)
+	}
+	Logf("    Moved %s to %s\n", filepath.Join(paths.Cellar, old), filepath.Join(paths.Cellar, newName))
+
+	// Manifests record the formula name and dependency names.
+	if pkgs, err := cel.List(); err == nil {
+		for _, pkg := range pkgs {
+			m, err := snapshot.Load(pkg.Path)
+			if err != nil {
+				continue
+			}
+			changed := false
+			if m.Name == old {
+				m.Name = newName
+				changed = true
+			}
+			for i, dep := range m.Dependencies {
+				if dep == old {
+					m.Dependencies[i] = newName
+					changed = true
+				}
+			}
+			if changed {
+				if err := snapshot.Save(m, pkg.Path); err != nil {
+					Logf("    Warning: could not update manifest of %s: %v\n", pkg.Name, err)
+				}
+			}
+		}
+	}
+
+	if wasLinked {
+		ver, err := cel.InstalledVersion(newName)
+		if err != nil {
 			return err
 		}
+		if _, err := lnk.Link(newName, ver, f.KegOnly); err != nil {
+			return fmt.Errorf("link %s: %w", newName, err)
+		}
+	}
+
+	if pins, err := pin.Load(paths.Root); err == nil && pins.Rename(old, newName) {
+		if err := pin.Save(pins, paths.Root); err != nil {
+			fmt.Printf("==> Warning: could not update pins: %v\n", err)
+		} else {
+			Logf("    Moved the pin of %s to %s\n", old, newName)
+		}
+	}
+
+	if lf, err := lockfile.Load(paths.Root); err == nil && lockfile.RenameEntry(lf, old, newName) {
+		if err := lockfile.Save(lf, paths.Root); err != nil {
+			fmt.Printf("==> Warning: could not update lockfile: %v\n", err)
+		} else {
+			Logf("    Renamed %s to %s in the lockfile\n", old, newName)
+		}
+	}
+	return nil
+}
+
+// upgradeFormula replaces an installed keg with the tap version of the
+// formula, or installs it next to the keg when t.keepOld is set. Shared
+// by upgrade and by install when a version constraint rules out the
+// installed keg.
+func upgradeFormula(t outdatedPkg, paths config.Paths, cel *cellar.Cellar, lnk *linker.Linker, dl *downloader.Downloader) error {
+	fmt.Printf("==> Upgrading %s %s -> %s\n", t.formula.Name, t.installedVersion, t.formula.PkgVersion())
+
+	// Unlink old version
+	skipLink := t.build && !lnk.IsLinked(t.formula.Name)
+	lnk.Unlink(t.formula.Name)
+	Logf("    Unlinked old version %s\n", t.installedVersion)
+
+	// Install new version (old keg stays until we confirm success)
+	if err := installFormula(t.formula, paths, cel, lnk, dl, false, skipLink, false); err != nil {
+		return err
+	}
+
+	// Remove old version keg if different from new
+	oldKeg := cel.KegPath(t.formula.Name, t.installedVersion)
+	if t.keepOld {
+		Logf("    Kept old keg: %s\n", oldKeg)
+	} else if t.installedVersion != t.formula.PkgVersion() {
+		if err := removeDir(oldKeg); err != nil {
+			Logf("    Warning: could not remove old keg %s: %v\n", oldKeg, err)
+		} else {
+			Logf("    Removed old keg: %s\n", oldKeg)
+		}
+	}
+	return nil
+}
+
+// installedFormulas loads the tap formula of every installed formula, for
+// checking the constraints they place on their dependencies. Formulas no
+// longer in any tap are left out.
+func installedFormulas(loader *formula.Loader, cel *cellar.Cellar) []*formula.Formula {
+	pkgs, err := cel.List()
+	if err != nil {
+		return nil
+	}
+	var fs []*formula.Formula
+	for _, pkg := range pkgs {
+		if f, err := loader.LoadByName(pkg.Name); err == nil {
+			fs = append(fs, f)
+		}
+	}
+	return fs
+}
+
+// describeRequirements joins requirements for a one-line message, e.g.
+// "foo requires bar < 2".
+func describeRequirements(reqs []depgraph.Requirement) string {
+	parts := make([]string, len(reqs))
+	for i, r := range reqs {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// installedReplaced returns the installed formulas that f replaces.
+func installedReplaced(f *formula.Formula, cel *cellar.Cellar) []string {
+	var names []string
+	for _, old := range f.Replaces {
+		if cel.IsInstalled(old) {
+			names = append(names, old)
+		}
+	}
+	return names
+}
+
+// removeReplaced uninstalls formulas superseded by a newly installed one.
+// They must already be unlinked.
+func removeReplaced(names []string, cel *cellar.Cellar) {
+	for _, old := range names {
+		if err := cel.Uninstall(old); err != nil {
+			fmt.Printf("==> Warning: could not remove replaced formula %s: %v\n", old, err)
+			continue
+		}
+		fmt.Printf("==> Removed %s\n", old)
+	}
+}
 
-		// Remove old version keg if different from new
-		oldKeg := cel.KegPath(t.formula.Name, t.installedVersion)
-		if t.installedVersion != t.formula.Version {
-			if err := removeDir(oldKeg); err != nil {
-				Logf("    Warning: could not remove old keg %s: %v\n", oldKeg, err)
-			} else {
-				Logf("    Removed old keg: %s\n", oldKeg)
+// successors maps each formula name listed in some tap formula's replaces
+// to that formula.
+func successors(loader *formula.Loader) map[string]*formula.Formula {
+	all, err := loader.LoadAll()
+	if err != nil {
+		Debugf("load formulas for replaces: %v\n", err)
+		return nil
+	}
+	m := make(map[string]*formula.Formula)
+	for _, f := range all {
+		for _, old := range f.Replaces {
+			if _, ok := m[old]; !ok {
+				m[old] = f
 			}
 		}
 	}
+	return m
+}
 
+// migrateFormula moves an installed formula to the tap formula that
+// replaces it: the successor and any missing dependencies are installed,
+// then the old formula is removed.
+func migrateFormula(old string, successor *formula.Formula, loader *formula.Loader, paths config.Paths, cel *cellar.Cellar, lnk *linker.Linker, dl *downloader.Downloader) error {
+	fmt.Printf("==> %s has been replaced by %s\n", old, successor.Name)
+	if successor.IsDisabled() {
+		fmt.Printf("==> Skipping %s: %s\n", old, successor.Disabled.Message(successor.Name, "disabled"))
+		return nil
+	}
+
+	resolver := &depgraph.Resolver{Loader: loader, Installed: installedLookup(cel)}
+	plan, err := resolver.Plan(successor.Name)
+	if err != nil {
+		return fmt.Errorf("migrate %s to %s: %w", old, successor.Name, err)
+	}
+
+	lnk.Unlink(old)
+	for _, s := range plan {
+		switch {
+		case s.Installed != "":
+			continue
+		case s.Replaces != "":
+			err = upgradeFormula(outdatedPkg{formula: s.Formula, installedVersion: s.Replaces}, paths, cel, lnk, dl)
+		default:
+			err = installFormula(s.Formula, paths, cel, lnk, dl, false, false, false)
+		}
+		if err != nil {
+			return fmt.Errorf("migrate %s to %s: %w (%s is unlinked; run 'grew link %s' to restore it)", old, successor.Name, err, old, old)
+		}
+	}
+	removeReplaced([]string{old}, cel)
 	return nil
 }
 
@@ -117,7 +543,7 @@ func runOutdated(args []string) error {
 	}
 
 	loader := newLoader(paths.Taps)
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 
 	installed, err := cel.List()
 	if err != nil {
@@ -127,26 +553,91 @@ func runOutdated(args []string) error {
 		fmt.Println("No packages installed.")
 		return nil
 	}
+	pins, err := pin.Load(paths.Root)
+	if err != nil {
+		return err
+	}
 
-	found := false
+	succ := successors(loader)
+	found, held := false, false
 	for _, pkg := range installed {
+		pkg.Version = newestVersion(cel, pkg.Name)
+		if newName := loader.RenamedTo(pkg.Name); newName != "" {
+			fmt.Printf("%-20s %s -> %s (renamed)\n", pkg.Name, pkg.Version, newName)
+			found = true
+			continue
+		}
+		if s := succ[pkg.Name]; s != nil {
+			fmt.Printf("%-20s %s -> %s %s (replaced)\n", pkg.Name, pkg.Version, s.Name, s.Version)
+			found = true
+			continue
+		}
 		f, err := loader.LoadByName(pkg.Name)
 		if err != nil {
 			Debugf("skipping %s: not in any tap (%v)\n", pkg.Name, err)
 			continue
 		}
-		if pkg.Version != f.Version {
-			fmt.Printf("%-20s %s -> %s\n", pkg.Name, pkg.Version, f.Version)
+		if formula.IsHeadVersion(pkg.Version) {
+			Debugf("skipping %s: built from HEAD\n", pkg.Name)
+			continue
+		}
+		switch cmp := compareToTap(f, cel, pkg.Version); {
+		case cmp > 0 && pins[pkg.Name]:
+			// Pinned formulas are not upgraded, so they are listed apart
+			// from the outdated ones.
+			fmt.Printf("%-20s %s -> %s (pinned, not upgraded)\n", pkg.Name, pkg.Version, f.PkgVersion())
+			held = true
+		case cmp > 0:
+			note := ""
+			if status, _ := formulaStatus(f); status != "" {
+				note = " (" + status + ")"
+			}
+			fmt.Printf("%-20s %s -> %s%s\n", pkg.Name, pkg.Version, f.PkgVersion(), note)
 			found = true
+		case cmp < 0:
+			fmt.Fprintf(os.Stderr, "Warning: %s %s is newer than the tap version %s\n", pkg.Name, pkg.Version, f.PkgVersion())
 		}
 	}
 
-	if !found {
+	switch {
+	case !found && held:
+		fmt.Println("All unpinned packages are up-to-date.")
+	case !found:
 		fmt.Println("All packages are up-to-date.")
 	}
 	return nil
 }
 
+// compareToTap orders the tap formula against an installed keg. A positive
+// result means the tap is newer; negative means the installed keg is newer
+// (a downgrade in the tap, or a locally built prerelease). The keg's
+// version_scheme and revision come from its manifest so scheme bumps are
+// honoured, and a bumped formula revision makes the tap newer at the same
+// version.
+func compareToTap(f *formula.Formula, cel *cellar.Cellar, installed string) int {
+	return f.ParsedVersion().Compare(cel.KegVersion(f.Name, installed))
+}
+
+// kegOptions returns the build options recorded in a keg's manifest.
+func kegOptions(cel *cellar.Cellar, name, version string) []string {
+	m, err := snapshot.Load(cel.KegPath(name, version))
+	if err != nil {
+		return nil
+	}
+	return m.Options
+}
+
+// newestVersion returns the highest installed version of a formula.
+// Upgrades start from it, whichever version is active, so a formula
+// switched back to an older version is not upgraded again.
+func newestVersion(cel *cellar.Cellar, name string) string {
+	versions, err := cel.InstalledVersions(name)
+	if err != nil || len(versions) == 0 {
+		return ""
+	}
+	return versions[len(versions)-1]
+}
+
 func removeDir(path string) error {
 	return os.RemoveAll(path)
 }
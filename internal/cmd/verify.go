@@ -23,7 +23,7 @@ func runVerify(args []string) error {
 	}
 
 	paths := config.Default()
-	cel := &cellar.Cellar{Path: paths.Cellar}
+	cel := &cellar.Cellar{Path: paths.Cellar, Opt: paths.Opt}
 
 	// If no targets, verify all installed.
 	if len(targets) == 0 {
@@ -72,13 +72,23 @@ func runVerify(args []string) error {
 			allOK = false
 			continue
 		}
+		missingLinks, hijacked, err := snapshot.VerifyLinks(kegPath, paths.Root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error verifying links of %s: %v\n", name, err)
+			allOK = false
+			continue
+		}
+		if len(missingLinks) > 0 || len(hijacked) > 0 {
+			result.OK = false
+		}
 
 		if jsonOutput {
 			jsonResults = append(jsonResults, map[string]any{
 				"name": result.Name, "version": result.Version,
 				"ok": result.OK, "missing": result.Missing,
 				"modified": result.Modified, "added": result.Added,
-				"errors": result.Errors,
+				"errors": result.Errors, "missing_links": missingLinks,
+				"hijacked_links": hijacked,
 			})
 		} else if result.OK {
 			fmt.Printf("%s %s: OK\n", result.Name, result.Version)
@@ -97,6 +107,12 @@ func runVerify(args []string) error {
 			for _, e := range result.Errors {
 				fmt.Printf("  error:    %s\n", e)
 			}
+			for _, l := range missingLinks {
+				fmt.Printf("  missing link:  %s\n", l)
+			}
+			for _, l := range hijacked {
+				fmt.Printf("  hijacked link: %s\n", l)
+			}
 		}
 	}
 
@@ -12,14 +12,23 @@ type Paths struct {
 	Cellar   string
 	Opt      string
 	Bin      string
+	Sbin     string
 	Lib      string
 	Include  string
+	Etc      string
+	Share    string
+	Man      string
 	Taps     string
 	CoreTap  string
 	CaskTap  string
 	Caskroom string
 	AppDir   string
 	Tmp      string
+
+	// Shell completion directories, inside Etc and Share.
+	BashCompletion string
+	ZshCompletion  string
+	FishCompletion string
 }
 
 // DefaultPrefix determines the grew prefix using these rules (in order):
@@ -101,21 +110,29 @@ func FromRoot(root, appDir string) Paths {
 		Cellar:   filepath.Join(root, "Cellar"),
 		Opt:      filepath.Join(root, "opt"),
 		Bin:      filepath.Join(root, "bin"),
+		Sbin:     filepath.Join(root, "sbin"),
 		Lib:      filepath.Join(root, "lib"),
 		Include:  filepath.Join(root, "include"),
+		Etc:      filepath.Join(root, "etc"),
+		Share:    filepath.Join(root, "share"),
+		Man:      filepath.Join(root, "share", "man"),
 		Taps:     filepath.Join(root, "Taps"),
 		CoreTap:  filepath.Join(root, "Taps", "core"),
 		CaskTap:  filepath.Join(root, "Taps", "cask"),
 		Caskroom: filepath.Join(root, "Caskroom"),
 		AppDir:   appDir,
 		Tmp:      filepath.Join(root, "tmp"),
+
+		BashCompletion: filepath.Join(root, "etc", "bash_completion.d"),
+		ZshCompletion:  filepath.Join(root, "share", "zsh", "site-functions"),
+		FishCompletion: filepath.Join(root, "share", "fish", "vendor_completions.d"),
 	}
 }
 
 func (p Paths) Init() error {
 	dirs := []string{
-		p.Root, p.Cellar, p.Opt, p.Bin, p.Lib,
-		p.Include, p.Taps, p.CoreTap, p.CaskTap,
+		p.Root, p.Cellar, p.Opt, p.Bin, p.Sbin, p.Lib,
+		p.Include, p.Etc, p.Share, p.Taps, p.CoreTap, p.CaskTap,
 		p.Caskroom, p.AppDir, p.Tmp,
 	}
 	for _, d := range dirs {
@@ -126,6 +143,13 @@ func (p Paths) Init() error {
 	return nil
 }
 
+// LinkDirs returns the prefix directories kegs are linked into, each
+// mirroring the keg directory of the same name. Man pages and shell
+// completions are linked below Share and Etc.
+func (p Paths) LinkDirs() []string {
+	return []string{p.Bin, p.Sbin, p.Lib, p.Include, p.Etc, p.Share}
+}
+
 // IsDir reports whether path is an existing directory.
 func IsDir(path string) bool {
 	info, err := os.Stat(path)
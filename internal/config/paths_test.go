@@ -50,7 +50,7 @@ func TestInit_CreatesDirectories(t *testing.T) {
 		t.Fatalf("init failed: %v", err)
 	}
 
-	for _, d := range []string{paths.Root, paths.Cellar, paths.Opt, paths.Bin, paths.Lib, paths.Include, paths.Taps, paths.CoreTap, paths.CaskTap, paths.Caskroom, paths.AppDir, paths.Tmp} {
+	for _, d := range []string{paths.Root, paths.Cellar, paths.Opt, paths.Bin, paths.Sbin, paths.Lib, paths.Include, paths.Etc, paths.Share, paths.Taps, paths.CoreTap, paths.CaskTap, paths.Caskroom, paths.AppDir, paths.Tmp} {
 		if info, err := os.Stat(d); err != nil || !info.IsDir() {
 			t.Errorf("directory %q was not created", d)
 		}
@@ -65,6 +65,12 @@ func TestFromRoot(t *testing.T) {
 	if paths.Bin != "/opt/grew/bin" {
 		t.Errorf("Bin = %q", paths.Bin)
 	}
+	if paths.Man != "/opt/grew/share/man" {
+		t.Errorf("Man = %q", paths.Man)
+	}
+	if paths.ZshCompletion != "/opt/grew/share/zsh/site-functions" {
+		t.Errorf("ZshCompletion = %q", paths.ZshCompletion)
+	}
 	if paths.AppDir != "/Users/test/Applications" {
 		t.Errorf("AppDir = %q", paths.AppDir)
 	}
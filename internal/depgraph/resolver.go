@@ -1,11 +1,13 @@
 package depgraph
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
 
 	"github.com/homegrew/grew/internal/formula"
+	"github.com/homegrew/grew/internal/vercmp"
 )
 
 type CycleError struct {
@@ -16,55 +18,442 @@ func (e *CycleError) Error() string {
 	return fmt.Sprintf("circular dependency detected: %s", strings.Join(e.Chain, " -> "))
 }
 
+// Requirement is one version constraint placed on a formula, together with
+// the dependency chain that introduced it.
+type Requirement struct {
+	Chain      []string // root -> ... -> the formula declaring the constraint
+	Name       string   // the constrained formula
+	Constraint vercmp.Constraint
+}
+
+func (r Requirement) String() string {
+	return fmt.Sprintf("%s requires %s %s", strings.Join(r.Chain, " -> "), r.Name, r.Constraint)
+}
+
+// UnsatisfiableError reports a formula for which no available version
+// meets every constraint placed on it.
+type UnsatisfiableError struct {
+	Name         string
+	Requirements []Requirement
+	Candidates   []string // versions considered, e.g. "1.3 (tap)"
+}
+
+// ConflictError reports two formulas in a plan that cannot be installed
+// side by side.
+type ConflictError struct {
+	Name   string
+	With   string
+	Reason string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s conflicts with %s (%s)", e.Name, e.With, e.Reason)
+}
+
+func (e *UnsatisfiableError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "cannot satisfy version constraints on %s", e.Name)
+	if len(e.Candidates) > 0 {
+		fmt.Fprintf(&b, " (available: %s)", strings.Join(e.Candidates, ", "))
+	}
+	for _, req := range e.Requirements {
+		fmt.Fprintf(&b, "\n  %s", req)
+	}
+	return b.String()
+}
+
 type Resolver struct {
 	Loader *formula.Loader
+	// Installed optionally reports the Cellar version of a formula, parsed
+	// with its recorded revision (see cellar.KegVersion). An installed
+	// version is preferred over the tap version whenever it satisfies
+	// every constraint, so resolving never upgrades needlessly.
+	Installed func(name string) (vercmp.Version, bool)
+	// Platform is the platform formulas are evaluated for. The zero
+	// value means the running platform.
+	Platform formula.Platform
+	// IncludeBuild adds the root formula's build_dependencies (and their
+	// runtime dependencies) to the plan, for building it from source.
+	IncludeBuild bool
+	// Options are build option flags ("with-<name>", "without-<name>")
+	// for the root formula. They decide which of its optional and
+	// recommended dependencies are in the plan; other formulas use their
+	// defaults.
+	Options []string
+}
+
+// Step is one formula in an install plan.
+type Step struct {
+	Formula *formula.Formula
+	// Installed is the Cellar version that already satisfies every
+	// constraint. Empty when Formula.Version has to be installed.
+	Installed string
+	// Replaces is an installed version that violates a constraint and must
+	// be upgraded to Formula.Version.
+	Replaces string
+	// Build is true when the formula is only needed to build the root
+	// from source: it is not reachable through runtime dependencies.
+	Build bool
 }
 
 // Resolve returns formulas in installation order (dependencies first).
 func (r *Resolver) Resolve(name string) ([]*formula.Formula, error) {
-	// Build the full dependency graph by loading formulas transitively.
+	steps, err := r.Plan(name)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*formula.Formula, len(steps))
+	for i, s := range steps {
+		result[i] = s.Formula
+	}
+	return result, nil
+}
+
+// Plan resolves name and its transitive dependencies, choosing for each
+// formula a version that satisfies every constraint placed on it, and
+// returns the steps in installation order (dependencies first). A
+// dependency with no formula of its name is satisfied by a formula that
+// provides it; aliases and renamed formulas resolve to their target.
+// Formulas in the plan that conflict are reported as a *ConflictError.
+//
+// Candidates are the installed version (if any) and the tap version. The
+// search backtracks over those choices, so an installed version that
+// conflicts with a constraint discovered later is replaced by the tap
+// version when that resolves the conflict.
+func (r *Resolver) Plan(name string) ([]Step, error) {
+	sv := &solver{r: r, formulas: map[string]*formula.Formula{}, names: map[string]string{}}
+	// Resolve aliases and renames so the plan uses formula names.
+	name = sv.canonical(name)
+	sv.root = name
+	if len(r.Options) > 0 {
+		f, err := sv.load(name, []string{name})
+		if err != nil {
+			return nil, err
+		}
+		if sv.formulas[name], err = f.WithOptions(r.Options); err != nil {
+			return nil, err
+		}
+	}
+	st := &solveState{
+		chosen: map[string]candidate{},
+		reqs:   map[string][]Requirement{},
+		chain:  map[string][]string{name: {name}},
+	}
+	st, err := sv.solve(st, []string{name})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sv.checkConflicts(st); err != nil {
+		return nil, err
+	}
+
 	// graph[A] = [B, C] means "A depends on B and C".
-	graph := map[string][]string{}
-	formulas := map[string]*formula.Formula{}
+	graph := make(map[string][]string, len(st.chosen))
+	for n := range st.chosen {
+		deps := []string{}
+		for _, d := range sv.deps(sv.formulas[n]) {
+			deps = append(deps, sv.canonical(d.Name))
+		}
+		graph[n] = deps
+	}
+	sorted, err := topoSort(graph)
+	if err != nil {
+		return nil, err
+	}
+
+	runtimeDeps := sv.runtimeClosure(name)
+	steps := make([]Step, len(sorted))
+	for i, n := range sorted {
+		c := st.chosen[n]
+		step := Step{Formula: sv.formulas[n], Build: !runtimeDeps[n]}
+		if c.installed {
+			step.Installed = c.version
+		} else if ver, ok := sv.installed(n); ok {
+			step.Replaces = ver.String()
+		}
+		steps[i] = step
+	}
+	return steps, nil
+}
+
+type candidate struct {
+	version   string
+	parsed    vercmp.Version
+	installed bool
+}
+
+// solveState is one branch of the search. It is cloned before every
+// choice so backtracking is just dropping the clone.
+type solveState struct {
+	chosen map[string]candidate
+	reqs   map[string][]Requirement
+	chain  map[string][]string // first dependency path from the root
+}
 
+func (st *solveState) clone() *solveState {
+	next := &solveState{
+		chosen: make(map[string]candidate, len(st.chosen)),
+		reqs:   make(map[string][]Requirement, len(st.reqs)),
+		chain:  make(map[string][]string, len(st.chain)),
+	}
+	for k, v := range st.chosen {
+		next.chosen[k] = v
+	}
+	for k, v := range st.reqs {
+		next.reqs[k] = append([]Requirement(nil), v...)
+	}
+	for k, v := range st.chain {
+		next.chain[k] = v
+	}
+	return next
+}
+
+type solver struct {
+	r        *Resolver
+	root     string
+	formulas map[string]*formula.Formula
+	names    map[string]string // dependency name -> providing formula
+}
+
+// canonical returns the formula that satisfies a dependency on name:
+// name itself, or a formula that provides it.
+func (sv *solver) canonical(name string) string {
+	if n, ok := sv.names[name]; ok {
+		return n
+	}
+	if _, ok := sv.formulas[name]; ok {
+		return name
+	}
+	n := name
+	if f, err := sv.r.Loader.LoadProvider(name); err == nil {
+		if f, err = sv.evaluate(f); err != nil {
+			sv.names[name] = n
+			return n
+		}
+		n = f.Name
+		if _, ok := sv.formulas[n]; !ok {
+			sv.formulas[n] = f
+		}
+	}
+	sv.names[name] = n
+	return n
+}
+
+// checkConflicts reports the first pair of chosen formulas that conflict.
+func (sv *solver) checkConflicts(st *solveState) error {
+	names := make([]string, 0, len(st.chosen))
+	for n := range st.chosen {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			if ok, reason := sv.formulas[a].ConflictsWith(sv.formulas[b]); ok {
+				return &ConflictError{Name: a, With: b, Reason: reason}
+			}
+		}
+	}
+	return nil
+}
+
+func (sv *solver) installed(name string) (vercmp.Version, bool) {
+	if sv.r.Installed == nil {
+		return vercmp.Version{}, false
+	}
+	return sv.r.Installed(name)
+}
+
+func (sv *solver) platform() formula.Platform {
+	if sv.r.Platform == (formula.Platform{}) {
+		return formula.HostPlatform()
+	}
+	return sv.r.Platform
+}
+
+// evaluate returns f as evaluated for the resolver's platform. Formulas
+// are loaded for the host, so this only re-evaluates when resolving for
+// another platform.
+func (sv *solver) evaluate(f *formula.Formula) (*formula.Formula, error) {
+	if p := sv.platform(); f.Platform() != p {
+		return f.ForPlatform(p)
+	}
+	return f, nil
+}
+
+// deps returns the dependencies of f that take part in resolution.
+func (sv *solver) deps(f *formula.Formula) []formula.Dependency {
+	deps := f.Deps()
+	if sv.r.IncludeBuild && f.Name == sv.root {
+		deps = append(deps, f.BuildDeps()...)
+	}
+	return deps
+}
+
+// runtimeClosure returns name and every formula reachable from it through
+// runtime dependencies alone.
+func (sv *solver) runtimeClosure(name string) map[string]bool {
+	seen := map[string]bool{}
 	queue := []string{name}
-	visited := map[string]bool{}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		if f, ok := sv.formulas[n]; ok {
+			for _, d := range f.Deps() {
+				queue = append(queue, sv.canonical(d.Name))
+			}
+		}
+	}
+	return seen
+}
 
+func (sv *solver) load(name string, chain []string) (*formula.Formula, error) {
+	if f, ok := sv.formulas[name]; ok {
+		return f, nil
+	}
+	f, err := sv.r.Loader.LoadByName(name)
+	if err != nil {
+		if name == sv.root {
+			return nil, err
+		}
+		parent := sv.root
+		if len(chain) >= 2 {
+			parent = chain[len(chain)-2]
+		}
+		return nil, fmt.Errorf("dependency %q required by %q not found: %w", name, parent, err)
+	}
+	if f, err = sv.evaluate(f); err != nil {
+		return nil, err
+	}
+	sv.formulas[name] = f
+	return f, nil
+}
+
+// candidates lists the versions of f that may be chosen, most preferred
+// first.
+func (sv *solver) candidates(f *formula.Formula) []candidate {
+	var cs []candidate
+	if ver, ok := sv.installed(f.Name); ok {
+		cs = append(cs, candidate{version: ver.String(), parsed: ver, installed: true})
+		if ver.String() == f.PkgVersion() {
+			return cs
+		}
+	}
+	return append(cs, candidate{version: f.PkgVersion(), parsed: f.ParsedVersion()})
+}
+
+func (sv *solver) solve(st *solveState, queue []string) (*solveState, error) {
 	for len(queue) > 0 {
-		current := queue[0]
+		name := queue[0]
 		queue = queue[1:]
-		if visited[current] {
+		if _, done := st.chosen[name]; done {
 			continue
 		}
-		visited[current] = true
 
-		f, err := r.Loader.LoadByName(current)
+		f, err := sv.load(name, st.chain[name])
 		if err != nil {
-			if current == name {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, c := range sv.candidates(f) {
+			if !satisfies(c.parsed, st.reqs[name]) {
+				continue
+			}
+			next := st.clone()
+			next.chosen[name] = c
+			nextQueue := append([]string(nil), queue...)
+			if err := sv.constrain(next, f, &nextQueue); err != nil {
+				lastErr = err
+				continue
+			}
+			result, err := sv.solve(next, nextQueue)
+			if err == nil {
+				return result, nil
+			}
+			var unsat *UnsatisfiableError
+			if !errors.As(err, &unsat) {
 				return nil, err
 			}
-			return nil, fmt.Errorf("dependency %q required by %q not found: %w", current, name, err)
+			lastErr = err
+		}
+		if lastErr == nil {
+			lastErr = sv.unsatisfiable(name, st.reqs[name])
+		}
+		return nil, lastErr
+	}
+	return st, nil
+}
+
+// constrain records the requirements f places on its dependencies and
+// queues the ones not chosen yet. It fails if a requirement rules out a
+// version that was already chosen.
+func (sv *solver) constrain(st *solveState, f *formula.Formula, queue *[]string) error {
+	chain := st.chain[f.Name]
+	for _, d := range sv.deps(f) {
+		name := sv.canonical(d.Name)
+		if _, seen := st.chain[name]; !seen {
+			st.chain[name] = append(append([]string(nil), chain...), name)
+		}
+		if !d.Constraint.IsEmpty() {
+			st.reqs[name] = append(st.reqs[name], Requirement{
+				Chain:      chain,
+				Name:       name,
+				Constraint: d.Constraint,
+			})
+		}
+		if c, ok := st.chosen[name]; ok {
+			if !d.Constraint.Allows(c.parsed) {
+				return sv.unsatisfiable(name, st.reqs[name])
+			}
+			continue
 		}
-		formulas[current] = f
-		graph[current] = f.Dependencies
+		*queue = append(*queue, name)
+	}
+	return nil
+}
 
-		for _, dep := range f.Dependencies {
-			if !visited[dep] {
-				queue = append(queue, dep)
+func (sv *solver) unsatisfiable(name string, reqs []Requirement) error {
+	e := &UnsatisfiableError{Name: name, Requirements: reqs}
+	if f, ok := sv.formulas[name]; ok {
+		for _, c := range sv.candidates(f) {
+			origin := "tap"
+			if c.installed {
+				origin = "installed"
 			}
+			e.Candidates = append(e.Candidates, fmt.Sprintf("%s (%s)", c.version, origin))
 		}
 	}
+	return e
+}
 
-	sorted, err := topoSort(graph)
-	if err != nil {
-		return nil, err
+// Unsatisfied returns the constraints that dependents place on name which
+// version does not meet, e.g. before an installed formula is upgraded.
+// Each requirement's chain is the dependent alone.
+func Unsatisfied(name string, version vercmp.Version, dependents []*formula.Formula) []Requirement {
+	var reqs []Requirement
+	for _, f := range dependents {
+		for _, d := range f.Deps() {
+			if d.Name != name || d.Constraint.Allows(version) {
+				continue
+			}
+			reqs = append(reqs, Requirement{Chain: []string{f.Name}, Name: name, Constraint: d.Constraint})
+		}
 	}
+	return reqs
+}
 
-	result := make([]*formula.Formula, len(sorted))
-	for i, n := range sorted {
-		result[i] = formulas[n]
+func satisfies(version vercmp.Version, reqs []Requirement) bool {
+	for _, req := range reqs {
+		if !req.Constraint.Allows(version) {
+			return false
+		}
 	}
-	return result, nil
+	return true
 }
 
 // topoSort performs Kahn's algorithm with a pre-built reverse adjacency
@@ -1,24 +1,29 @@
 package depgraph
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/homegrew/grew/internal/formula"
+	"github.com/homegrew/grew/internal/vercmp"
 )
 
-func writeFormula(t *testing.T, dir, name string, deps []string) {
+// writeFormula writes a binary formula name at version with deps to dir,
+// with body (provides, build_dependencies, ...) appended to the YAML.
+func writeFormula(t *testing.T, dir, name, version string, deps []string, body string) {
 	t.Helper()
-	yaml := "name: " + name + "\nversion: \"1.0\"\ndescription: \"test\"\nhomepage: \"https://example.com\"\nlicense: \"MIT\"\nurl:\n  darwin_arm64: \"https://example.com/" + name + "\"\n  linux_amd64: \"https://example.com/" + name + "\"\nsha256:\n  darwin_arm64: \"abc\"\n  linux_amd64: \"def\"\ninstall:\n  type: binary\n  binary_name: " + name + "\ndependencies:\n"
+	yaml := "name: " + name + "\nversion: \"" + version + "\"\ndescription: \"test\"\nhomepage: \"https://example.com\"\nlicense: \"MIT\"\nurl:\n  darwin_arm64: \"https://example.com/" + name + "\"\n  linux_amd64: \"https://example.com/" + name + "\"\nsha256:\n  darwin_arm64: \"abc\"\n  linux_amd64: \"def\"\ninstall:\n  type: binary\n  binary_name: " + name + "\ndependencies:\n"
 	if len(deps) == 0 {
 		yaml += "  []\n"
-	} else {
-		for _, d := range deps {
-			yaml += "  - " + d + "\n"
-		}
 	}
-	yaml += "keg_only: false\n"
+	for _, d := range deps {
+		yaml += "  - \"" + d + "\"\n"
+	}
+	yaml += "keg_only: false\n" + body
 	if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(yaml), 0644); err != nil {
 		t.Fatal(err)
 	}
@@ -28,7 +33,7 @@ func TestResolve_NoDeps(t *testing.T) {
 	tmpDir := t.TempDir()
 	tapDir := filepath.Join(tmpDir, "core")
 	os.MkdirAll(tapDir, 0755)
-	writeFormula(t, tapDir, "solo", nil)
+	writeFormula(t, tapDir, "solo", "1.0", nil, "")
 
 	loader := &formula.Loader{TapDir: tmpDir}
 	resolver := &Resolver{Loader: loader}
@@ -49,9 +54,9 @@ func TestResolve_LinearChain(t *testing.T) {
 	tmpDir := t.TempDir()
 	tapDir := filepath.Join(tmpDir, "core")
 	os.MkdirAll(tapDir, 0755)
-	writeFormula(t, tapDir, "a", []string{"b"})
-	writeFormula(t, tapDir, "b", []string{"c"})
-	writeFormula(t, tapDir, "c", nil)
+	writeFormula(t, tapDir, "a", "1.0", []string{"b"}, "")
+	writeFormula(t, tapDir, "b", "1.0", []string{"c"}, "")
+	writeFormula(t, tapDir, "c", "1.0", nil, "")
 
 	loader := &formula.Loader{TapDir: tmpDir}
 	resolver := &Resolver{Loader: loader}
@@ -80,10 +85,10 @@ func TestResolve_Diamond(t *testing.T) {
 	tmpDir := t.TempDir()
 	tapDir := filepath.Join(tmpDir, "core")
 	os.MkdirAll(tapDir, 0755)
-	writeFormula(t, tapDir, "a", []string{"b", "c"})
-	writeFormula(t, tapDir, "b", []string{"d"})
-	writeFormula(t, tapDir, "c", []string{"d"})
-	writeFormula(t, tapDir, "d", nil)
+	writeFormula(t, tapDir, "a", "1.0", []string{"b", "c"}, "")
+	writeFormula(t, tapDir, "b", "1.0", []string{"d"}, "")
+	writeFormula(t, tapDir, "c", "1.0", []string{"d"}, "")
+	writeFormula(t, tapDir, "d", "1.0", nil, "")
 
 	loader := &formula.Loader{TapDir: tmpDir}
 	resolver := &Resolver{Loader: loader}
@@ -117,8 +122,8 @@ func TestResolve_CircularDependency(t *testing.T) {
 	tmpDir := t.TempDir()
 	tapDir := filepath.Join(tmpDir, "core")
 	os.MkdirAll(tapDir, 0755)
-	writeFormula(t, tapDir, "x", []string{"y"})
-	writeFormula(t, tapDir, "y", []string{"x"})
+	writeFormula(t, tapDir, "x", "1.0", []string{"y"}, "")
+	writeFormula(t, tapDir, "y", "1.0", []string{"x"}, "")
 
 	loader := &formula.Loader{TapDir: tmpDir}
 	resolver := &Resolver{Loader: loader}
@@ -136,7 +141,7 @@ func TestResolve_MissingDependency(t *testing.T) {
 	tmpDir := t.TempDir()
 	tapDir := filepath.Join(tmpDir, "core")
 	os.MkdirAll(tapDir, 0755)
-	writeFormula(t, tapDir, "a", []string{"missing"})
+	writeFormula(t, tapDir, "a", "1.0", []string{"missing"}, "")
 
 	loader := &formula.Loader{TapDir: tmpDir}
 	resolver := &Resolver{Loader: loader}
@@ -146,3 +151,396 @@ func TestResolve_MissingDependency(t *testing.T) {
 		t.Fatal("expected error for missing dependency")
 	}
 }
+
+func TestPlan_ConstraintSatisfied(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeFormula(t, tapDir, "app", "1.0", []string{"zlib ~> 1.3", "openssl@3 >= 3.1"}, "")
+	writeFormula(t, tapDir, "zlib", "1.3.1", nil, "")
+	writeFormula(t, tapDir, "openssl@3", "3.2.0", nil, "")
+
+	resolver := &Resolver{Loader: &formula.Loader{TapDir: tmpDir}}
+	steps, err := resolver.Plan("app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(steps) != 3 {
+		t.Fatalf("expected 3 steps, got %d", len(steps))
+	}
+	if steps[len(steps)-1].Formula.Name != "app" {
+		t.Errorf("app should be installed last, got %q", steps[len(steps)-1].Formula.Name)
+	}
+}
+
+func TestPlan_ConflictingDependents(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeFormula(t, tapDir, "app", "1.0", []string{"b", "c"}, "")
+	writeFormula(t, tapDir, "b", "1.0", []string{"zlib >= 1.3"}, "")
+	writeFormula(t, tapDir, "c", "1.0", []string{"zlib < 1.3"}, "")
+	writeFormula(t, tapDir, "zlib", "1.3.1", nil, "")
+
+	resolver := &Resolver{Loader: &formula.Loader{TapDir: tmpDir}}
+	_, err := resolver.Plan("app")
+	var unsat *UnsatisfiableError
+	if !errors.As(err, &unsat) {
+		t.Fatalf("expected *UnsatisfiableError, got %T: %v", err, err)
+	}
+	if unsat.Name != "zlib" {
+		t.Errorf("name = %q, want zlib", unsat.Name)
+	}
+	if len(unsat.Requirements) != 2 {
+		t.Fatalf("expected 2 requirements, got %d", len(unsat.Requirements))
+	}
+	msg := err.Error()
+	for _, want := range []string{"app -> b requires zlib >= 1.3", "app -> c requires zlib < 1.3", "1.3.1 (tap)"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error should contain %q, got:\n%s", want, msg)
+		}
+	}
+}
+
+func TestPlan_PrefersInstalledVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeFormula(t, tapDir, "app", "1.0", []string{"zlib >= 1.2"}, "")
+	writeFormula(t, tapDir, "zlib", "1.3.1", nil, "")
+
+	resolver := &Resolver{
+		Loader: &formula.Loader{TapDir: tmpDir},
+		Installed: func(name string) (vercmp.Version, bool) {
+			if name == "zlib" {
+				return vercmp.ParsePkg("1.2.13"), true
+			}
+			return vercmp.Version{}, false
+		},
+	}
+	steps, err := resolver.Plan("app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if steps[0].Formula.Name != "zlib" || steps[0].Installed != "1.2.13" {
+		t.Errorf("zlib step = %+v, want installed 1.2.13", steps[0])
+	}
+}
+
+func TestPlan_InstalledRevision(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeFormula(t, tapDir, "app", "1.0", []string{"zlib = 1.2.13"}, "")
+	writeFormula(t, tapDir, "zlib", "1.3.1", nil, "")
+
+	resolver := &Resolver{
+		Loader: &formula.Loader{TapDir: tmpDir},
+		Installed: func(name string) (vercmp.Version, bool) {
+			if name == "zlib" {
+				return vercmp.ParsePkg("1.2.13_1"), true
+			}
+			return vercmp.Version{}, false
+		},
+	}
+	steps, err := resolver.Plan("app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if steps[0].Installed != "1.2.13_1" {
+		t.Errorf("zlib step = %+v, want installed 1.2.13_1", steps[0])
+	}
+}
+
+func TestPlan_UpgradesInstalledVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeFormula(t, tapDir, "app", "1.0", []string{"zlib >= 1.3"}, "")
+	writeFormula(t, tapDir, "zlib", "1.3.1", nil, "")
+
+	resolver := &Resolver{
+		Loader: &formula.Loader{TapDir: tmpDir},
+		Installed: func(name string) (vercmp.Version, bool) {
+			if name == "zlib" {
+				return vercmp.ParsePkg("1.2.13"), true
+			}
+			return vercmp.Version{}, false
+		},
+	}
+	steps, err := resolver.Plan("app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if steps[0].Installed != "" || steps[0].Replaces != "1.2.13" {
+		t.Errorf("zlib step = %+v, want replacement of 1.2.13", steps[0])
+	}
+}
+
+func TestPlan_InstalledVersionUnsatisfiable(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeFormula(t, tapDir, "app", "1.0", []string{"zlib >= 2"}, "")
+	writeFormula(t, tapDir, "zlib", "1.3.1", nil, "")
+
+	resolver := &Resolver{
+		Loader: &formula.Loader{TapDir: tmpDir},
+		Installed: func(name string) (vercmp.Version, bool) {
+			if name == "zlib" {
+				return vercmp.ParsePkg("1.2.13"), true
+			}
+			return vercmp.Version{}, false
+		},
+	}
+	_, err := resolver.Plan("app")
+	var unsat *UnsatisfiableError
+	if !errors.As(err, &unsat) {
+		t.Fatalf("expected *UnsatisfiableError, got %T: %v", err, err)
+	}
+	if !strings.Contains(err.Error(), "1.2.13 (installed)") {
+		t.Errorf("error should list the installed candidate, got:\n%s", err)
+	}
+}
+
+func TestUnsatisfied(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeFormula(t, tapDir, "foo", "1.0", []string{"bar < 2"}, "")
+	writeFormula(t, tapDir, "baz", "1.0", []string{"bar"}, "")
+	loader := &formula.Loader{TapDir: tmpDir}
+	var dependents []*formula.Formula
+	for _, name := range []string{"foo", "baz"} {
+		f, err := loader.LoadByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dependents = append(dependents, f)
+	}
+
+	if reqs := Unsatisfied("bar", vercmp.Parse("1.9"), dependents); len(reqs) != 0 {
+		t.Errorf("bar 1.9: unexpected requirements %v", reqs)
+	}
+	reqs := Unsatisfied("bar", vercmp.Parse("2.0").WithRevision(1), dependents)
+	if len(reqs) != 1 || reqs[0].String() != "foo requires bar < 2" {
+		t.Errorf("bar 2.0: requirements = %v, want foo requires bar < 2", reqs)
+	}
+}
+
+func stepNames(steps []Step) map[string]Step {
+	m := make(map[string]Step, len(steps))
+	for _, s := range steps {
+		m[s.Formula.Name] = s
+	}
+	return m
+}
+
+func TestPlan_LinuxDependencies(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeFormula(t, tapDir, "app", "1.0", []string{"zlib"}, "build_dependencies:\n  - cmake\nlinux_dependencies:\n  - glibc\n")
+	writeFormula(t, tapDir, "zlib", "1.3", nil, "")
+	writeFormula(t, tapDir, "cmake", "3.30", []string{"zlib"}, "")
+	writeFormula(t, tapDir, "glibc", "2.40", nil, "")
+	loader := &formula.Loader{TapDir: tmpDir}
+
+	steps, err := (&Resolver{Loader: loader, Platform: formula.Platform{OS: "linux", Arch: "amd64"}}).Plan("app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := stepNames(steps)
+	if _, ok := got["glibc"]; !ok {
+		t.Error("linux_dependencies should be resolved on linux")
+	}
+	if _, ok := got["cmake"]; ok {
+		t.Error("build_dependencies should not be resolved without IncludeBuild")
+	}
+
+	steps, err = (&Resolver{Loader: loader, Platform: formula.Platform{OS: "darwin", Arch: "arm64"}}).Plan("app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := stepNames(steps)["glibc"]; ok {
+		t.Error("linux_dependencies should not be resolved on darwin")
+	}
+}
+
+func TestPlan_PlatformBlocks(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	yaml := `name: app
+version: "1.0"
+url:
+  linux_amd64: "https://example.com/app"
+install:
+  type: binary
+dependencies:
+  - zlib
+on_macos:
+  dependencies:
+    - libiconv
+on_linux:
+  dependencies:
+    - glibc
+on_arm:
+  dependencies:
+    - "zlib >= 1.3"
+`
+	if err := os.WriteFile(filepath.Join(tapDir, "app.yaml"), []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeFormula(t, tapDir, "zlib", "1.3", nil, "")
+	writeFormula(t, tapDir, "glibc", "2.40", nil, "")
+	writeFormula(t, tapDir, "libiconv", "1.17", nil, "")
+	loader := &formula.Loader{TapDir: tmpDir}
+
+	tests := []struct {
+		platform formula.Platform
+		want     string
+	}{
+		{formula.Platform{OS: "darwin", Arch: "arm64"}, "app,libiconv,zlib"},
+		{formula.Platform{OS: "linux", Arch: "amd64"}, "app,glibc,zlib"},
+	}
+	for _, tt := range tests {
+		steps, err := (&Resolver{Loader: loader, Platform: tt.platform}).Plan("app")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.platform, err)
+		}
+		var names []string
+		for _, s := range steps {
+			names = append(names, s.Formula.Name)
+		}
+		sort.Strings(names)
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("%s: plan = %s, want %s", tt.platform, got, tt.want)
+		}
+	}
+}
+
+func TestPlan_IncludeBuild(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeFormula(t, tapDir, "app", "1.0", []string{"zlib"}, "build_dependencies:\n  - cmake\nlinux_dependencies:\n  - glibc\n")
+	writeFormula(t, tapDir, "zlib", "1.3", nil, "")
+	writeFormula(t, tapDir, "cmake", "3.30", []string{"zlib"}, "")
+	writeFormula(t, tapDir, "glibc", "2.40", nil, "")
+
+	resolver := &Resolver{Loader: &formula.Loader{TapDir: tmpDir}, Platform: formula.Platform{OS: "darwin", Arch: "arm64"}, IncludeBuild: true}
+	steps, err := resolver.Plan("app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := stepNames(steps)
+	if s, ok := got["cmake"]; !ok || !s.Build {
+		t.Errorf("cmake should be a build-only step, got %+v (present %v)", s, ok)
+	}
+	if got["zlib"].Build {
+		t.Error("zlib is a runtime dependency and must not be marked build-only")
+	}
+	if got["app"].Build {
+		t.Error("the root must not be marked build-only")
+	}
+	if steps[len(steps)-1].Formula.Name != "app" {
+		t.Errorf("app should come last, got %q", steps[len(steps)-1].Formula.Name)
+	}
+}
+
+func TestPlan_Options(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeFormula(t, tapDir, "app", "1.0", nil, "optional_dependencies:\n  - openssl\nrecommended_dependencies:\n  - zlib\n")
+	writeFormula(t, tapDir, "openssl", "1.0", nil, "")
+	writeFormula(t, tapDir, "zlib", "1.0", nil, "")
+
+	loader := &formula.Loader{TapDir: tmpDir}
+	steps, err := (&Resolver{Loader: loader}).Plan("app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := stepNames(steps)
+	if _, ok := got["openssl"]; ok {
+		t.Error("optional dependency openssl should be left out by default")
+	}
+	if _, ok := got["zlib"]; !ok {
+		t.Error("recommended dependency zlib should be included by default")
+	}
+
+	steps, err = (&Resolver{Loader: loader, Options: []string{"with-openssl", "without-zlib"}}).Plan("app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got = stepNames(steps)
+	if _, ok := got["openssl"]; !ok {
+		t.Error("--with-openssl should add openssl")
+	}
+	if _, ok := got["zlib"]; ok {
+		t.Error("--without-zlib should drop zlib")
+	}
+	if used := got["app"].Formula.UsedOptions(); len(used) != 2 {
+		t.Errorf("root UsedOptions = %v, want both flags", used)
+	}
+
+	if _, err := (&Resolver{Loader: loader, Options: []string{"with-gnutls"}}).Plan("app"); err == nil {
+		t.Error("expected error for an unknown option")
+	}
+}
+
+func TestPlan_DependencyOnProvidedName(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeFormula(t, tapDir, "app", "1.0", []string{"awk"}, "")
+	writeFormula(t, tapDir, "gawk", "1.0", nil, "provides:\n  - awk\n")
+
+	resolver := &Resolver{Loader: &formula.Loader{TapDir: tmpDir}}
+	steps, err := resolver.Plan("app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(steps) != 2 || steps[0].Formula.Name != "gawk" {
+		t.Errorf("expected gawk to satisfy awk before app, got %v", stepNames(steps))
+	}
+}
+
+func TestPlan_Aliases(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeFormula(t, tapDir, "app", "1.0", []string{"python"}, "")
+	writeFormula(t, tapDir, "python@3.12", "1.0", nil, "")
+	os.WriteFile(filepath.Join(tapDir, formula.AliasesFile), []byte(`{"python": "python@3.12", "py-app": "app"}`), 0644)
+
+	resolver := &Resolver{Loader: &formula.Loader{TapDir: tmpDir}}
+	steps, err := resolver.Plan("py-app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := stepNames(steps)
+	if len(got) != 2 || got["python@3.12"].Formula == nil || got["app"].Formula == nil {
+		t.Errorf("expected python@3.12 and app, got %v", got)
+	}
+}
+
+func TestPlan_Conflict(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeFormula(t, tapDir, "app", "1.0", []string{"jq", "gojq"}, "")
+	writeFormula(t, tapDir, "jq", "1.0", nil, "")
+	writeFormula(t, tapDir, "gojq", "1.0", nil, "conflicts_with:\n  - jq\n")
+
+	resolver := &Resolver{Loader: &formula.Loader{TapDir: tmpDir}}
+	_, err := resolver.Plan("app")
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "gojq declares conflicts_with jq") {
+		t.Errorf("error should explain the conflict: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+// Package deprecation describes the deprecated: and disabled: blocks that
+// formulas and casks share.
+package deprecation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/homegrew/grew/internal/validation"
+)
+
+// DateLayout is the format of deprecation and disable dates.
+const DateLayout = "2006-01-02"
+
+// Notice is the deprecated: or disabled: block of a formula or cask.
+// A Date in the future schedules the change; until then the block is
+// inactive. Replacement names the formula (or cask) to use instead.
+//
+//	deprecated:
+//	  date: "2025-06-01"
+//	  reason: "is unmaintained upstream"
+//	  replacement: jaq
+type Notice struct {
+	Date        string `yaml:"date"`
+	Reason      string `yaml:"reason"`
+	Replacement string `yaml:"replacement"`
+}
+
+// Active reports whether the block is in effect at now. A nil block is
+// never active.
+func (d *Notice) Active(now time.Time) bool {
+	if d == nil {
+		return false
+	}
+	if d.Date == "" {
+		return true
+	}
+	t, err := time.Parse(DateLayout, d.Date)
+	return err != nil || !t.After(now)
+}
+
+// Message describes the block for name, e.g. "jq has been deprecated since
+// 2025-06-01 because it is unmaintained upstream. Use jaq instead." Verb is
+// "deprecated" or "disabled".
+func (d *Notice) Message(name, verb string) string {
+	msg := name + " has been " + verb
+	if d.Date != "" {
+		msg += " since " + d.Date
+	}
+	if d.Reason != "" {
+		msg += " because it " + d.Reason
+	}
+	msg += "."
+	if d.Replacement != "" {
+		msg += " Use " + d.Replacement + " instead."
+	}
+	return msg
+}
+
+// Validate checks the date format and replacement name. Field is used in
+// error messages ("deprecated" or "disabled").
+func (d *Notice) Validate(field string) error {
+	if d == nil {
+		return nil
+	}
+	if d.Date != "" {
+		if _, err := time.Parse(DateLayout, d.Date); err != nil {
+			return fmt.Errorf("%s: date %q must be YYYY-MM-DD", field, d.Date)
+		}
+	}
+	if d.Replacement != "" && !validation.IsValidName(d.Replacement) {
+		return fmt.Errorf("%s: replacement %q contains invalid characters", field, d.Replacement)
+	}
+	return nil
+}
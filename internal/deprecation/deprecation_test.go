@@ -0,0 +1,37 @@
+package deprecation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNoticeActive(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		d    *Notice
+		want bool
+	}{
+		{"nil", nil, false},
+		{"no date", &Notice{Reason: "is unmaintained"}, true},
+		{"past", &Notice{Date: "2024-01-01"}, true},
+		{"today", &Notice{Date: "2025-06-01"}, true},
+		{"future", &Notice{Date: "2025-07-01"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.d.Active(now); got != tt.want {
+			t.Errorf("%s: Active = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNoticeMessage(t *testing.T) {
+	d := &Notice{Date: "2025-06-01", Reason: "is unmaintained upstream", Replacement: "jaq"}
+	want := "jq has been deprecated since 2025-06-01 because it is unmaintained upstream. Use jaq instead."
+	if got := d.Message("jq", "deprecated"); got != want {
+		t.Errorf("Message = %q, want %q", got, want)
+	}
+	if got := (&Notice{}).Message("jq", "disabled"); got != "jq has been disabled." {
+		t.Errorf("Message = %q", got)
+	}
+}
@@ -58,22 +58,29 @@ func (d *Downloader) Download(url, filename string) (string, error) {
 }
 
 func VerifySHA256(filepath, expected string) error {
-	f, err := os.Open(filepath)
+	actual, err := FileSHA256(filepath)
 	if err != nil {
-		return fmt.Errorf("open for verification: %w", err)
+		return err
+	}
+	if actual != expected {
+		return fmt.Errorf("SHA256 mismatch: expected %.16s..., got %.16s...", expected, actual)
+	}
+	return nil
+}
+
+// FileSHA256 returns the hex-encoded SHA256 of the file at path.
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open for verification: %w", err)
 	}
 	defer f.Close()
 
 	h := sha256.New()
 	if _, err := io.Copy(h, f); err != nil {
-		return fmt.Errorf("compute SHA256: %w", err)
-	}
-
-	actual := hex.EncodeToString(h.Sum(nil))
-	if actual != expected {
-		return fmt.Errorf("SHA256 mismatch: expected %.16s..., got %.16s...", expected, actual)
+		return "", fmt.Errorf("compute SHA256: %w", err)
 	}
-	return nil
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 type progressReader struct {
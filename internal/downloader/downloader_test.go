@@ -43,6 +43,23 @@ func TestVerifySHA256_Mismatch(t *testing.T) {
 // validator is the single source of truth for URL policy.
 // See internal/formula/formula.go TestParse_HTTPURLRejected and TestGetURL_RejectsHTTP.
 
+func TestFileSHA256(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "testfile")
+	os.WriteFile(path, []byte("hello world"), 0644)
+
+	got, err := FileSHA256(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"; got != want {
+		t.Errorf("FileSHA256 = %s, want %s", got, want)
+	}
+	if _, err := FileSHA256(filepath.Join(tmpDir, "missing")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
 func TestDownload_TLS(t *testing.T) {
 	content := []byte("test binary content")
 	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,66 @@
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// RemoteCommit returns the commit ref points at in the git repository at
+// url, without cloning it. Ref is a full ref such as refs/heads/main, or
+// HEAD for the default branch.
+func RemoteCommit(url, ref string) (string, error) {
+	out, err := git("", "ls-remote", "--", url, ref)
+	if err != nil {
+		return "", fmt.Errorf("ls-remote %s: %w", url, err)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == ref {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("ls-remote %s: ref %s not found", url, ref)
+}
+
+// Clone makes a shallow clone of branch (the default branch when empty)
+// of the repository at url into dir, which must not exist yet, and
+// returns the commit it checked out.
+func Clone(url, branch, dir string) (string, error) {
+	args := []string{"clone", "--quiet", "--depth", "1", "--single-branch"}
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	args = append(args, "--", url, dir)
+	if _, err := git("", args...); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("clone %s: %w", url, err)
+	}
+	commit, err := git(dir, "rev-parse", "HEAD")
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("clone %s: %w", url, err)
+	}
+	return strings.TrimSpace(commit), nil
+}
+
+// git runs a git command in dir (the current directory when empty) and
+// returns its standard output. Prompts for credentials are disabled so a
+// private or missing repository fails instead of hanging.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
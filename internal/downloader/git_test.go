@@ -0,0 +1,88 @@
+package downloader
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newBareRepo creates a bare repository with one commit on branch main
+// and returns its file:// URL and the path of a work tree pushing to it.
+func newBareRepo(t *testing.T) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	bare := filepath.Join(dir, "repo.git")
+	work := filepath.Join(dir, "work")
+	run := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run(dir, "init", "--quiet", "--bare", "--initial-branch=main", bare)
+	run(dir, "init", "--quiet", "--initial-branch=main", work)
+	os.WriteFile(filepath.Join(work, "README"), []byte("one\n"), 0644)
+	run(work, "add", "README")
+	run(work, "commit", "--quiet", "-m", "one")
+	run(work, "push", "--quiet", bare, "main")
+	return "file://" + bare, work
+}
+
+func gitHead(t *testing.T, dir string) string {
+	t.Helper()
+	out, err := git(dir, "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(out)
+}
+
+func TestRemoteCommit(t *testing.T) {
+	url, work := newBareRepo(t)
+	want := gitHead(t, work)
+
+	got, err := RemoteCommit(url, "refs/heads/main")
+	if err != nil {
+		t.Fatalf("RemoteCommit: %v", err)
+	}
+	if got != want {
+		t.Errorf("RemoteCommit = %s, want %s", got, want)
+	}
+	if got, err := RemoteCommit(url, "HEAD"); err != nil || got != want {
+		t.Errorf("RemoteCommit(HEAD) = %s, %v; want %s", got, err, want)
+	}
+	if _, err := RemoteCommit(url, "refs/heads/nope"); err == nil {
+		t.Error("expected error for a missing branch")
+	}
+}
+
+func TestClone(t *testing.T) {
+	url, work := newBareRepo(t)
+	want := gitHead(t, work)
+
+	dest := filepath.Join(t.TempDir(), "src")
+	commit, err := Clone(url, "main", dest)
+	if err != nil {
+		t.Fatalf("Clone: %v", err)
+	}
+	if commit != want {
+		t.Errorf("commit = %s, want %s", commit, want)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "README")); err != nil || string(data) != "one\n" {
+		t.Errorf("README = %q, %v", data, err)
+	}
+
+	if _, err := Clone(url, "nope", filepath.Join(t.TempDir(), "src")); err == nil {
+		t.Error("expected error for a missing branch")
+	}
+}
@@ -0,0 +1,135 @@
+package formula
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// A tap can carry two optional JSON objects next to its formulas.
+// AliasesFile maps alternative names to formulas (e.g. "python" to
+// "python@3.12"); RenamesFile maps the old name of a renamed formula to
+// its new one. Neither ends in .yaml, so LoadFromTap skips them.
+const (
+	AliasesFile = "aliases.json"
+	RenamesFile = "renames.json"
+)
+
+// Aliases returns the alias map of every tap. When two taps define the
+// same alias, the first tap in directory order wins.
+func (l *Loader) Aliases() map[string]string {
+	return l.readTapMaps(AliasesFile)
+}
+
+// Renames returns the rename map of every tap, old name to new name.
+func (l *Loader) Renames() map[string]string {
+	return l.readTapMaps(RenamesFile)
+}
+
+// RenamedTo returns the current name of a formula that was renamed from
+// old, following chains of renames, or "" if old was not renamed.
+func (l *Loader) RenamedTo(old string) string {
+	renames := l.Renames()
+	name := old
+	for range len(renames) {
+		next, ok := renames[name]
+		if !ok || next == old {
+			break
+		}
+		name = next
+	}
+	if name == old {
+		return ""
+	}
+	return name
+}
+
+// ResolveName returns the formula name that name refers to: renames are
+// followed first, then aliases. Names that are neither are returned
+// unchanged.
+func (l *Loader) ResolveName(name string) string {
+	if renamed := l.RenamedTo(name); renamed != "" {
+		name = renamed
+	}
+	if target, ok := l.Aliases()[name]; ok {
+		return target
+	}
+	return name
+}
+
+// AliasesOf returns the aliases that point at name, sorted.
+func (l *Loader) AliasesOf(name string) []string {
+	var aliases []string
+	for alias, target := range l.Aliases() {
+		if target == name {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// CheckAliases reports problems with the alias and rename maps of one tap:
+// files that are not a JSON object of strings, and aliases or renames
+// whose target no formula in any tap answers to.
+func (l *Loader) CheckAliases(tapPath string) []error {
+	var problems []error
+	for _, file := range []string{AliasesFile, RenamesFile} {
+		path := filepath.Join(tapPath, file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				problems = append(problems, err)
+			}
+			continue
+		}
+		var m map[string]string
+		if err := json.Unmarshal(data, &m); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			target := m[name]
+			if file == RenamesFile {
+				target = l.RenamedTo(name)
+			}
+			if _, err := l.loadExact(target); err != nil {
+				problems = append(problems, fmt.Errorf("%s: %q points to %q, which is not a formula", path, name, target))
+			}
+		}
+	}
+	return problems
+}
+
+func (l *Loader) readTapMaps(file string) map[string]string {
+	merged := make(map[string]string)
+	dirs, err := l.tapDirs()
+	if err != nil {
+		return merged
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var m map[string]string
+		if err := json.Unmarshal(data, &m); err != nil {
+			l.debugf("failed to parse %s: %v\n", path, err)
+			continue
+		}
+		for k, v := range m {
+			if _, ok := merged[k]; !ok {
+				merged[k] = v
+			}
+		}
+	}
+	return merged
+}
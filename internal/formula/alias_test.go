@@ -0,0 +1,80 @@
+package formula
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTapMap(t *testing.T, dir, file, data string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadByName_AliasAndRename(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeTestFormula(t, tapDir, "python@3.12")
+	writeTestFormula(t, tapDir, "ripgrep")
+	writeTapMap(t, tapDir, AliasesFile, `{"python": "python@3.12", "python3": "python@3.12"}`)
+	writeTapMap(t, tapDir, RenamesFile, `{"rg": "rip", "rip": "ripgrep"}`)
+
+	loader := &Loader{TapDir: tmpDir}
+	for name, want := range map[string]string{
+		"python":      "python@3.12",
+		"python@3.12": "python@3.12",
+		"rg":          "ripgrep",
+	} {
+		f, err := loader.LoadByName(name)
+		if err != nil {
+			t.Errorf("LoadByName(%q): unexpected error: %v", name, err)
+			continue
+		}
+		if f.Name != want {
+			t.Errorf("LoadByName(%q).Name = %q, want %q", name, f.Name, want)
+		}
+	}
+
+	if got := loader.RenamedTo("rg"); got != "ripgrep" {
+		t.Errorf("RenamedTo(rg) = %q, want ripgrep", got)
+	}
+	if got := loader.RenamedTo("ripgrep"); got != "" {
+		t.Errorf("RenamedTo(ripgrep) = %q, want empty", got)
+	}
+	if got := loader.AliasesOf("python@3.12"); !reflect.DeepEqual(got, []string{"python", "python3"}) {
+		t.Errorf("AliasesOf = %v, want [python python3]", got)
+	}
+	if _, err := loader.LoadByName("nosuch"); err == nil {
+		t.Error("expected error for unknown name")
+	}
+}
+
+func TestRenamedTo_Cycle(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeTapMap(t, tapDir, RenamesFile, `{"a": "b", "b": "c", "c": "b"}`)
+
+	loader := &Loader{TapDir: tmpDir}
+	if got := loader.RenamedTo("a"); got == "" {
+		t.Error("RenamedTo should stop on a cycle and still report a rename")
+	}
+}
+
+func TestCheckAliases(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeTestFormula(t, tapDir, "ripgrep")
+	writeTapMap(t, tapDir, AliasesFile, `{"rg": "ripgrep", "ghost": "nosuch"}`)
+	writeTapMap(t, tapDir, RenamesFile, `not json`)
+
+	loader := &Loader{TapDir: tmpDir}
+	if problems := loader.CheckAliases(tapDir); len(problems) != 2 {
+		t.Errorf("CheckAliases reported %d problem(s), want 2: %v", len(problems), problems)
+	}
+}
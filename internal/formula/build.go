@@ -0,0 +1,97 @@
+package formula
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// buildSystems holds the built-in recipes selectable with build.system.
+// Steps are shell commands run with sh -c inside the build sandbox, after
+// placeholder expansion (see ExpandBuildStep). The sandbox has no network
+// access, so cargo and go builds expect vendored dependencies.
+var buildSystems = map[string]BuildSpec{
+	"autotools": {
+		Configure: []string{"./configure --prefix={prefix}"},
+		Install:   []string{"make -j{jobs}", "make install"},
+	},
+	"make": {
+		Install: []string{"make -j{jobs} PREFIX={prefix}", "make install PREFIX={prefix}"},
+	},
+	"cmake": {
+		Configure: []string{"cmake -S . -B build -DCMAKE_INSTALL_PREFIX={prefix} -DCMAKE_BUILD_TYPE=Release"},
+		Install:   []string{"cmake --build build -j {jobs}", "cmake --install build"},
+	},
+	"meson": {
+		Configure: []string{"meson setup build --prefix={prefix} --buildtype=release"},
+		Install:   []string{"meson compile -C build -j {jobs}", "meson install -C build"},
+	},
+	"cargo": {
+		Install: []string{"CARGO_HOME=\"$TMPDIR/cargo\" cargo install --offline --locked --path . --root {prefix} -j {jobs}"},
+	},
+	"go": {
+		Install: []string{
+			"mkdir -p {prefix}/bin",
+			"GOCACHE=\"$TMPDIR/gocache\" GOPATH=\"$TMPDIR/gopath\" GOFLAGS=-mod=vendor go build -trimpath -o {prefix}/bin/{name} .",
+		},
+	},
+}
+
+// DefaultBuildSystem is used when a formula declares no build steps.
+const DefaultBuildSystem = "autotools"
+
+// BuildSystemNames returns the names accepted by build.system, sorted.
+func BuildSystemNames() []string {
+	names := make([]string, 0, len(buildSystems))
+	for name := range buildSystems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the effective configure and install steps. Explicit
+// configure or install lists override the matching phase of the selected
+// build system; with neither, the autotools recipe is used.
+func (b BuildSpec) Resolve() (BuildSpec, error) {
+	system := b.System
+	if system == "" {
+		if len(b.Configure) > 0 || len(b.Install) > 0 {
+			return BuildSpec{Configure: b.Configure, Install: b.Install}, nil
+		}
+		system = DefaultBuildSystem
+	}
+	recipe, ok := buildSystems[system]
+	if !ok {
+		return BuildSpec{}, fmt.Errorf("unknown build system %q (available: %s)", system, strings.Join(BuildSystemNames(), ", "))
+	}
+	out := BuildSpec{Configure: recipe.Configure, Install: recipe.Install}
+	if len(b.Configure) > 0 {
+		out.Configure = b.Configure
+	}
+	if len(b.Install) > 0 {
+		out.Install = b.Install
+	}
+	return out, nil
+}
+
+// ExpandBuildStep substitutes {key} placeholders in a build step with the
+// matching value from vars, shell-quoting values that need it. Build steps
+// use {prefix} (the keg), {jobs}, {name} and {version}. Unknown
+// placeholders are left untouched.
+func ExpandBuildStep(step string, vars map[string]string) string {
+	pairs := make([]string, 0, 2*len(vars))
+	for k, v := range vars {
+		pairs = append(pairs, "{"+k+"}", shellQuote(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(step)
+}
+
+// shellQuote quotes s for sh if it contains anything beyond a safe set of
+// characters.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%+=:,./_-") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
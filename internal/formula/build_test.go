@@ -0,0 +1,97 @@
+package formula
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildResolve_DefaultsToAutotools(t *testing.T) {
+	got, err := BuildSpec{}.Resolve()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := buildSystems[DefaultBuildSystem]
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolve() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBuildResolve_ExplicitSteps(t *testing.T) {
+	b := BuildSpec{Install: []string{"make install PREFIX={prefix}"}}
+	got, err := b.Resolve()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Configure) != 0 {
+		t.Errorf("Configure = %v, want none", got.Configure)
+	}
+	if !reflect.DeepEqual(got.Install, b.Install) {
+		t.Errorf("Install = %v, want %v", got.Install, b.Install)
+	}
+}
+
+func TestBuildResolve_SystemWithOverride(t *testing.T) {
+	b := BuildSpec{
+		System:    "cmake",
+		Configure: []string{"cmake -S . -B build -DCMAKE_INSTALL_PREFIX={prefix} -DWITH_SSL=ON"},
+	}
+	got, err := b.Resolve()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got.Configure, b.Configure) {
+		t.Errorf("Configure = %v, want override %v", got.Configure, b.Configure)
+	}
+	if !reflect.DeepEqual(got.Install, buildSystems["cmake"].Install) {
+		t.Errorf("Install = %v, want cmake recipe", got.Install)
+	}
+}
+
+func TestBuildResolve_UnknownSystem(t *testing.T) {
+	_, err := BuildSpec{System: "scons"}.Resolve()
+	if err == nil {
+		t.Fatal("expected error for unknown build system")
+	}
+	if !strings.Contains(err.Error(), "cmake") {
+		t.Errorf("error should list available systems: %v", err)
+	}
+}
+
+func TestExpandBuildStep(t *testing.T) {
+	vars := map[string]string{
+		"prefix": "/opt/grew/Cellar/foo/1.0",
+		"jobs":   "8",
+		"name":   "foo",
+	}
+	got := ExpandBuildStep("./configure --prefix={prefix} && make -j{jobs} {unknown}", vars)
+	want := "./configure --prefix=/opt/grew/Cellar/foo/1.0 && make -j8 {unknown}"
+	if got != want {
+		t.Errorf("ExpandBuildStep = %q, want %q", got, want)
+	}
+}
+
+func TestExpandBuildStep_QuotesUnsafeValues(t *testing.T) {
+	got := ExpandBuildStep("cp out {prefix}/bin", map[string]string{"prefix": "/home/a b/it's"})
+	want := `cp out '/home/a b/it'\''s'/bin`
+	if got != want {
+		t.Errorf("ExpandBuildStep = %q, want %q", got, want)
+	}
+}
+
+func TestParse_BuildSystem(t *testing.T) {
+	yaml := strings.Replace(validYAML, "install:\n  type: binary\n  binary_name: testpkg\n",
+		"install:\n  type: binary\n  binary_name: testpkg\nbuild:\n  system: meson\n", 1)
+	f, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.Build.System != "meson" {
+		t.Errorf("Build.System = %q, want meson", f.Build.System)
+	}
+
+	bad := strings.Replace(yaml, "system: meson", "system: bazel", 1)
+	if _, err := Parse([]byte(bad)); err == nil {
+		t.Error("expected error for unknown build system")
+	}
+}
@@ -0,0 +1,195 @@
+package formula
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Artifact is one download of a formula whose url, sha256 and signature
+// change together when the formula is bumped to a new version. Field is
+// the top-level key it lives under: "url" (with the sibling sha256 and
+// signature maps), "bottle", "source" or the legacy "source_url".
+// Platform is the platform key, empty for source downloads.
+type Artifact struct {
+	Field     string
+	Platform  string
+	URL       string
+	SHA256    string
+	Signature string
+}
+
+// Label names the artifact for messages, e.g. "bottle[linux_amd64]".
+func (a Artifact) Label() string {
+	if a.Platform == "" {
+		return a.Field
+	}
+	return a.Field + "[" + a.Platform + "]"
+}
+
+// Artifacts returns the formula's downloads: platform urls and bottles in
+// platform order, then the source.
+func (f *Formula) Artifacts() []Artifact {
+	var arts []Artifact
+	for _, p := range sortedKeys(f.URL) {
+		arts = append(arts, Artifact{Field: "url", Platform: p, URL: f.URL[p], SHA256: f.SHA256[p], Signature: f.Signature[p]})
+	}
+	for _, p := range sortedKeys(f.Bottle) {
+		b := f.Bottle[p]
+		arts = append(arts, Artifact{Field: "bottle", Platform: p, URL: b.URL, SHA256: b.SHA256, Signature: b.Signature})
+	}
+	if f.Source.URL != "" {
+		arts = append(arts, Artifact{Field: "source", URL: f.Source.URL, SHA256: f.Source.SHA256, Signature: f.Source.Signature})
+	}
+	if f.SourceURL != "" {
+		arts = append(arts, Artifact{Field: "source_url", URL: f.SourceURL, SHA256: f.SourceSHA256})
+	}
+	return arts
+}
+
+// Bump rewrites the formula file data for a new version and artifacts,
+// editing the YAML node tree so comments and key order survive. Each
+// artifact's url and sha256 are replaced; its signature is set, or
+// removed when empty since the old one no longer matches. A new version
+// starts over at revision 0 and bottle rebuild 0. The result is parsed
+// again to make sure it is still a valid formula.
+func Bump(data []byte, version string, arts []Artifact) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("formula is not a YAML mapping")
+	}
+	root := doc.Content[0]
+	setScalar(root, "version", version)
+	deleteKey(root, "revision")
+	if bottles := mappingValue(root, "bottle"); bottles != nil {
+		for i := 1; i < len(bottles.Content); i += 2 {
+			deleteKey(bottles.Content[i], "rebuild")
+		}
+	}
+
+	for _, a := range arts {
+		switch a.Field {
+		case "url":
+			for _, kv := range [][2]string{{"url", a.URL}, {"sha256", a.SHA256}, {"signature", a.Signature}} {
+				key, value := kv[0], kv[1]
+				m := mappingValue(root, key)
+				if m == nil {
+					if value == "" {
+						continue
+					}
+					m = &yaml.Node{Kind: yaml.MappingNode}
+					appendKey(root, key, m)
+				}
+				setOrDelete(m, a.Platform, value)
+				if len(m.Content) == 0 {
+					deleteKey(root, key)
+				}
+			}
+		case "bottle":
+			bottles := mappingValue(root, "bottle")
+			if bottles == nil {
+				return nil, fmt.Errorf("formula has no bottle block")
+			}
+			b := mappingValue(bottles, a.Platform)
+			if b == nil {
+				return nil, fmt.Errorf("formula has no bottle for %s", a.Platform)
+			}
+			setScalar(b, "url", a.URL)
+			setScalar(b, "sha256", a.SHA256)
+			setOrDelete(b, "signature", a.Signature)
+		case "source":
+			src := mappingValue(root, "source")
+			if src == nil {
+				return nil, fmt.Errorf("formula has no source block")
+			}
+			setScalar(src, "url", a.URL)
+			setScalar(src, "sha256", a.SHA256)
+			setOrDelete(src, "signature", a.Signature)
+		case "source_url":
+			setScalar(root, "source_url", a.URL)
+			setScalar(root, "source_sha256", a.SHA256)
+		default:
+			return nil, fmt.Errorf("unknown artifact field %q", a.Field)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	if _, err := Parse(buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("bumped formula is invalid: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ExpandURLTemplate substitutes {name}, {version} and {platform} in a URL
+// template.
+func ExpandURLTemplate(tmpl, name, version, platform string) string {
+	return strings.NewReplacer("{name}", name, "{version}", version, "{platform}", platform).Replace(tmpl)
+}
+
+// mappingValue returns the value node of key in mapping m, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setScalar sets key in mapping m to the string value, keeping the
+// existing node's style and comments, or appends the key.
+func setScalar(m *yaml.Node, key, value string) {
+	if n := mappingValue(m, key); n != nil && n.Kind == yaml.ScalarNode {
+		n.Value = value
+		// Force a string tag so "1.10" is quoted rather than re-read as
+		// a float.
+		n.Tag = "!!str"
+		return
+	}
+	deleteKey(m, key)
+	appendKey(m, key, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+}
+
+func setOrDelete(m *yaml.Node, key, value string) {
+	if value == "" {
+		deleteKey(m, key)
+		return
+	}
+	setScalar(m, key, value)
+}
+
+func appendKey(m *yaml.Node, key string, value *yaml.Node) {
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+func deleteKey(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
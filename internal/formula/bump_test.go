@@ -0,0 +1,125 @@
+package formula
+
+import (
+	"strings"
+	"testing"
+)
+
+const bumpSHA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+func TestBump_PreservesCommentsAndOrder(t *testing.T) {
+	data := []byte(`# Formula for foo.
+name: foo
+version: "1.0"  # bumped by hand
+revision: 2
+description: Foo tool
+bottle:
+  linux_amd64:
+    url: https://example.com/foo-1.0-linux.tar.gz
+    sha256: ` + validSHA + `
+    signature: oldsig
+    rebuild: 1
+source:
+  url: https://example.com/foo-1.0.tar.gz
+  sha256: ` + validSHA + `
+install:
+  type: archive
+`)
+	f, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arts := f.Artifacts()
+	if len(arts) != 2 || arts[0].Label() != "bottle[linux_amd64]" || arts[1].Label() != "source" {
+		t.Fatalf("Artifacts = %+v", arts)
+	}
+	for i := range arts {
+		arts[i].URL = strings.ReplaceAll(arts[i].URL, "1.0", "1.10")
+		arts[i].SHA256 = bumpSHA
+		arts[i].Signature = ""
+	}
+	arts[1].Signature = "newsig"
+
+	out, err := Bump(data, "1.10", arts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := string(out)
+	for _, want := range []string{
+		"# Formula for foo.\nname: foo\nversion: \"1.10\" # bumped by hand\ndescription: Foo tool\n",
+		"url: https://example.com/foo-1.10-linux.tar.gz",
+		"signature: newsig",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "oldsig") {
+		t.Errorf("stale signature kept:\n%s", text)
+	}
+	if strings.Contains(text, "revision:") || strings.Contains(text, "rebuild:") {
+		t.Errorf("revision and bottle rebuild should be reset:\n%s", text)
+	}
+
+	g, err := Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Version != "1.10" || g.Bottle["linux_amd64"].SHA256 != bumpSHA || g.Source.SHA256 != bumpSHA || g.Source.Signature != "newsig" {
+		t.Errorf("unexpected bumped formula: %+v", g)
+	}
+}
+
+func TestBump_LegacyMaps(t *testing.T) {
+	data := []byte(`name: foo
+version: "1.0"
+url:
+  linux_amd64: https://example.com/foo-1.0
+sha256:
+  linux_amd64: ` + validSHA + `
+signature:
+  linux_amd64: oldsig
+install:
+  type: binary
+`)
+	f, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arts := f.Artifacts()
+	arts[0].URL = "https://example.com/foo-2.0"
+	arts[0].SHA256 = bumpSHA
+	arts[0].Signature = ""
+
+	out, err := Bump(data, "2.0", arts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(out), "signature") {
+		t.Errorf("empty signature map should be removed:\n%s", out)
+	}
+	g, err := Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.URL["linux_amd64"] != "https://example.com/foo-2.0" || g.SHA256["linux_amd64"] != bumpSHA {
+		t.Errorf("unexpected bumped formula: %+v", g)
+	}
+}
+
+func TestBump_Invalid(t *testing.T) {
+	data := []byte("name: foo\nversion: \"1.0\"\nsource:\n  url: https://example.com/foo-1.0.tar.gz\n  sha256: " + validSHA + "\nbuild:\n  system: make\n")
+	if _, err := Bump(data, "2.0", []Artifact{{Field: "bottle", Platform: "linux_amd64"}}); err == nil {
+		t.Error("expected error for missing bottle block")
+	}
+	if _, err := Bump(data, "bad version!", nil); err == nil {
+		t.Error("expected error for invalid version")
+	}
+}
+
+func TestExpandURLTemplate(t *testing.T) {
+	got := ExpandURLTemplate("https://example.com/{name}/v{version}/{name}-{platform}.tar.gz", "foo", "2.0", "linux_amd64")
+	if got != "https://example.com/foo/v2.0/foo-linux_amd64.tar.gz" {
+		t.Errorf("ExpandURLTemplate = %q", got)
+	}
+}
@@ -0,0 +1,181 @@
+package formula
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/homegrew/grew/internal/validation"
+)
+
+// buildSystemMarkers maps files found at the top of a source tree to the
+// build system they indicate, in order of preference: a generated
+// configure script beats the CMakeLists.txt some projects ship alongside.
+var buildSystemMarkers = []struct {
+	file   string
+	system string
+}{
+	{"configure", "autotools"},
+	{"CMakeLists.txt", "cmake"},
+	{"meson.build", "meson"},
+	{"Cargo.toml", "cargo"},
+	{"go.mod", "go"},
+	{"Makefile", "make"},
+}
+
+// buildSystemTools lists the formulas that provide each build system's
+// tools, suggested as build dependencies by grew create.
+var buildSystemTools = map[string][]string{
+	"cmake": {"cmake"},
+	"meson": {"meson", "ninja"},
+	"cargo": {"rust"},
+	"go":    {"go"},
+}
+
+// archiveSuffixes are stripped from a download's file name before the
+// name and version are read from it.
+var archiveSuffixes = []string{".tar.gz", ".tgz", ".tar.xz", ".txz", ".tar.bz2", ".tbz2", ".tar", ".zip"}
+
+// nameVersionRe splits a file stem like "foo-bar-1.2.3" or "foo_v2.0" at
+// the first separator followed by a digit.
+var nameVersionRe = regexp.MustCompile(`^(.+?)[-_.]v?(\d[a-zA-Z0-9._+~-]*)$`)
+
+// DetectBuildSystem returns the build system indicated by the files at the
+// top of the source tree in dir, or "" if none is recognised.
+func DetectBuildSystem(dir string) string {
+	for _, m := range buildSystemMarkers {
+		if info, err := os.Stat(filepath.Join(dir, m.file)); err == nil && !info.IsDir() {
+			return m.system
+		}
+	}
+	return ""
+}
+
+// BuildSystemTools returns the formulas that provide the tools of a build
+// system, or nil if it only needs what a base system has (sh and make).
+func BuildSystemTools(system string) []string {
+	return buildSystemTools[system]
+}
+
+// InferNameVersion guesses a formula name and version from a source URL.
+// GitHub archive and release URLs take the name from the repository;
+// other URLs use the file name, e.g. "foo-1.2.3.tar.gz".
+func InferNameVersion(rawURL string) (name, version string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	stem := trimArchiveSuffix(path.Base(u.Path))
+
+	if u.Host == "github.com" {
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) >= 4 {
+			repo := parts[1]
+			tag := ""
+			switch {
+			case parts[2] == "archive":
+				tag = stem
+			case parts[2] == "releases" && parts[3] == "download" && len(parts) >= 6:
+				tag = parts[4]
+			}
+			if tag != "" {
+				tag = strings.TrimPrefix(tag, repo+"-")
+				tag = strings.TrimPrefix(strings.TrimPrefix(tag, "v"), "V")
+				return normalizeName(repo), tag, checkInferred(rawURL, normalizeName(repo), tag)
+			}
+		}
+	}
+
+	for _, suffix := range []string{"-src", "-source", ".src", ".orig"} {
+		stem = strings.TrimSuffix(stem, suffix)
+	}
+	m := nameVersionRe.FindStringSubmatch(stem)
+	if m == nil {
+		return "", "", fmt.Errorf("cannot infer name and version from %q", rawURL)
+	}
+	name, version = normalizeName(m[1]), m[2]
+	return name, version, checkInferred(rawURL, name, version)
+}
+
+// InferHomepage guesses a homepage from a source URL: the repository page
+// for GitHub URLs, the site root otherwise.
+func InferHomepage(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	if u.Host == "github.com" {
+		if parts := strings.Split(strings.Trim(u.Path, "/"), "/"); len(parts) >= 2 {
+			return "https://github.com/" + parts[0] + "/" + parts[1]
+		}
+	}
+	return "https://" + u.Host
+}
+
+func trimArchiveSuffix(base string) string {
+	lower := strings.ToLower(base)
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return base[:len(base)-len(suffix)]
+		}
+	}
+	return base
+}
+
+func normalizeName(s string) string {
+	return strings.ReplaceAll(strings.ToLower(s), "_", "-")
+}
+
+func checkInferred(rawURL, name, version string) error {
+	if !validation.IsValidName(name) || !validation.IsValidVersion(version) {
+		return fmt.Errorf("cannot infer name and version from %q (got %q and %q)", rawURL, name, version)
+	}
+	return nil
+}
+
+// Draft is a new formula that builds from source, as written by grew
+// create. Empty optional fields are left out of the YAML.
+type Draft struct {
+	Name              string
+	Version           string
+	Description       string
+	Homepage          string
+	License           string
+	SourceURL         string
+	SourceSHA256      string
+	BuildSystem       string
+	BuildDependencies []string
+}
+
+// YAML renders the draft as a formula file.
+func (d *Draft) YAML() []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "name: %s\n", d.Name)
+	fmt.Fprintf(&b, "version: %s\n", strconv.Quote(d.Version))
+	if d.Description != "" {
+		fmt.Fprintf(&b, "description: %s\n", strconv.Quote(d.Description))
+	}
+	if d.Homepage != "" {
+		fmt.Fprintf(&b, "homepage: %s\n", strconv.Quote(d.Homepage))
+	}
+	if d.License != "" {
+		fmt.Fprintf(&b, "license: %s\n", strconv.Quote(d.License))
+	}
+	b.WriteString("source:\n")
+	fmt.Fprintf(&b, "  url: %s\n", strconv.Quote(d.SourceURL))
+	fmt.Fprintf(&b, "  sha256: %s\n", d.SourceSHA256)
+	b.WriteString("build:\n")
+	fmt.Fprintf(&b, "  system: %s\n", d.BuildSystem)
+	if len(d.BuildDependencies) > 0 {
+		b.WriteString("build_dependencies:\n")
+		for _, dep := range d.BuildDependencies {
+			fmt.Fprintf(&b, "  - %s\n", dep)
+		}
+	}
+	return []byte(b.String())
+}
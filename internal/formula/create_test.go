@@ -0,0 +1,104 @@
+package formula
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInferNameVersion(t *testing.T) {
+	tests := []struct {
+		url, name, version string
+	}{
+		{"https://example.com/dist/hello-2.12.1.tar.gz", "hello", "2.12.1"},
+		{"https://example.com/pkg/foo-bar-1.0.tar.xz", "foo-bar", "1.0"},
+		{"https://example.com/Python-3.12.1.tgz", "python", "3.12.1"},
+		{"https://example.com/lib_thing_v0.4.2-src.zip", "lib-thing", "0.4.2"},
+		{"https://github.com/BurntSushi/ripgrep/archive/refs/tags/14.1.0.tar.gz", "ripgrep", "14.1.0"},
+		{"https://github.com/jqlang/jq/archive/v1.7.1.tar.gz", "jq", "1.7.1"},
+		{"https://github.com/jqlang/jq/archive/refs/tags/jq-1.7.1.tar.gz", "jq", "1.7.1"},
+		{"https://github.com/owner/tool/releases/download/v2.0.0/tool-src.tar.gz", "tool", "2.0.0"},
+	}
+	for _, tt := range tests {
+		name, version, err := InferNameVersion(tt.url)
+		if err != nil {
+			t.Errorf("InferNameVersion(%q): unexpected error: %v", tt.url, err)
+			continue
+		}
+		if name != tt.name || version != tt.version {
+			t.Errorf("InferNameVersion(%q) = %q, %q; want %q, %q", tt.url, name, version, tt.name, tt.version)
+		}
+	}
+
+	for _, bad := range []string{"https://example.com/download", "https://example.com/latest.tar.gz"} {
+		if _, _, err := InferNameVersion(bad); err == nil {
+			t.Errorf("InferNameVersion(%q): expected error", bad)
+		}
+	}
+}
+
+func TestInferHomepage(t *testing.T) {
+	if got := InferHomepage("https://github.com/jqlang/jq/archive/v1.7.1.tar.gz"); got != "https://github.com/jqlang/jq" {
+		t.Errorf("GitHub homepage = %q", got)
+	}
+	if got := InferHomepage("https://ftp.gnu.org/gnu/hello/hello-2.12.tar.gz"); got != "https://ftp.gnu.org" {
+		t.Errorf("homepage = %q", got)
+	}
+}
+
+func TestDetectBuildSystem(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{[]string{"configure", "CMakeLists.txt", "Makefile.in"}, "autotools"},
+		{[]string{"CMakeLists.txt", "README"}, "cmake"},
+		{[]string{"Cargo.toml", "Cargo.lock"}, "cargo"},
+		{[]string{"go.mod", "main.go"}, "go"},
+		{[]string{"meson.build"}, "meson"},
+		{[]string{"Makefile"}, "make"},
+		{[]string{"README"}, ""},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		for _, f := range tt.files {
+			os.WriteFile(filepath.Join(dir, f), nil, 0644)
+		}
+		if got := DetectBuildSystem(dir); got != tt.want {
+			t.Errorf("DetectBuildSystem(%v) = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+
+	// A directory named like a marker does not count.
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "configure"), 0755)
+	if got := DetectBuildSystem(dir); got != "" {
+		t.Errorf("DetectBuildSystem with configure/ dir = %q, want empty", got)
+	}
+}
+
+func TestDraft_Parses(t *testing.T) {
+	d := &Draft{
+		Name:              "hello",
+		Version:           "2.12.1",
+		Description:       `Prints "hello"`,
+		Homepage:          "https://example.com",
+		SourceURL:         "https://example.com/hello-2.12.1.tar.gz?download=1",
+		SourceSHA256:      validSHA,
+		BuildSystem:       "cmake",
+		BuildDependencies: BuildSystemTools("cmake"),
+	}
+	f, err := Parse(d.YAML())
+	if err != nil {
+		t.Fatalf("draft does not parse: %v\n%s", err, d.YAML())
+	}
+	if f.Name != "hello" || f.Version != "2.12.1" || f.Description != `Prints "hello"` {
+		t.Errorf("unexpected metadata: %+v", f)
+	}
+	if f.Source.URL != d.SourceURL || f.Source.SHA256 != validSHA {
+		t.Errorf("source = %+v", f.Source)
+	}
+	if f.Build.System != "cmake" || len(f.BuildDependencies) != 1 || f.BuildDependencies[0] != "cmake" {
+		t.Errorf("build = %+v, build_dependencies = %v", f.Build, f.BuildDependencies)
+	}
+}
@@ -0,0 +1,111 @@
+package formula
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/homegrew/grew/internal/validation"
+	"github.com/homegrew/grew/internal/vercmp"
+)
+
+// Dependency is one parsed entry of a dependency list. Entries are written
+// as a formula name optionally followed by a version constraint:
+//
+//	dependencies:
+//	  - pcre2
+//	  - openssl@3 >= 3.1
+//	  - zlib ~> 1.3
+type Dependency struct {
+	Name       string
+	Constraint vercmp.Constraint
+}
+
+// String returns the dependency in its YAML form.
+func (d Dependency) String() string {
+	if d.Constraint.IsEmpty() {
+		return d.Name
+	}
+	return d.Name + " " + d.Constraint.String()
+}
+
+// ParseDependency parses a dependency entry such as "zlib ~> 1.3".
+func ParseDependency(s string) (Dependency, error) {
+	s = strings.TrimSpace(s)
+	end := strings.IndexAny(s, " \t<>=!~")
+	name, rest := s, ""
+	if end >= 0 {
+		name, rest = s[:end], s[end:]
+	}
+	if !validation.IsValidName(name) {
+		return Dependency{}, fmt.Errorf("dependency %q contains invalid characters", name)
+	}
+	c, err := vercmp.ParseConstraint(rest)
+	if err != nil {
+		return Dependency{}, fmt.Errorf("dependency %q: %w", name, err)
+	}
+	return Dependency{Name: name, Constraint: c}, nil
+}
+
+// parseDependencies parses a list of dependency entries, stopping at the
+// first invalid one.
+func parseDependencies(entries []string) ([]Dependency, error) {
+	deps := make([]Dependency, 0, len(entries))
+	for _, e := range entries {
+		d, err := ParseDependency(e)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, d)
+	}
+	return deps, nil
+}
+
+// Deps returns the parsed runtime dependencies for the platform the
+// formula was evaluated for.
+func (f *Formula) Deps() []Dependency {
+	return f.DepsFor(f.Platform().OS)
+}
+
+// DepsFor returns the parsed runtime dependencies on goos: dependencies,
+// plus linux_dependencies when goos is "linux", plus the optional and
+// recommended dependencies whose options are on. Entries that fail to
+// parse are skipped; Validate rejects such formulas, so this only matters
+// for hand-built Formula values.
+func (f *Formula) DepsFor(goos string) []Dependency {
+	deps := validDeps(f.Dependencies)
+	if goos == "linux" {
+		deps = append(deps, validDeps(f.LinuxDependencies)...)
+	}
+	return append(deps, f.optionDeps()...)
+}
+
+// BuildDeps returns the parsed build_dependencies. They are only needed
+// when building from source and are never runtime dependencies.
+func (f *Formula) BuildDeps() []Dependency {
+	return validDeps(f.BuildDependencies)
+}
+
+// DependencyNames returns the runtime dependency names for the platform
+// the formula was evaluated for, without constraints.
+func (f *Formula) DependencyNames() []string {
+	return DepNames(f.Deps())
+}
+
+func validDeps(entries []string) []Dependency {
+	var deps []Dependency
+	for _, e := range entries {
+		if d, err := ParseDependency(e); err == nil {
+			deps = append(deps, d)
+		}
+	}
+	return deps
+}
+
+// DepNames returns the names of deps, without constraints.
+func DepNames(deps []Dependency) []string {
+	names := make([]string, len(deps))
+	for i, d := range deps {
+		names[i] = d.Name
+	}
+	return names
+}
@@ -0,0 +1,13 @@
+package formula
+
+import "time"
+
+// IsDeprecated reports whether f is deprecated now (and not disabled).
+func (f *Formula) IsDeprecated() bool {
+	return f.Deprecated.Active(time.Now()) && !f.IsDisabled()
+}
+
+// IsDisabled reports whether f is disabled now.
+func (f *Formula) IsDisabled() bool {
+	return f.Disabled.Active(time.Now())
+}
@@ -0,0 +1,41 @@
+package formula
+
+import "testing"
+
+func TestParse_Deprecation(t *testing.T) {
+	yml := validYAML + `caveats: |
+  Add testpkg to your shell config.
+deprecated:
+  date: "2020-01-01"
+  reason: "is unmaintained"
+  replacement: newpkg
+`
+	f, err := Parse([]byte(yml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !f.IsDeprecated() {
+		t.Error("expected formula to be deprecated")
+	}
+	if f.IsDisabled() {
+		t.Error("formula should not be disabled")
+	}
+	if f.Caveats == "" {
+		t.Error("expected caveats to be parsed")
+	}
+
+	disabled, err := Parse([]byte(yml + "disabled:\n  reason: \"no longer builds\"\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !disabled.IsDisabled() || disabled.IsDeprecated() {
+		t.Error("a disabled formula should report disabled, not deprecated")
+	}
+
+	if _, err := Parse([]byte(validYAML + "deprecated:\n  date: \"June 2025\"\n")); err == nil {
+		t.Error("expected error for malformed date")
+	}
+	if _, err := Parse([]byte(validYAML + "disabled:\n  replacement: \"../x\"\n")); err == nil {
+		t.Error("expected error for invalid replacement")
+	}
+}
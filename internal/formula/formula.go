@@ -2,12 +2,15 @@ package formula
 
 import (
 	"fmt"
-	"runtime"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/homegrew/grew/internal/deprecation"
+	"github.com/homegrew/grew/internal/schema"
 	"github.com/homegrew/grew/internal/validation"
-	"gopkg.in/yaml.v3"
+	"github.com/homegrew/grew/internal/vercmp"
 )
 
 type SourceSpec struct {
@@ -16,39 +19,85 @@ type SourceSpec struct {
 	Signature string `yaml:"signature"`
 }
 
+// BottleSpec is the bottle for one platform. Rebuild counts bottles
+// rebuilt for the same formula version and revision. Files optionally
+// lists the keg paths the bottle installs (e.g. bin/jq,
+// share/man/man1/jq.1), so link conflicts can be found before download.
 type BottleSpec struct {
-	URL       string `yaml:"url"`
-	SHA256    string `yaml:"sha256"`
-	Signature string `yaml:"signature"`
+	URL       string   `yaml:"url"`
+	SHA256    string   `yaml:"sha256"`
+	Signature string   `yaml:"signature"`
+	Rebuild   int      `yaml:"rebuild"`
+	Files     []string `yaml:"files"`
 }
 
+// BuildSpec describes a source build. System selects a built-in recipe
+// (see BuildSystemNames); Configure and Install are shell commands that
+// override the recipe's phases. Steps may use {prefix}, {jobs}, {name}
+// and {version} placeholders, and {with-<option>} for each build option.
 type BuildSpec struct {
+	System    string   `yaml:"system"`
 	Configure []string `yaml:"configure"`
 	Install   []string `yaml:"install"`
 }
 
 type Formula struct {
-	Name         string            `yaml:"name"`
-	Version      string            `yaml:"version"`
-	Description  string            `yaml:"description"`
-	Homepage     string            `yaml:"homepage"`
-	License      string            `yaml:"license"`
-	URL          map[string]string `yaml:"url"`
-	SHA256       map[string]string `yaml:"sha256"`
-	Signature    map[string]string `yaml:"signature"`
-	SourceURL    string            `yaml:"source_url"`
-	SourceSHA256 string            `yaml:"source_sha256"`
-	Install      InstallSpec       `yaml:"install"`
-	PostInstall  string            `yaml:"post_install"`
-	Dependencies []string          `yaml:"dependencies"`
-	KegOnly      bool              `yaml:"keg_only"`
+	Name          string            `yaml:"name"`
+	Version       string            `yaml:"version"`
+	VersionScheme int               `yaml:"version_scheme"`
+	Revision      int               `yaml:"revision"`
+	Description   string            `yaml:"description"`
+	Homepage      string            `yaml:"homepage"`
+	License       string            `yaml:"license"`
+	URL           map[string]string `yaml:"url"`
+	SHA256        map[string]string `yaml:"sha256"`
+	Signature     map[string]string `yaml:"signature"`
+	SourceURL     string            `yaml:"source_url"`
+	SourceSHA256  string            `yaml:"source_sha256"`
+	Install       InstallSpec       `yaml:"install"`
+	PostInstall   string            `yaml:"post_install"`
+	Dependencies  []string          `yaml:"dependencies"`
+	KegOnly       bool              `yaml:"keg_only"`
 	// New schema fields
 	Bottle            map[string]BottleSpec `yaml:"bottle"`
 	Source            SourceSpec            `yaml:"source"`
 	BuildDependencies []string              `yaml:"build_dependencies"`
 	LinuxDependencies []string              `yaml:"linux_dependencies"`
 	Build             BuildSpec             `yaml:"build"`
+	Patches           []PatchSpec           `yaml:"patches"`
+	Resources         []ResourceSpec        `yaml:"resources"`
 	Service           *ServiceSpec          `yaml:"service"`
+	Test              []TestStep            `yaml:"test"`
+	Caveats           string                `yaml:"caveats"`
+	Deprecated        *deprecation.Notice   `yaml:"deprecated"`
+	Disabled          *deprecation.Notice   `yaml:"disabled"`
+	Conflicts         []string              `yaml:"conflicts_with"`
+	Provides          []string              `yaml:"provides"`
+	Replaces          []string              `yaml:"replaces"`
+	OnMacOS           *PlatformBlock        `yaml:"on_macos"`
+	OnLinux           *PlatformBlock        `yaml:"on_linux"`
+	OnARM             *PlatformBlock        `yaml:"on_arm"`
+	OnIntel           *PlatformBlock        `yaml:"on_intel"`
+	Livecheck         *LivecheckSpec        `yaml:"livecheck"`
+	Head              *HeadSpec             `yaml:"head"`
+	Options           []OptionSpec          `yaml:"options"`
+	Requirements      []Requirement         `yaml:"requirements"`
+
+	// Optional and recommended dependencies are switched by build
+	// options of their name; see OptionSpec.
+	OptionalDependencies    []string `yaml:"optional_dependencies"`
+	RecommendedDependencies []string `yaml:"recommended_dependencies"`
+
+	// Path is the file the formula was loaded from; empty when parsed
+	// from bytes.
+	Path string `yaml:"-"`
+
+	// platform is the platform the conditional blocks were applied for,
+	// and generic the formula as written, before they were applied.
+	platform Platform
+	generic  *Formula
+	// choices are the build options set explicitly, see WithOptions.
+	choices map[string]bool
 }
 
 type ServiceSpec struct {
@@ -60,6 +109,15 @@ type ServiceSpec struct {
 	KeepAlive    bool     `yaml:"keep_alive"`
 }
 
+// TestStep is one command of a formula's test block, run by grew test with
+// sh -c. It passes when it exits with ExpectExit and its combined output
+// contains ExpectOutput (when set).
+type TestStep struct {
+	Run          string `yaml:"run"`
+	ExpectOutput string `yaml:"expect_output"`
+	ExpectExit   int    `yaml:"expect_exit"`
+}
+
 type InstallSpec struct {
 	Type            string `yaml:"type"` // "binary" or "archive"
 	BinaryName      string `yaml:"binary_name"`
@@ -68,11 +126,11 @@ type InstallSpec struct {
 }
 
 func PlatformKey() string {
-	return runtime.GOOS + "_" + runtime.GOARCH
+	return HostPlatform().Key()
 }
 
 func (f *Formula) GetURL() (string, error) {
-	key := PlatformKey()
+	key := f.Platform().Key()
 	// New format support
 	if len(f.Bottle) > 0 {
 		if b, ok := f.Bottle[key]; ok {
@@ -129,7 +187,7 @@ func (f *Formula) GetSourceSHA256() (string, error) {
 }
 
 func (f *Formula) GetSHA256() (string, error) {
-	key := PlatformKey()
+	key := f.Platform().Key()
 	// New format support
 	if len(f.Bottle) > 0 {
 		if b, ok := f.Bottle[key]; ok {
@@ -154,7 +212,7 @@ func (f *Formula) GetSHA256() (string, error) {
 // if none is set. New-format bottles store it on BottleSpec; legacy formulas
 // use the top-level Signature map.
 func (f *Formula) GetSignature() string {
-	key := PlatformKey()
+	key := f.Platform().Key()
 	if len(f.Bottle) > 0 {
 		if b, ok := f.Bottle[key]; ok {
 			return b.Signature
@@ -168,6 +226,39 @@ func (f *Formula) GetSourceSignature() string {
 	return f.Source.Signature
 }
 
+// ParsedVersion returns the formula version for ordering, including its
+// version_scheme. Bump version_scheme when upstream switches to a scheme
+// that would otherwise sort lower (e.g. dates to semver).
+func (f *Formula) ParsedVersion() vercmp.Version {
+	return vercmp.Parse(f.Version).WithScheme(f.VersionScheme).WithRevision(f.Revision)
+}
+
+// PkgVersion returns the version kegs of the formula are stored under:
+// the version, followed by "_<revision>" once the revision is bumped.
+func (f *Formula) PkgVersion() string {
+	return PkgVersion(f.Version, f.Revision)
+}
+
+// PkgVersion joins a version and formula revision into a keg version.
+func PkgVersion(version string, revision int) string {
+	if revision <= 0 {
+		return version
+	}
+	return version + "_" + strconv.Itoa(revision)
+}
+
+// BottleRebuild returns the rebuild counter of the bottle for the
+// formula's platform, 0 without one.
+func (f *Formula) BottleRebuild() int {
+	return f.Bottle[f.Platform().Key()].Rebuild
+}
+
+// BottleFiles returns the keg paths the bottle for the formula's platform
+// lists, or nil when it lists none.
+func (f *Formula) BottleFiles() []string {
+	return f.Bottle[f.Platform().Key()].Files
+}
+
 func (f *Formula) Validate() error {
 	if f.Name == "" {
 		return fmt.Errorf("formula missing required field: name")
@@ -181,8 +272,14 @@ func (f *Formula) Validate() error {
 	if !validation.IsValidVersion(f.Version) {
 		return fmt.Errorf("formula %q: version %q contains invalid characters", f.Name, f.Version)
 	}
-	if len(f.URL) == 0 && len(f.Bottle) == 0 && f.Source.URL == "" {
-		return fmt.Errorf("formula %q missing required field: url, bottle, or source", f.Name)
+	if f.VersionScheme < 0 {
+		return fmt.Errorf("formula %q: version_scheme must not be negative", f.Name)
+	}
+	if f.Revision < 0 {
+		return fmt.Errorf("formula %q: revision must not be negative", f.Name)
+	}
+	if len(f.URL) == 0 && len(f.Bottle) == 0 && f.Source.URL == "" && f.Head == nil {
+		return fmt.Errorf("formula %q missing required field: url, bottle, source, or head", f.Name)
 	}
 	for platform, u := range f.URL {
 		if !strings.HasPrefix(u, "https://") {
@@ -193,9 +290,23 @@ func (f *Formula) Validate() error {
 		if !strings.HasPrefix(b.URL, "https://") {
 			return fmt.Errorf("formula %q: bottle URL for %s must use HTTPS: %s", f.Name, platform, b.URL)
 		}
+		if b.Rebuild < 0 {
+			return fmt.Errorf("formula %q: bottle rebuild for %s must not be negative", f.Name, platform)
+		}
+		for _, file := range b.Files {
+			if file == "" || filepath.IsAbs(file) || filepath.Clean(file) != file || strings.HasPrefix(file, "..") {
+				return fmt.Errorf("formula %q: bottle files for %s: %q must be a clean path inside the keg", f.Name, platform, file)
+			}
+		}
+	}
+
+	if f.Build.System != "" {
+		if _, err := f.Build.Resolve(); err != nil {
+			return fmt.Errorf("formula %q: %w", f.Name, err)
+		}
 	}
 
-	if f.Install.Type == "" && len(f.Build.Configure) == 0 && len(f.Build.Install) == 0 {
+	if f.Install.Type == "" && f.Build.System == "" && len(f.Build.Configure) == 0 && len(f.Build.Install) == 0 {
 		if len(f.Bottle) > 0 {
 			f.Install.Type = "archive"
 			f.Install.StripComponents = 2 // Most homebrew bottles extract to `name/version/`
@@ -207,19 +318,81 @@ func (f *Formula) Validate() error {
 	if f.Install.Type != "" && f.Install.Type != "binary" && f.Install.Type != "archive" {
 		return fmt.Errorf("formula %q has invalid install type %q (must be binary or archive)", f.Name, f.Install.Type)
 	}
-	for _, dep := range f.Dependencies {
-		if !validation.IsValidName(dep) {
-			return fmt.Errorf("formula %q: dependency %q contains invalid characters", f.Name, dep)
+	for _, list := range []struct {
+		field   string
+		entries []string
+	}{
+		{"dependencies", f.Dependencies},
+		{"build_dependencies", f.BuildDependencies},
+		{"linux_dependencies", f.LinuxDependencies},
+	} {
+		if _, err := parseDependencies(list.entries); err != nil {
+			return fmt.Errorf("formula %q: %s: %w", f.Name, list.field, err)
+		}
+	}
+	for i, step := range f.Test {
+		if strings.TrimSpace(step.Run) == "" {
+			return fmt.Errorf("formula %q: test[%d]: missing run command", f.Name, i)
 		}
+		if step.ExpectExit < 0 || step.ExpectExit > 255 {
+			return fmt.Errorf("formula %q: test[%d]: expect_exit must be between 0 and 255", f.Name, i)
+		}
+	}
+	if err := f.Deprecated.Validate("deprecated"); err != nil {
+		return fmt.Errorf("formula %q: %w", f.Name, err)
+	}
+	if err := f.Disabled.Validate("disabled"); err != nil {
+		return fmt.Errorf("formula %q: %w", f.Name, err)
+	}
+	if err := f.Livecheck.Validate(); err != nil {
+		return fmt.Errorf("formula %q: %w", f.Name, err)
+	}
+	if err := f.Head.Validate(); err != nil {
+		return fmt.Errorf("formula %q: %w", f.Name, err)
+	}
+	if err := f.validateOptions(); err != nil {
+		return err
+	}
+	if err := validateRequirements(f.Name, "requirements", f.Requirements); err != nil {
+		return err
+	}
+	if err := f.validateRelations(); err != nil {
+		return err
+	}
+	if err := f.validatePlatformBlocks(); err != nil {
+		return err
+	}
+	if err := validatePatches(f.Name, "patches", f.Patches); err != nil {
+		return err
+	}
+	if err := f.validateResources(); err != nil {
+		return err
 	}
 	return nil
 }
 
+// Parse parses and validates a formula for the host platform.
 func Parse(data []byte) (*Formula, error) {
+	return ParseFor(data, HostPlatform())
+}
+
+// JSONSchema describes the formula file format for editors.
+func JSONSchema() map[string]any {
+	return schema.Generate(Formula{}, "grew formula", "name", "version")
+}
+
+// ParseFor parses a formula and applies the on_macos, on_linux, on_arm and
+// on_intel blocks matching p. The result can be re-evaluated for another
+// platform with ForPlatform. Unknown keys are rejected; decoding errors
+// are *schema.Error values carrying the line and column.
+func ParseFor(data []byte, p Platform) (*Formula, error) {
 	var f Formula
-	if err := yaml.Unmarshal(data, &f); err != nil {
-		return nil, fmt.Errorf("parse formula YAML: %w", err)
+	if err := schema.Decode(data, &f); err != nil {
+		return nil, err
 	}
+	generic := f
+	f.generic = &generic
+	f.applyPlatform(p)
 	if err := f.Validate(); err != nil {
 		return nil, err
 	}
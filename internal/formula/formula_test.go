@@ -67,6 +67,21 @@ func TestParse_InvalidYAML(t *testing.T) {
 	}
 }
 
+func TestParse_UnknownField(t *testing.T) {
+	_, err := Parse([]byte(`name: test
+version: "1.0.0"
+dependancies: [foo]
+install:
+  type: binary
+`))
+	if err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+	if !strings.Contains(err.Error(), `line 3, column 1: unknown field "dependancies" (did you mean "dependencies"?)`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
 func TestParse_MissingName(t *testing.T) {
 	yml := `
 version: "1.0"
@@ -146,6 +161,98 @@ dependencies:
 	}
 }
 
+func TestParse_VersionScheme(t *testing.T) {
+	yml := `
+name: testpkg
+version: "1.0"
+version_scheme: 1
+url:
+  linux_amd64: "https://example.com/internal"
+install:
+  type: binary
+`
+	f, err := Parse([]byte(yml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	older := &Formula{Version: "2024.01"}
+	if f.ParsedVersion().Compare(older.ParsedVersion()) != 1 {
+		t.Error("version_scheme 1 should sort after version_scheme 0")
+	}
+
+	_, err = Parse([]byte(strings.Replace(yml, "version_scheme: 1", "version_scheme: -1", 1)))
+	if err == nil {
+		t.Fatal("expected error for negative version_scheme")
+	}
+}
+
+func TestParse_Revision(t *testing.T) {
+	yml := `
+name: testpkg
+version: "1.0"
+revision: 2
+bottle:
+  ` + PlatformKey() + `:
+    url: "https://example.com/testpkg-1.0.tar.gz"
+    rebuild: 1
+install:
+  type: binary
+`
+	f, err := Parse([]byte(yml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := f.PkgVersion(); got != "1.0_2" {
+		t.Errorf("PkgVersion() = %q, want 1.0_2", got)
+	}
+	if got := f.BottleRebuild(); got != 1 {
+		t.Errorf("BottleRebuild() = %d, want 1", got)
+	}
+	older := &Formula{Version: "1.0", Revision: 1}
+	if f.ParsedVersion().Compare(older.ParsedVersion()) != 1 {
+		t.Error("revision 2 should sort after revision 1")
+	}
+	if got := (&Formula{Version: "1.0"}).PkgVersion(); got != "1.0" {
+		t.Errorf("PkgVersion() without revision = %q, want 1.0", got)
+	}
+
+	_, err = Parse([]byte(strings.Replace(yml, "revision: 2", "revision: -1", 1)))
+	if err == nil {
+		t.Fatal("expected error for negative revision")
+	}
+	_, err = Parse([]byte(strings.Replace(yml, "rebuild: 1", "rebuild: -1", 1)))
+	if err == nil {
+		t.Fatal("expected error for negative bottle rebuild")
+	}
+}
+
+func TestParse_BottleFiles(t *testing.T) {
+	yml := `
+name: testpkg
+version: "1.0"
+bottle:
+  ` + PlatformKey() + `:
+    url: "https://example.com/testpkg-1.0.tar.gz"
+    files:
+      - bin/testpkg
+      - share/man/man1/testpkg.1
+install:
+  type: archive
+`
+	f, err := Parse([]byte(yml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := f.BottleFiles(); len(got) != 2 || got[1] != "share/man/man1/testpkg.1" {
+		t.Errorf("BottleFiles() = %v", got)
+	}
+	for _, bad := range []string{"/bin/testpkg", "../bin/testpkg", "bin//testpkg"} {
+		if _, err := Parse([]byte(strings.Replace(yml, "bin/testpkg", bad, 1))); err == nil {
+			t.Errorf("expected error for bottle file %q", bad)
+		}
+	}
+}
+
 func TestGetURL_CurrentPlatform(t *testing.T) {
 	f := &Formula{
 		Name: "test",
@@ -244,3 +351,123 @@ func TestSortedMapKeys(t *testing.T) {
 		t.Errorf("sortedMapKeys = %q, want %q", got, "a, b, c")
 	}
 }
+
+func TestParseDependency(t *testing.T) {
+	d, err := ParseDependency("openssl@3 >= 3.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Name != "openssl@3" {
+		t.Errorf("name = %q, want %q", d.Name, "openssl@3")
+	}
+	if !d.Constraint.Check("3.2") || d.Constraint.Check("3.0") {
+		t.Errorf("constraint %q checks wrong versions", d.Constraint)
+	}
+	if d.String() != "openssl@3 >= 3.1" {
+		t.Errorf("String() = %q", d.String())
+	}
+
+	d, err = ParseDependency("zlib~>1.3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Name != "zlib" || !d.Constraint.Check("1.9") || d.Constraint.Check("2.0") {
+		t.Errorf("unexpected parse of zlib~>1.3: %+v", d)
+	}
+
+	if _, err := ParseDependency("zlib >="); err == nil {
+		t.Error("expected error for operator without version")
+	}
+}
+
+func TestParse_InvalidDependencyConstraint(t *testing.T) {
+	yml := `
+name: testpkg
+version: "1.0"
+url:
+  linux_amd64: "https://example.com/internal"
+install:
+  type: binary
+dependencies:
+  - "zlib ~> latest"
+`
+	if _, err := Parse([]byte(yml)); err == nil {
+		t.Fatal("expected error for invalid dependency constraint")
+	}
+}
+
+func TestDepsFor_Platforms(t *testing.T) {
+	yml := `
+name: testpkg
+version: "1.0"
+url:
+  linux_amd64: "https://example.com/testpkg"
+install:
+  type: binary
+dependencies:
+  - zlib
+build_dependencies:
+  - "cmake >= 3.20"
+linux_dependencies:
+  - glibc
+`
+	f, err := Parse([]byte(yml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := DepNames(f.DepsFor("linux")); strings.Join(got, ",") != "zlib,glibc" {
+		t.Errorf("DepsFor(linux) = %v, want [zlib glibc]", got)
+	}
+	if got := DepNames(f.DepsFor("darwin")); strings.Join(got, ",") != "zlib" {
+		t.Errorf("DepsFor(darwin) = %v, want [zlib]", got)
+	}
+	build := f.BuildDeps()
+	if len(build) != 1 || build[0].String() != "cmake >= 3.20" {
+		t.Errorf("BuildDeps() = %v, want [cmake >= 3.20]", build)
+	}
+}
+
+func TestParse_InvalidBuildDependency(t *testing.T) {
+	yml := `
+name: testpkg
+version: "1.0"
+url:
+  linux_amd64: "https://example.com/testpkg"
+install:
+  type: binary
+build_dependencies:
+  - "../evil"
+`
+	if _, err := Parse([]byte(yml)); err == nil {
+		t.Fatal("expected error for invalid build dependency")
+	}
+}
+
+func TestParse_TestBlock(t *testing.T) {
+	yml := validYAML + `test:
+  - run: "testpkg --version"
+    expect_output: "1.0.0"
+  - run: "testpkg --bogus"
+    expect_exit: 2
+`
+	f, err := Parse([]byte(yml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.Test) != 2 {
+		t.Fatalf("expected 2 test steps, got %d", len(f.Test))
+	}
+	if f.Test[0].ExpectOutput != "1.0.0" || f.Test[0].ExpectExit != 0 {
+		t.Errorf("unexpected first step: %+v", f.Test[0])
+	}
+	if f.Test[1].ExpectExit != 2 {
+		t.Errorf("ExpectExit = %d, want 2", f.Test[1].ExpectExit)
+	}
+
+	if _, err := Parse([]byte(validYAML + "test:\n  - expect_output: x\n")); err == nil {
+		t.Error("expected error for test step without run")
+	}
+	if _, err := Parse([]byte(validYAML + "test:\n  - run: x\n    expect_exit: 300\n")); err == nil {
+		t.Error("expected error for out-of-range expect_exit")
+	}
+}
@@ -0,0 +1,73 @@
+package formula
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// HeadPrefix starts the version of kegs built from a head: source.
+const HeadPrefix = "HEAD-"
+
+// HeadSpec is the head: block of a formula: a git repository and branch
+// that grew install --HEAD builds from. An empty Branch means the
+// repository's default branch.
+//
+//	head:
+//	  url: https://github.com/jqlang/jq.git
+//	  branch: master
+type HeadSpec struct {
+	URL    string `yaml:"url"`
+	Branch string `yaml:"branch"`
+}
+
+var (
+	commitRe = regexp.MustCompile(`^[0-9a-f]{40}([0-9a-f]{24})?$`)
+	branchRe = regexp.MustCompile(`^[A-Za-z0-9._/+-]+$`)
+)
+
+// Validate checks the URL scheme and branch name.
+func (h *HeadSpec) Validate() error {
+	if h == nil {
+		return nil
+	}
+	if h.URL == "" {
+		return fmt.Errorf("head: missing url")
+	}
+	if !strings.HasPrefix(h.URL, "https://") {
+		return fmt.Errorf("head: url must use HTTPS: %s", h.URL)
+	}
+	if h.Branch != "" && (!branchRe.MatchString(h.Branch) || strings.HasPrefix(h.Branch, "-") || strings.Contains(h.Branch, "..")) {
+		return fmt.Errorf("head: invalid branch %q", h.Branch)
+	}
+	return nil
+}
+
+// Ref returns the git ref the head tracks: refs/heads/<branch>, or HEAD
+// for the default branch.
+func (h *HeadSpec) Ref() string {
+	if h.Branch == "" {
+		return "HEAD"
+	}
+	return "refs/heads/" + h.Branch
+}
+
+// IsCommit reports whether s is a full git commit hash.
+func IsCommit(s string) bool {
+	return commitRe.MatchString(s)
+}
+
+// HeadVersion returns the keg version for a build of commit:
+// "HEAD-" and the first seven characters of the hash.
+func HeadVersion(commit string) string {
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	return HeadPrefix + commit
+}
+
+// IsHeadVersion reports whether version names a keg built from a head:
+// source.
+func IsHeadVersion(version string) bool {
+	return strings.HasPrefix(version, HeadPrefix)
+}
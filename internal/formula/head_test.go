@@ -0,0 +1,86 @@
+package formula
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHeadSpec_Validate(t *testing.T) {
+	tests := []struct {
+		spec    HeadSpec
+		wantErr string
+	}{
+		{HeadSpec{URL: "https://example.com/foo.git", Branch: "main"}, ""},
+		{HeadSpec{URL: "https://example.com/foo.git", Branch: "release/2.x"}, ""},
+		{HeadSpec{}, "missing url"},
+		{HeadSpec{URL: "git://example.com/foo.git"}, "HTTPS"},
+		{HeadSpec{URL: "file:///srv/git/foo.git"}, "HTTPS"},
+		{HeadSpec{URL: "https://example.com/foo.git", Branch: "--upload-pack=x"}, "invalid branch"},
+		{HeadSpec{URL: "https://example.com/foo.git", Branch: "a..b"}, "invalid branch"},
+	}
+	for _, tt := range tests {
+		err := tt.spec.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%+v: unexpected error: %v", tt.spec, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%+v: error = %v, want %q", tt.spec, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHeadSpec_Ref(t *testing.T) {
+	if got := (&HeadSpec{URL: "https://x"}).Ref(); got != "HEAD" {
+		t.Errorf("Ref() = %q, want HEAD", got)
+	}
+	if got := (&HeadSpec{URL: "https://x", Branch: "dev"}).Ref(); got != "refs/heads/dev" {
+		t.Errorf("Ref() = %q, want refs/heads/dev", got)
+	}
+}
+
+func TestHeadVersion(t *testing.T) {
+	commit := "0123456789abcdef0123456789abcdef01234567"
+	if !IsCommit(commit) {
+		t.Errorf("IsCommit(%q) = false", commit)
+	}
+	if IsCommit("0123456") {
+		t.Error("IsCommit accepted a short hash")
+	}
+	v := HeadVersion(commit)
+	if v != "HEAD-0123456" {
+		t.Errorf("HeadVersion = %q, want HEAD-0123456", v)
+	}
+	if !IsHeadVersion(v) || IsHeadVersion("1.0") {
+		t.Error("IsHeadVersion mismatch")
+	}
+}
+
+func TestParse_HeadOnly(t *testing.T) {
+	f, err := Parse([]byte(`
+name: foo
+version: "1.0"
+head:
+  url: https://example.com/foo.git
+  branch: main
+build:
+  system: make
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if f.Head == nil || f.Head.Branch != "main" {
+		t.Errorf("Head = %+v", f.Head)
+	}
+
+	_, err = Parse([]byte(`
+name: foo
+version: "1.0"
+head:
+  url: http://example.com/foo.git
+build:
+  system: make
+`))
+	if err == nil || !strings.Contains(err.Error(), "HTTPS") {
+		t.Errorf("expected HTTPS error, got %v", err)
+	}
+}
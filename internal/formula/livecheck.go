@@ -0,0 +1,124 @@
+package formula
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Livecheck strategies.
+const (
+	// LivecheckPage matches Regex against the body of an HTML or JSON page.
+	LivecheckPage = "page"
+	// LivecheckGitHubReleases reads a GitHub-releases-style JSON feed: an
+	// array of objects with tag_name, draft and prerelease.
+	LivecheckGitHubReleases = "github_releases"
+	// LivecheckDirectory matches Regex against the links of a directory
+	// listing.
+	LivecheckDirectory = "directory"
+)
+
+// LivecheckSpec is the livecheck: block of a formula, which tells grew
+// livecheck where to look for upstream releases. Regex must have at most
+// one capture group; the group (or the whole match) is the version. URL
+// and Regex have defaults per strategy, see Resolve.
+//
+//	livecheck:
+//	  strategy: page
+//	  url: https://example.com/download.html
+//	  regex: foo-(\d+(?:\.\d+)+)\.tar\.gz
+type LivecheckSpec struct {
+	Strategy string `yaml:"strategy"`
+	URL      string `yaml:"url"`
+	Regex    string `yaml:"regex"`
+}
+
+// LivecheckStrategies returns the accepted strategy names.
+func LivecheckStrategies() []string {
+	return []string{LivecheckDirectory, LivecheckGitHubReleases, LivecheckPage}
+}
+
+// Validate checks the strategy, URL and regex.
+func (l *LivecheckSpec) Validate() error {
+	if l == nil {
+		return nil
+	}
+	switch l.Strategy {
+	case LivecheckPage, LivecheckGitHubReleases, LivecheckDirectory:
+	default:
+		return fmt.Errorf("livecheck: unknown strategy %q (available: %s)", l.Strategy, strings.Join(LivecheckStrategies(), ", "))
+	}
+	if l.URL != "" && !strings.HasPrefix(l.URL, "https://") {
+		return fmt.Errorf("livecheck: url must use HTTPS: %s", l.URL)
+	}
+	if l.Strategy == LivecheckPage && l.Regex == "" {
+		return fmt.Errorf("livecheck: the page strategy needs a regex")
+	}
+	if l.Regex != "" {
+		re, err := regexp.Compile(l.Regex)
+		if err != nil {
+			return fmt.Errorf("livecheck: invalid regex: %w", err)
+		}
+		if re.NumSubexp() > 1 {
+			return fmt.Errorf("livecheck: regex must have at most one capture group")
+		}
+	}
+	return nil
+}
+
+// Resolve returns the URL and regex to check f with, filling in the
+// defaults: the GitHub releases API of the repository the homepage or
+// source points at, the directory holding the source tarball, and for
+// directories a regex matching "<name>-<version>.<archive>" files or
+// version-named subdirectories.
+func (l *LivecheckSpec) Resolve(f *Formula) (string, *regexp.Regexp, error) {
+	u, expr := l.URL, l.Regex
+	switch l.Strategy {
+	case LivecheckGitHubReleases:
+		if u == "" {
+			u = githubReleasesAPI(f)
+		}
+		if expr == "" {
+			expr = `^v?(\d[\w.+-]*)$`
+		}
+	case LivecheckDirectory:
+		if u == "" {
+			if src, err := f.GetSourceURL(); err == nil {
+				if p, err := url.Parse(src); err == nil {
+					p.Path = strings.TrimSuffix(path.Dir(p.Path), "/") + "/"
+					p.RawQuery, p.Fragment = "", ""
+					u = p.String()
+				}
+			}
+		}
+		if expr == "" {
+			expr = `^(?:` + regexp.QuoteMeta(f.Name) + `-v?)?(\d+(?:\.\d+)+)(?:\.tar\.(?:gz|xz|bz2)|\.tgz|\.zip|/)?$`
+		}
+	}
+	if u == "" {
+		return "", nil, fmt.Errorf("livecheck: no url given and none can be derived")
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", nil, fmt.Errorf("livecheck: invalid regex: %w", err)
+	}
+	return u, re, nil
+}
+
+// githubReleasesAPI returns the releases API URL of the GitHub repository
+// f's homepage or source URL points at, or "".
+func githubReleasesAPI(f *Formula) string {
+	candidates := []string{f.Homepage, f.Source.URL, f.SourceURL}
+	for _, raw := range candidates {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host != "github.com" {
+			continue
+		}
+		if parts := strings.Split(strings.Trim(u.Path, "/"), "/"); len(parts) >= 2 {
+			return "https://api.github.com/repos/" + parts[0] + "/" + parts[1] + "/releases"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package formula
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLivecheckSpec_Validate(t *testing.T) {
+	tests := []struct {
+		spec    LivecheckSpec
+		wantErr string
+	}{
+		{LivecheckSpec{Strategy: "page", URL: "https://example.com", Regex: `foo-(\d+)`}, ""},
+		{LivecheckSpec{Strategy: "directory"}, ""},
+		{LivecheckSpec{Strategy: "rss"}, "unknown strategy"},
+		{LivecheckSpec{Strategy: "page", URL: "https://example.com"}, "needs a regex"},
+		{LivecheckSpec{Strategy: "page", URL: "http://example.com", Regex: "x"}, "HTTPS"},
+		{LivecheckSpec{Strategy: "page", Regex: "("}, "invalid regex"},
+		{LivecheckSpec{Strategy: "page", Regex: "(a)(b)"}, "one capture group"},
+	}
+	for _, tt := range tests {
+		err := tt.spec.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%+v: unexpected error: %v", tt.spec, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%+v: error = %v, want %q", tt.spec, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLivecheckSpec_ResolveDefaults(t *testing.T) {
+	f := &Formula{
+		Name:     "foo",
+		Homepage: "https://github.com/owner/foo",
+		Source:   SourceSpec{URL: "https://example.com/pub/foo/foo-1.0.tar.gz?x=1"},
+	}
+
+	u, re, err := (&LivecheckSpec{Strategy: LivecheckGitHubReleases}).Resolve(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "https://api.github.com/repos/owner/foo/releases" {
+		t.Errorf("github url = %q", u)
+	}
+	if m := re.FindStringSubmatch("v1.2.3"); m == nil || m[1] != "1.2.3" {
+		t.Errorf("github regex on v1.2.3 = %v", m)
+	}
+
+	u, re, err = (&LivecheckSpec{Strategy: LivecheckDirectory}).Resolve(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "https://example.com/pub/foo/" {
+		t.Errorf("directory url = %q", u)
+	}
+	for name, want := range map[string]string{"foo-1.2.tar.gz": "1.2", "2.0/": "2.0", "foo-1.2.tar.gz.sig": "", "foobar-3.0.zip": ""} {
+		got := ""
+		if m := re.FindStringSubmatch(name); m != nil {
+			got = m[1]
+		}
+		if got != want {
+			t.Errorf("directory regex on %q = %q, want %q", name, got, want)
+		}
+	}
+
+	if _, _, err := (&LivecheckSpec{Strategy: LivecheckPage, Regex: "x"}).Resolve(f); err == nil {
+		t.Error("expected error: page strategy has no default url")
+	}
+}
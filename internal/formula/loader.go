@@ -1,15 +1,28 @@
 package formula
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+
+	"github.com/homegrew/grew/internal/schema"
 )
 
 type Loader struct {
-	TapDir   string
+	TapDir string
+	// TapPaths are extra tap directories outside TapDir (e.g. a formula
+	// checkout under development). They are searched before the taps.
+	TapPaths []string
 	DebugLog func(format string, args ...any) // optional debug logger
+
+	// files maps the names of formulas loaded with LoadFile to their
+	// paths, so dependency resolution finds them by name.
+	files map[string]string
+	// overrides holds formulas set with Use.
+	overrides map[string]*Formula
 }
 
 func (l *Loader) debugf(format string, args ...any) {
@@ -18,46 +31,163 @@ func (l *Loader) debugf(format string, args ...any) {
 	}
 }
 
+// notFoundError reports that no formula file has the given name.
+type notFoundError struct {
+	name string
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("formula not found: %q", e.name)
+}
+
+func isNotFound(err error) bool {
+	var nf *notFoundError
+	return errors.As(err, &nf)
+}
+
+// LoadByName loads the formula named name. A name with no formula file of
+// its own is looked up in the taps' renames and aliases, and then among
+// the versioned formulas of its family, so the returned formula's Name
+// may differ from name. A formula file that exists but fails to load is
+// an error, never a reason to fall back.
 func (l *Loader) LoadByName(name string) (*Formula, error) {
 	name = strings.TrimSuffix(name, ".yaml")
-	taps, err := os.ReadDir(l.TapDir)
+	f, err := l.loadExact(name)
+	if err == nil || !isNotFound(err) {
+		return f, err
+	}
+	if target := l.ResolveName(name); target != name {
+		l.debugf("%s resolves to %s\n", name, target)
+		if f, terr := l.loadExact(target); terr == nil || !isNotFound(terr) {
+			return f, terr
+		}
+	}
+	if f, ferr := l.loadFamilyMember(name); !isNotFound(ferr) {
+		return f, ferr
+	}
+	return nil, err
+}
+
+func (l *Loader) loadExact(name string) (*Formula, error) {
+	if f, ok := l.overrides[name]; ok {
+		g := *f
+		return &g, nil
+	}
+	if path, ok := l.files[name]; ok {
+		return l.loadFromFile(path)
+	}
+	dirs, err := l.tapDirs()
 	if err != nil {
-		return nil, fmt.Errorf("read taps directory: %w", err)
+		return nil, err
 	}
 
 	var lastErr error
-	for _, tap := range taps {
-		if !tap.IsDir() {
-			continue
-		}
-		path := filepath.Join(l.TapDir, tap.Name(), name+".yaml")
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name+".yaml")
 		f, err := l.loadFromFile(path)
 		if err == nil {
 			return f, nil
 		}
 		if !os.IsNotExist(err) {
-			lastErr = fmt.Errorf("failed to parse %s: %w", path, err)
+			lastErr = err
 		}
 	}
 	if lastErr != nil {
-		return nil, fmt.Errorf("formula not found: %q (%v)", name, lastErr)
+		return nil, fmt.Errorf("load formula %q: %w", name, lastErr)
 	}
-	return nil, fmt.Errorf("formula not found: %q", name)
+	return nil, &notFoundError{name: name}
 }
 
-func (l *Loader) LoadAll() ([]*Formula, error) {
-	var formulas []*Formula
+// LoadFile loads a formula from a file outside the taps. From then on the
+// loader answers to the formula's name with this file, ahead of any tap.
+func (l *Loader) LoadFile(path string) (*Formula, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := l.loadFromFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	if l.files == nil {
+		l.files = make(map[string]string)
+	}
+	l.files[f.Name] = abs
+	return f, nil
+}
+
+// Use makes the loader answer to f's name with f, ahead of any file, e.g.
+// an older version of a formula taken from the history of its tap.
+func (l *Loader) Use(f *Formula) {
+	if l.overrides == nil {
+		l.overrides = make(map[string]*Formula)
+	}
+	l.overrides[f.Name] = f
+}
+
+// IsLocal reports whether a formula file lies outside TapDir, i.e. it was
+// loaded with LoadFile or from one of TapPaths.
+func (l *Loader) IsLocal(path string) bool {
+	if path == "" {
+		return false
+	}
+	rel, err := filepath.Rel(l.TapDir, path)
+	return err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// tapDirs returns the directories formulas are looked up in, in order:
+// TapPaths, then each tap under TapDir.
+func (l *Loader) tapDirs() ([]string, error) {
 	taps, err := os.ReadDir(l.TapDir)
 	if err != nil {
 		return nil, fmt.Errorf("read taps directory: %w", err)
 	}
+	dirs := slices.Clone(l.TapPaths)
 	for _, tap := range taps {
-		if !tap.IsDir() {
-			continue
+		if tap.IsDir() {
+			dirs = append(dirs, filepath.Join(l.TapDir, tap.Name()))
 		}
-		tapFormulas, err := l.LoadFromTap(filepath.Join(l.TapDir, tap.Name()))
+	}
+	return dirs, nil
+}
+
+// LoadProvider returns the formula named name or, if there is none, the
+// first formula in tap order that lists name in provides.
+func (l *Loader) LoadProvider(name string) (*Formula, error) {
+	f, err := l.LoadByName(name)
+	if err == nil || !isNotFound(err) {
+		return f, err
+	}
+	all, allErr := l.LoadAll()
+	if allErr != nil {
+		return nil, err
+	}
+	for _, candidate := range all {
+		if candidate.ProvidesName(name) {
+			l.debugf("%s is provided by %s\n", name, candidate.Name)
+			return candidate, nil
+		}
+	}
+	return nil, err
+}
+
+// LoadAll loads the formulas loaded with LoadFile and every formula of
+// TapPaths and the taps.
+func (l *Loader) LoadAll() ([]*Formula, error) {
+	var formulas []*Formula
+	for _, name := range sortedKeys(l.files) {
+		if f, err := l.loadFromFile(l.files[name]); err == nil {
+			formulas = append(formulas, f)
+		}
+	}
+	dirs, err := l.tapDirs()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		tapFormulas, err := l.LoadFromTap(dir)
 		if err != nil {
-			l.debugf("failed to load tap %s: %v\n", tap.Name(), err)
+			l.debugf("failed to load tap %s: %v\n", filepath.Base(dir), err)
 			continue
 		}
 		formulas = append(formulas, tapFormulas...)
@@ -65,24 +195,41 @@ func (l *Loader) LoadAll() ([]*Formula, error) {
 	return formulas, nil
 }
 
+// LoadFromTap loads the formulas of one tap, skipping (and debug-logging)
+// files that fail to parse. Use ReadTap to see every problem.
 func (l *Loader) LoadFromTap(tapPath string) ([]*Formula, error) {
+	formulas, problems, err := l.ReadTap(tapPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range problems {
+		l.debugf("failed to parse %v\n", p)
+	}
+	return formulas, nil
+}
+
+// ReadTap loads every formula of one tap and returns the ones that parse,
+// plus one error per file that does not, each naming the file (and the
+// line and column when known).
+func (l *Loader) ReadTap(tapPath string) ([]*Formula, []error, error) {
 	entries, err := os.ReadDir(tapPath)
 	if err != nil {
-		return nil, fmt.Errorf("read tap %s: %w", tapPath, err)
+		return nil, nil, fmt.Errorf("read tap %s: %w", tapPath, err)
 	}
 	var formulas []*Formula
+	var problems []error
 	for _, e := range entries {
 		if e.IsDir() || !strings.HasSuffix(e.Name(), ".yaml") {
 			continue
 		}
 		f, err := l.loadFromFile(filepath.Join(tapPath, e.Name()))
 		if err != nil {
-			l.debugf("failed to parse %s: %v\n", e.Name(), err)
+			problems = append(problems, err)
 			continue
 		}
 		formulas = append(formulas, f)
 	}
-	return formulas, nil
+	return formulas, problems, nil
 }
 
 func (l *Loader) loadFromFile(path string) (*Formula, error) {
@@ -90,5 +237,10 @@ func (l *Loader) loadFromFile(path string) (*Formula, error) {
 	if err != nil {
 		return nil, err
 	}
-	return Parse(data)
+	f, err := Parse(data)
+	if err != nil {
+		return nil, schema.InFile(err, path)
+	}
+	f.Path = path
+	return f, nil
 }
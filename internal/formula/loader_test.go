@@ -3,6 +3,7 @@ package formula
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -75,3 +76,108 @@ func TestLoadAll_MultipleFiles(t *testing.T) {
 		t.Errorf("loaded %d formulas, want 3", len(all))
 	}
 }
+
+func TestReadTap_ReportsBadFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeTestFormula(t, tapDir, "good")
+	bad := filepath.Join(tapDir, "bad.yaml")
+	if err := os.WriteFile(bad, []byte("name: bad\nversion: \"1.0\"\nbogus: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := &Loader{TapDir: tmpDir}
+	formulas, problems, err := loader.ReadTap(tapDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(formulas) != 1 || formulas[0].Name != "good" {
+		t.Errorf("loaded %v, want only good", formulas)
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0].Error(), bad+":3:1: unknown field \"bogus\"") {
+		t.Errorf("problems = %v", problems)
+	}
+}
+
+func TestLoadFile_AndTapPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "Taps", "core")
+	os.MkdirAll(tapDir, 0755)
+	writeTestFormula(t, tapDir, "shared")
+	writeTestFormula(t, tapDir, "tapped")
+
+	devDir := filepath.Join(tmpDir, "dev")
+	os.MkdirAll(devDir, 0755)
+	writeTestFormula(t, devDir, "shared")
+	writeTestFormula(t, devDir, "devonly")
+	fileDir := filepath.Join(tmpDir, "work")
+	os.MkdirAll(fileDir, 0755)
+	writeTestFormula(t, fileDir, "single")
+
+	loader := &Loader{TapDir: filepath.Join(tmpDir, "Taps"), TapPaths: []string{devDir}}
+
+	f, err := loader.LoadByName("shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Path != filepath.Join(devDir, "shared.yaml") || !loader.IsLocal(f.Path) {
+		t.Errorf("shared should come from the tap path, got %s", f.Path)
+	}
+	if f, err := loader.LoadByName("tapped"); err != nil || loader.IsLocal(f.Path) {
+		t.Errorf("tapped should come from the tap: %v", err)
+	}
+
+	if _, err := loader.LoadByName("single"); err == nil {
+		t.Fatal("single should not be found before LoadFile")
+	}
+	f, err = loader.LoadFile(filepath.Join(fileDir, "single.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loader.IsLocal(f.Path) {
+		t.Error("a formula loaded with LoadFile is local")
+	}
+	if f, err := loader.LoadByName("single"); err != nil || f.Path != filepath.Join(fileDir, "single.yaml") {
+		t.Errorf("single should be found by name after LoadFile: %v", err)
+	}
+
+	all, err := loader.LoadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 {
+		t.Errorf("LoadAll loaded %d formulas, want 5", len(all))
+	}
+
+	if _, err := loader.LoadFile(filepath.Join(fileDir, "missing.yaml")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestLoader_Use(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeTestFormula(t, tapDir, "mypkg")
+
+	loader := &Loader{TapDir: tmpDir}
+	f, err := loader.LoadByName("mypkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := *f
+	old.Version = "0.9"
+	loader.Use(&old)
+
+	got, err := loader.LoadByName("mypkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != "0.9" {
+		t.Errorf("LoadByName after Use gave version %q, want 0.9", got.Version)
+	}
+	if got == &old {
+		t.Error("LoadByName should return a copy of the formula set with Use")
+	}
+}
@@ -0,0 +1,190 @@
+package formula
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/homegrew/grew/internal/validation"
+)
+
+// OptionSpec is one build option of a formula, switched on with
+// --with-<name> and off with --without-<name> on a source build. Default
+// is its state when neither is given.
+//
+//	options:
+//	  - name: tls
+//	    description: Build with TLS support
+//	optional_dependencies:
+//	  - openssl@3
+//	recommended_dependencies:
+//	  - zlib
+//
+// Each optional dependency adds an option of its name that is off by
+// default, and each recommended dependency one that is on by default;
+// the dependency is only installed while its option is on.
+type OptionSpec struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Default     bool   `yaml:"default"`
+}
+
+// BuildOptions returns the formula's options: the options: list, then
+// one per optional and recommended dependency.
+func (f *Formula) BuildOptions() []OptionSpec {
+	opts := append([]OptionSpec(nil), f.Options...)
+	for _, d := range validDeps(f.OptionalDependencies) {
+		opts = append(opts, OptionSpec{Name: d.Name, Description: "Build with " + d.Name + " support"})
+	}
+	for _, d := range validDeps(f.RecommendedDependencies) {
+		opts = append(opts, OptionSpec{Name: d.Name, Description: "Build with " + d.Name + " support", Default: true})
+	}
+	return opts
+}
+
+// ParseOptionFlag splits an option flag such as "--with-tls" or
+// "without-zlib" into the option name and whether it turns it on.
+func ParseOptionFlag(s string) (string, bool, bool) {
+	s = strings.TrimLeft(s, "-")
+	if name, ok := strings.CutPrefix(s, "without-"); ok && name != "" {
+		return name, false, true
+	}
+	if name, ok := strings.CutPrefix(s, "with-"); ok && name != "" {
+		return name, true, true
+	}
+	return "", false, false
+}
+
+// WithOptions returns a copy of f built with the given option flags
+// ("with-<name>" or "without-<name>", dashes optional). Unknown options
+// are an error.
+func (f *Formula) WithOptions(flags []string) (*Formula, error) {
+	known := make(map[string]bool)
+	for _, o := range f.BuildOptions() {
+		known[o.Name] = true
+	}
+	choices := make(map[string]bool, len(f.choices)+len(flags))
+	for k, v := range f.choices {
+		choices[k] = v
+	}
+	for _, flag := range flags {
+		name, on, ok := ParseOptionFlag(flag)
+		if !ok {
+			return nil, fmt.Errorf("invalid option %q: expected --with-<option> or --without-<option>", flag)
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("formula %q has no option %q%s", f.Name, name, f.optionHint())
+		}
+		choices[name] = on
+	}
+	g := *f
+	g.choices = choices
+	return &g, nil
+}
+
+// OptionEnabled reports whether option name is on for this build.
+func (f *Formula) OptionEnabled(name string) bool {
+	if on, ok := f.choices[name]; ok {
+		return on
+	}
+	for _, o := range f.BuildOptions() {
+		if o.Name == name {
+			return o.Default
+		}
+	}
+	return false
+}
+
+// UsedOptions returns the option flags that differ from the defaults,
+// sorted, e.g. ["with-tls", "without-zlib"]. They are what a rebuild needs
+// to reproduce this one.
+func (f *Formula) UsedOptions() []string {
+	var used []string
+	for _, o := range f.BuildOptions() {
+		on, ok := f.choices[o.Name]
+		if !ok || on == o.Default {
+			continue
+		}
+		if on {
+			used = append(used, "with-"+o.Name)
+		} else {
+			used = append(used, "without-"+o.Name)
+		}
+	}
+	sort.Strings(used)
+	return used
+}
+
+// OptionVars returns the build step placeholders for the options:
+// {with-<name>} expands to "yes" or "no".
+func (f *Formula) OptionVars() map[string]string {
+	vars := make(map[string]string)
+	for _, o := range f.BuildOptions() {
+		v := "no"
+		if f.OptionEnabled(o.Name) {
+			v = "yes"
+		}
+		vars["with-"+o.Name] = v
+	}
+	return vars
+}
+
+// optionDeps returns the optional and recommended dependencies whose
+// options are on.
+func (f *Formula) optionDeps() []Dependency {
+	var deps []Dependency
+	for _, list := range [][]string{f.OptionalDependencies, f.RecommendedDependencies} {
+		for _, d := range validDeps(list) {
+			if f.OptionEnabled(d.Name) {
+				deps = append(deps, d)
+			}
+		}
+	}
+	return deps
+}
+
+// validateOptions checks option names for clashes and makes sure an
+// optional or recommended dependency is not also a plain dependency.
+func (f *Formula) validateOptions() error {
+	for _, list := range []struct {
+		field   string
+		entries []string
+	}{
+		{"optional_dependencies", f.OptionalDependencies},
+		{"recommended_dependencies", f.RecommendedDependencies},
+	} {
+		if _, err := parseDependencies(list.entries); err != nil {
+			return fmt.Errorf("formula %q: %s: %w", f.Name, list.field, err)
+		}
+	}
+	plain := make(map[string]bool)
+	for _, d := range validDeps(f.Dependencies) {
+		plain[d.Name] = true
+	}
+	seen := make(map[string]bool)
+	for i, o := range f.BuildOptions() {
+		if i < len(f.Options) && !validation.IsValidName(o.Name) {
+			return fmt.Errorf("formula %q: options[%d]: invalid name %q", f.Name, i, o.Name)
+		}
+		if seen[o.Name] {
+			return fmt.Errorf("formula %q: option %q is declared twice", f.Name, o.Name)
+		}
+		seen[o.Name] = true
+		if i >= len(f.Options) && plain[o.Name] {
+			return fmt.Errorf("formula %q: %s is both a dependency and an optional or recommended one", f.Name, o.Name)
+		}
+	}
+	return nil
+}
+
+func (f *Formula) optionHint() string {
+	opts := f.BuildOptions()
+	if len(opts) == 0 {
+		return " (it has no options)"
+	}
+	names := make([]string, len(opts))
+	for i, o := range opts {
+		names[i] = o.Name
+	}
+	return " (available: " + strings.Join(names, ", ") + ")"
+}
@@ -0,0 +1,140 @@
+package formula
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const optionsFormula = `
+name: curl
+version: "8.5.0"
+source:
+  url: https://example.com/curl-8.5.0.tar.gz
+  sha256: e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855
+build:
+  configure:
+    - ./configure --prefix={prefix} --with-ssl={with-openssl@3} --enable-ipv6={with-ipv6}
+dependencies:
+  - pcre2
+options:
+  - name: ipv6
+    description: Build with IPv6 support
+    default: true
+  - name: debug
+    description: Build with debug symbols
+optional_dependencies:
+  - openssl@3 >= 3.1
+recommended_dependencies:
+  - zlib
+`
+
+func TestParseOptionFlag(t *testing.T) {
+	tests := []struct {
+		in   string
+		name string
+		on   bool
+		ok   bool
+	}{
+		{"--with-tls", "tls", true, true},
+		{"without-zlib", "zlib", false, true},
+		{"-with-openssl@3", "openssl@3", true, true},
+		{"--with-", "", false, false},
+		{"--verbose", "", false, false},
+	}
+	for _, tt := range tests {
+		name, on, ok := ParseOptionFlag(tt.in)
+		if name != tt.name || on != tt.on || ok != tt.ok {
+			t.Errorf("ParseOptionFlag(%q) = %q, %v, %v; want %q, %v, %v", tt.in, name, on, ok, tt.name, tt.on, tt.ok)
+		}
+	}
+}
+
+func TestBuildOptions_Defaults(t *testing.T) {
+	f, err := Parse([]byte(optionsFormula))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	var names []string
+	for _, o := range f.BuildOptions() {
+		names = append(names, o.Name)
+	}
+	if want := []string{"ipv6", "debug", "openssl@3", "zlib"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("options = %v, want %v", names, want)
+	}
+	if got := DepNames(f.DepsFor("darwin")); !reflect.DeepEqual(got, []string{"pcre2", "zlib"}) {
+		t.Errorf("default deps = %v, want [pcre2 zlib]", got)
+	}
+	if used := f.UsedOptions(); len(used) != 0 {
+		t.Errorf("UsedOptions = %v, want none", used)
+	}
+	vars := f.OptionVars()
+	if vars["with-ipv6"] != "yes" || vars["with-debug"] != "no" || vars["with-openssl@3"] != "no" || vars["with-zlib"] != "yes" {
+		t.Errorf("OptionVars = %v", vars)
+	}
+}
+
+func TestWithOptions(t *testing.T) {
+	f, err := Parse([]byte(optionsFormula))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	g, err := f.WithOptions([]string{"--with-openssl@3", "--without-zlib", "--without-ipv6", "--with-ipv6"})
+	if err != nil {
+		t.Fatalf("WithOptions: %v", err)
+	}
+	deps := g.DepsFor("darwin")
+	if got := DepNames(deps); !reflect.DeepEqual(got, []string{"pcre2", "openssl@3"}) {
+		t.Errorf("deps = %v, want [pcre2 openssl@3]", got)
+	}
+	if deps[1].Constraint.IsEmpty() {
+		t.Error("optional dependency lost its constraint")
+	}
+	if used := g.UsedOptions(); !reflect.DeepEqual(used, []string{"with-openssl@3", "without-zlib"}) {
+		t.Errorf("UsedOptions = %v", used)
+	}
+	if f.OptionEnabled("openssl@3") {
+		t.Error("WithOptions modified the original formula")
+	}
+
+	steps, _ := g.Build.Resolve()
+	line := ExpandBuildStep(steps.Configure[0], g.OptionVars())
+	if !strings.Contains(line, "--with-ssl=yes --enable-ipv6=yes") {
+		t.Errorf("expanded step = %q", line)
+	}
+
+	// Choices survive platform evaluation.
+	p, err := g.ForPlatform(Platform{OS: "linux", Arch: "arm64"})
+	if err != nil {
+		t.Fatalf("ForPlatform: %v", err)
+	}
+	if !p.OptionEnabled("openssl@3") {
+		t.Error("ForPlatform dropped the option choices")
+	}
+
+	if _, err := f.WithOptions([]string{"--with-gnutls"}); err == nil || !strings.Contains(err.Error(), "available: ipv6, debug, openssl@3, zlib") {
+		t.Errorf("expected unknown option error, got %v", err)
+	}
+	if _, err := f.WithOptions([]string{"--enable-foo"}); err == nil {
+		t.Error("expected error for a malformed option")
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	base := "name: foo\nversion: \"1.0\"\nurl:\n  linux_amd64: https://example.com/foo\ninstall:\n  type: binary\n"
+	tests := []struct {
+		extra   string
+		wantErr string
+	}{
+		{"options:\n  - name: Bad Name\n", "invalid name"},
+		{"options:\n  - name: zlib\nrecommended_dependencies:\n  - zlib\n", "declared twice"},
+		{"dependencies:\n  - zlib\noptional_dependencies:\n  - zlib\n", "both a dependency"},
+		{"optional_dependencies:\n  - \"zlib >=\"\n", "optional_dependencies"},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(base + tt.extra))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%q: error = %v, want %q", tt.extra, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package formula
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/homegrew/grew/internal/validation"
+)
+
+// PatchSpec is a patch applied to the source tree before the configure
+// phase of a source build. Exactly one of URL and File is set; File is
+// relative to the directory holding the formula file.
+type PatchSpec struct {
+	URL       string `yaml:"url"`
+	File      string `yaml:"file"`
+	SHA256    string `yaml:"sha256"`
+	Signature string `yaml:"signature"`
+	Strip     *int   `yaml:"strip"` // patch -p level; defaults to 1
+}
+
+// StripLevel returns the -p level to apply the patch with.
+func (p PatchSpec) StripLevel() int {
+	if p.Strip == nil {
+		return 1
+	}
+	return *p.Strip
+}
+
+// Location returns the patch URL or file, for messages.
+func (p PatchSpec) Location() string {
+	if p.URL != "" {
+		return p.URL
+	}
+	return p.File
+}
+
+// ResourceSpec is an extra source archive for a source build. It is
+// extracted (stripping one leading directory) into a subdirectory of the
+// build tree named after the resource.
+type ResourceSpec struct {
+	Name      string `yaml:"name"`
+	URL       string `yaml:"url"`
+	SHA256    string `yaml:"sha256"`
+	Signature string `yaml:"signature"`
+}
+
+// PatchPath returns the local path of a file patch.
+func (f *Formula) PatchPath(p PatchSpec) (string, error) {
+	if p.File == "" {
+		return "", fmt.Errorf("formula %q: patch %s is not a file patch", f.Name, p.URL)
+	}
+	if f.Path == "" {
+		return "", fmt.Errorf("formula %q: cannot locate patch %s: formula was not loaded from a file", f.Name, p.File)
+	}
+	return filepath.Join(filepath.Dir(f.Path), p.File), nil
+}
+
+func validatePatches(name, field string, patches []PatchSpec) error {
+	for i, p := range patches {
+		where := fmt.Sprintf("%s[%d]", field, i)
+		switch {
+		case p.URL != "" && p.File != "":
+			return fmt.Errorf("formula %q: %s: set only one of url and file", name, where)
+		case p.URL == "" && p.File == "":
+			return fmt.Errorf("formula %q: %s: missing url or file", name, where)
+		case p.URL != "" && !strings.HasPrefix(p.URL, "https://"):
+			return fmt.Errorf("formula %q: %s: refusing insecure URL %s", name, where, p.URL)
+		case p.File != "" && !filepath.IsLocal(p.File):
+			return fmt.Errorf("formula %q: %s: file %q must be a relative path inside the tap", name, where, p.File)
+		case p.Strip != nil && *p.Strip < 0:
+			return fmt.Errorf("formula %q: %s: strip must not be negative", name, where)
+		}
+		if err := validation.ValidateSHA256(p.SHA256); err != nil {
+			return fmt.Errorf("formula %q: %s: invalid sha256: %w", name, where, err)
+		}
+	}
+	return nil
+}
+
+func (f *Formula) validateResources() error {
+	seen := make(map[string]bool, len(f.Resources))
+	for i, r := range f.Resources {
+		where := fmt.Sprintf("resources[%d]", i)
+		if !validation.IsValidName(r.Name) {
+			return fmt.Errorf("formula %q: %s: invalid name %q", f.Name, where, r.Name)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("formula %q: duplicate resource %q", f.Name, r.Name)
+		}
+		seen[r.Name] = true
+		if !strings.HasPrefix(r.URL, "https://") {
+			return fmt.Errorf("formula %q: resource %q: refusing insecure or missing URL %q", f.Name, r.Name, r.URL)
+		}
+		if err := validation.ValidateSHA256(r.SHA256); err != nil {
+			return fmt.Errorf("formula %q: resource %q: invalid sha256: %w", f.Name, r.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package formula
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const patchYAML = `
+name: testpkg
+version: "1.0"
+source:
+  url: "https://example.com/testpkg-1.0.tar.gz"
+  sha256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+build:
+  system: autotools
+patches:
+  - url: "https://example.com/fix-build.patch"
+    sha256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+  - file: "patches/testpkg/musl.diff"
+    sha256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+    strip: 0
+resources:
+  - name: vendor-lib
+    url: "https://example.com/vendor-lib-2.1.tar.gz"
+    sha256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+`
+
+func TestParse_PatchesAndResources(t *testing.T) {
+	f, err := Parse([]byte(patchYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.Patches) != 2 {
+		t.Fatalf("expected 2 patches, got %d", len(f.Patches))
+	}
+	if got := f.Patches[0].StripLevel(); got != 1 {
+		t.Errorf("default strip level = %d, want 1", got)
+	}
+	if got := f.Patches[1].StripLevel(); got != 0 {
+		t.Errorf("strip level = %d, want 0", got)
+	}
+	if len(f.Resources) != 1 || f.Resources[0].Name != "vendor-lib" {
+		t.Errorf("unexpected resources: %+v", f.Resources)
+	}
+}
+
+func TestParse_InvalidPatches(t *testing.T) {
+	tests := map[string]struct{ old, new string }{
+		"url and file": {`  - file: "patches/testpkg/musl.diff"`, `  - url: "https://example.com/x.patch"
+    file: "patches/testpkg/musl.diff"`},
+		"escaping file":     {`"patches/testpkg/musl.diff"`, `"../../etc/passwd"`},
+		"insecure url":      {`"https://example.com/fix-build.patch"`, `"http://example.com/fix-build.patch"`},
+		"negative strip":    {"strip: 0", "strip: -1"},
+		"bad resource name": {"name: vendor-lib", "name: ../lib"},
+		"insecure resource": {`"https://example.com/vendor-lib-2.1.tar.gz"`, `"http://example.com/vendor-lib-2.1.tar.gz"`},
+		"resource sha": {`    url: "https://example.com/vendor-lib-2.1.tar.gz"
+    sha256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"`, `    url: "https://example.com/vendor-lib-2.1.tar.gz"
+    sha256: "abc"`},
+	}
+	for name, tt := range tests {
+		yml := strings.Replace(patchYAML, tt.old, tt.new, 1)
+		if yml == patchYAML {
+			t.Fatalf("%s: replacement did not apply", name)
+		}
+		if _, err := Parse([]byte(yml)); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestPatchPath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "testpkg.yaml")
+	if err := os.WriteFile(path, []byte(patchYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := (&Loader{}).loadFromFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := f.PatchPath(f.Patches[1])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(dir, "patches", "testpkg", "musl.diff"); got != want {
+		t.Errorf("PatchPath = %q, want %q", got, want)
+	}
+	if _, err := f.PatchPath(f.Patches[0]); err == nil {
+		t.Error("expected error for a URL patch")
+	}
+
+	parsed, _ := Parse([]byte(patchYAML))
+	if _, err := parsed.PatchPath(parsed.Patches[1]); err == nil {
+		t.Error("expected error when the formula has no file path")
+	}
+}
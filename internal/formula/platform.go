@@ -0,0 +1,211 @@
+package formula
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Platform is a target operating system and CPU architecture, in GOOS and
+// GOARCH terms.
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// SupportedPlatforms are the platforms formulas are published for, in the
+// order audit and lock report them.
+var SupportedPlatforms = []Platform{
+	{OS: "darwin", Arch: "arm64"},
+	{OS: "darwin", Arch: "amd64"},
+	{OS: "linux", Arch: "amd64"},
+	{OS: "linux", Arch: "arm64"},
+}
+
+// HostPlatform returns the platform grew is running on.
+func HostPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// Key returns the platform in the os_arch form used by the url, sha256
+// and bottle maps.
+func (p Platform) Key() string {
+	return p.OS + "_" + p.Arch
+}
+
+func (p Platform) String() string {
+	return p.Key()
+}
+
+// IsARM reports whether on_arm blocks apply to p.
+func (p Platform) IsARM() bool {
+	return p.Arch == "arm64" || p.Arch == "arm"
+}
+
+// IsIntel reports whether on_intel blocks apply to p.
+func (p Platform) IsIntel() bool {
+	return p.Arch == "amd64" || p.Arch == "386"
+}
+
+// ParsePlatform parses a platform such as "linux", "macos", "darwin_arm64"
+// or "linux_x86_64". The architecture defaults to the host's when omitted.
+func ParsePlatform(s string) (Platform, error) {
+	osName, arch, _ := strings.Cut(s, "_")
+	p := Platform{Arch: runtime.GOARCH}
+	switch osName {
+	case "darwin", "macos":
+		p.OS = "darwin"
+	case "linux":
+		p.OS = "linux"
+	default:
+		return Platform{}, fmt.Errorf("unknown platform %q (expected darwin, macos or linux, optionally with _arch)", s)
+	}
+	switch arch {
+	case "":
+	case "arm64", "aarch64":
+		p.Arch = "arm64"
+	case "amd64", "x86_64":
+		p.Arch = "amd64"
+	default:
+		return Platform{}, fmt.Errorf("unknown architecture %q in platform %q (expected arm64 or amd64)", arch, s)
+	}
+	return p, nil
+}
+
+// PlatformBlock holds the fields an on_linux, on_macos, on_arm or on_intel
+// block can override. Dependency, patch and requirement lists are
+// appended to the formula's own; build fields, post_install and service
+// replace them when set.
+type PlatformBlock struct {
+	Dependencies      []string      `yaml:"dependencies"`
+	BuildDependencies []string      `yaml:"build_dependencies"`
+	Build             *BuildSpec    `yaml:"build"`
+	Patches           []PatchSpec   `yaml:"patches"`
+	PostInstall       string        `yaml:"post_install"`
+	Service           *ServiceSpec  `yaml:"service"`
+	Requirements      []Requirement `yaml:"requirements"`
+}
+
+type platformBlock struct {
+	key     string
+	block   *PlatformBlock
+	applies func(Platform) bool
+}
+
+// platformBlocks returns the conditional blocks in the order they are
+// applied: the OS blocks first, then the architecture blocks.
+func (f *Formula) platformBlocks() []platformBlock {
+	return []platformBlock{
+		{"on_macos", f.OnMacOS, func(p Platform) bool { return p.OS == "darwin" }},
+		{"on_linux", f.OnLinux, func(p Platform) bool { return p.OS == "linux" }},
+		{"on_arm", f.OnARM, Platform.IsARM},
+		{"on_intel", f.OnIntel, Platform.IsIntel},
+	}
+}
+
+// PlatformBlocks returns the conditional blocks the formula declares,
+// keyed by their YAML key.
+func (f *Formula) PlatformBlocks() map[string]*PlatformBlock {
+	blocks := make(map[string]*PlatformBlock)
+	for _, pb := range f.platformBlocks() {
+		if pb.block != nil {
+			blocks[pb.key] = pb.block
+		}
+	}
+	return blocks
+}
+
+// Platform returns the platform the formula was evaluated for. Formulas
+// that were not parsed report the host platform.
+func (f *Formula) Platform() Platform {
+	if f.platform == (Platform{}) {
+		return HostPlatform()
+	}
+	return f.platform
+}
+
+// ForPlatform returns the formula as evaluated for p: the conditional
+// blocks matching p are applied to the formula as written, not to the
+// blocks already applied for the platform f was parsed for.
+func (f *Formula) ForPlatform(p Platform) (*Formula, error) {
+	g := *f
+	if f.generic != nil {
+		g = *f.generic
+		g.generic = f.generic
+		g.Path = f.Path
+		g.choices = f.choices
+	}
+	g.applyPlatform(p)
+	if err := g.Validate(); err != nil {
+		return nil, fmt.Errorf("for %s: %w", p, err)
+	}
+	return &g, nil
+}
+
+// applyPlatform merges the blocks matching p into f. Slices are copied so
+// the formula as written is left untouched.
+func (f *Formula) applyPlatform(p Platform) {
+	f.platform = p
+	for _, pb := range f.platformBlocks() {
+		if pb.block == nil || !pb.applies(p) {
+			continue
+		}
+		b := pb.block
+		f.Dependencies = slices.Concat(f.Dependencies, b.Dependencies)
+		f.BuildDependencies = slices.Concat(f.BuildDependencies, b.BuildDependencies)
+		f.Patches = slices.Concat(f.Patches, b.Patches)
+		f.Requirements = slices.Concat(f.Requirements, b.Requirements)
+		if b.Build != nil {
+			if b.Build.System != "" {
+				f.Build.System = b.Build.System
+			}
+			if len(b.Build.Configure) > 0 {
+				f.Build.Configure = b.Build.Configure
+			}
+			if len(b.Build.Install) > 0 {
+				f.Build.Install = b.Build.Install
+			}
+		}
+		if b.PostInstall != "" {
+			f.PostInstall = b.PostInstall
+		}
+		if b.Service != nil {
+			f.Service = b.Service
+		}
+	}
+}
+
+// validatePlatformBlocks checks every block, including those that do not
+// apply to the platform being validated for.
+func (f *Formula) validatePlatformBlocks() error {
+	for _, pb := range f.platformBlocks() {
+		b := pb.block
+		if b == nil {
+			continue
+		}
+		for _, list := range []struct {
+			field   string
+			entries []string
+		}{
+			{"dependencies", b.Dependencies},
+			{"build_dependencies", b.BuildDependencies},
+		} {
+			if _, err := parseDependencies(list.entries); err != nil {
+				return fmt.Errorf("formula %q: %s.%s: %w", f.Name, pb.key, list.field, err)
+			}
+		}
+		if b.Build != nil && b.Build.System != "" {
+			if _, err := b.Build.Resolve(); err != nil {
+				return fmt.Errorf("formula %q: %s: %w", f.Name, pb.key, err)
+			}
+		}
+		if err := validatePatches(f.Name, pb.key+".patches", b.Patches); err != nil {
+			return err
+		}
+		if err := validateRequirements(f.Name, pb.key+".requirements", b.Requirements); err != nil {
+			return err
+		}
+	}
+	return nil
+}
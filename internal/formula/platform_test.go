@@ -0,0 +1,124 @@
+package formula
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const platformYAML = `
+name: testpkg
+version: "1.0"
+url:
+  darwin_arm64: "https://example.com/testpkg-darwin-arm64"
+  linux_amd64: "https://example.com/testpkg-linux-amd64"
+source:
+  url: "https://example.com/testpkg-1.0.tar.gz"
+  sha256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+install:
+  type: binary
+dependencies:
+  - zlib
+post_install: "echo generic"
+on_macos:
+  dependencies:
+    - libiconv
+  post_install: "echo macos"
+on_linux:
+  dependencies:
+    - glibc
+  build:
+    system: cmake
+on_arm:
+  build_dependencies:
+    - nasm
+`
+
+func TestParsePlatform(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Platform
+	}{
+		{"linux", Platform{OS: "linux", Arch: runtime.GOARCH}},
+		{"macos", Platform{OS: "darwin", Arch: runtime.GOARCH}},
+		{"darwin_arm64", Platform{OS: "darwin", Arch: "arm64"}},
+		{"linux_x86_64", Platform{OS: "linux", Arch: "amd64"}},
+		{"linux_aarch64", Platform{OS: "linux", Arch: "arm64"}},
+	}
+	for _, tt := range tests {
+		got, err := ParsePlatform(tt.in)
+		if err != nil {
+			t.Errorf("ParsePlatform(%q): unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePlatform(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"windows", "linux_sparc", ""} {
+		if _, err := ParsePlatform(bad); err == nil {
+			t.Errorf("ParsePlatform(%q): expected error", bad)
+		}
+	}
+}
+
+func TestParseFor_AppliesBlocks(t *testing.T) {
+	f, err := ParseFor([]byte(platformYAML), Platform{OS: "darwin", Arch: "arm64"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(f.DependencyNames(), ","); got != "zlib,libiconv" {
+		t.Errorf("darwin_arm64 dependencies = %s, want zlib,libiconv", got)
+	}
+	if got := DepNames(f.BuildDeps()); len(got) != 1 || got[0] != "nasm" {
+		t.Errorf("darwin_arm64 build dependencies = %v, want [nasm]", got)
+	}
+	if f.PostInstall != "echo macos" {
+		t.Errorf("post_install = %q, want the on_macos override", f.PostInstall)
+	}
+	if f.Build.System != "" {
+		t.Errorf("build.system = %q, on_linux must not apply on darwin", f.Build.System)
+	}
+	if u, err := f.GetURL(); err != nil || u != "https://example.com/testpkg-darwin-arm64" {
+		t.Errorf("GetURL() = %q, %v; want the darwin_arm64 URL", u, err)
+	}
+}
+
+func TestForPlatform(t *testing.T) {
+	f, err := ParseFor([]byte(platformYAML), Platform{OS: "darwin", Arch: "arm64"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	linux, err := f.ForPlatform(Platform{OS: "linux", Arch: "amd64"})
+	if err != nil {
+		t.Fatalf("ForPlatform: unexpected error: %v", err)
+	}
+	if got := strings.Join(linux.DependencyNames(), ","); got != "zlib,glibc" {
+		t.Errorf("linux_amd64 dependencies = %s, want zlib,glibc", got)
+	}
+	if len(linux.BuildDependencies) != 0 {
+		t.Errorf("on_arm must not apply on amd64, got %v", linux.BuildDependencies)
+	}
+	if linux.Build.System != "cmake" || linux.PostInstall != "echo generic" {
+		t.Errorf("unexpected linux build/post_install: %q, %q", linux.Build.System, linux.PostInstall)
+	}
+	if linux.Platform() != (Platform{OS: "linux", Arch: "amd64"}) {
+		t.Errorf("Platform() = %v, want linux_amd64", linux.Platform())
+	}
+	if got := strings.Join(f.DependencyNames(), ","); got != "zlib,libiconv" {
+		t.Errorf("ForPlatform modified the original formula: %s", got)
+	}
+}
+
+func TestParse_InvalidPlatformBlock(t *testing.T) {
+	// Blocks are validated even when they do not apply to the platform.
+	for _, block := range []string{
+		"on_linux:\n  dependencies:\n    - \"../evil\"\n",
+		"on_macos:\n  build:\n    system: scons\n",
+		"on_intel:\n  patches:\n    - url: \"http://example.com/fix.patch\"\n      sha256: \"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\"\n",
+	} {
+		if _, err := Parse([]byte(validYAML + block)); err == nil {
+			t.Errorf("expected error for block:\n%s", block)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package formula
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/homegrew/grew/internal/validation"
+)
+
+// ConflictsWith reports whether f and o cannot be installed side by side,
+// with a reason for messages. They conflict when either lists the other
+// in conflicts_with, or when both provide the same name. A formula that
+// replaces the other does not conflict with it: installing it migrates
+// the replaced one instead.
+func (f *Formula) ConflictsWith(o *Formula) (bool, string) {
+	if f.Name == o.Name || f.ReplacesName(o.Name) || o.ReplacesName(f.Name) {
+		return false, ""
+	}
+	if slices.Contains(f.Conflicts, o.Name) {
+		return true, fmt.Sprintf("%s declares conflicts_with %s", f.Name, o.Name)
+	}
+	if slices.Contains(o.Conflicts, f.Name) {
+		return true, fmt.Sprintf("%s declares conflicts_with %s", o.Name, f.Name)
+	}
+	for _, p := range f.Provides {
+		if o.ProvidesName(p) {
+			return true, fmt.Sprintf("both provide %s", p)
+		}
+	}
+	if o.ProvidesName(f.Name) {
+		return true, fmt.Sprintf("%s provides %s", o.Name, f.Name)
+	}
+	return false, ""
+}
+
+// ProvidesName reports whether f is named name or lists it in provides.
+func (f *Formula) ProvidesName(name string) bool {
+	return f.Name == name || slices.Contains(f.Provides, name)
+}
+
+// ReplacesName reports whether f lists name in replaces.
+func (f *Formula) ReplacesName(name string) bool {
+	return slices.Contains(f.Replaces, name)
+}
+
+func (f *Formula) validateRelations() error {
+	for _, list := range []struct {
+		field string
+		names []string
+	}{
+		{"conflicts_with", f.Conflicts},
+		{"provides", f.Provides},
+		{"replaces", f.Replaces},
+	} {
+		for _, n := range list.names {
+			if !validation.IsValidName(n) {
+				return fmt.Errorf("formula %q: %s: %q contains invalid characters", f.Name, list.field, n)
+			}
+			if n == f.Name {
+				return fmt.Errorf("formula %q: %s must not name the formula itself", f.Name, list.field)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package formula
+
+import "testing"
+
+func TestConflictsWith(t *testing.T) {
+	jq := &Formula{Name: "jq"}
+	gojq := &Formula{Name: "gojq", Conflicts: []string{"jq"}}
+	gawk := &Formula{Name: "gawk", Provides: []string{"awk"}}
+	mawk := &Formula{Name: "mawk", Provides: []string{"awk"}}
+	awk := &Formula{Name: "awk"}
+	jaq := &Formula{Name: "jaq", Replaces: []string{"jq"}, Conflicts: []string{"jq"}}
+
+	tests := []struct {
+		a, b *Formula
+		want bool
+	}{
+		{jq, gojq, true},
+		{gojq, jq, true},
+		{gawk, mawk, true},
+		{awk, gawk, true},
+		{jq, gawk, false},
+		{jaq, jq, false}, // replaces wins over conflicts_with
+		{jq, jq, false},
+	}
+	for _, tt := range tests {
+		got, reason := tt.a.ConflictsWith(tt.b)
+		if got != tt.want {
+			t.Errorf("%s.ConflictsWith(%s) = %v, want %v", tt.a.Name, tt.b.Name, got, tt.want)
+		}
+		if got && reason == "" {
+			t.Errorf("%s.ConflictsWith(%s): missing reason", tt.a.Name, tt.b.Name)
+		}
+	}
+}
+
+func TestParse_Relations(t *testing.T) {
+	yml := validYAML + "conflicts_with:\n  - other\nprovides:\n  - virtual\nreplaces:\n  - oldpkg\n"
+	f, err := Parse([]byte(yml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !f.ProvidesName("virtual") || !f.ProvidesName("testpkg") {
+		t.Error("formula should provide its own name and its provides entries")
+	}
+	if !f.ReplacesName("oldpkg") {
+		t.Error("expected formula to replace oldpkg")
+	}
+
+	if _, err := Parse([]byte(validYAML + "replaces:\n  - testpkg\n")); err == nil {
+		t.Error("expected error for a formula replacing itself")
+	}
+	if _, err := Parse([]byte(validYAML + "conflicts_with:\n  - \"Bad Name\"\n")); err == nil {
+		t.Error("expected error for invalid conflicts_with name")
+	}
+}
@@ -0,0 +1,118 @@
+package formula
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Requirement kinds, the keys of a requirements: entry.
+const (
+	RequireMinKernel  = "min_kernel"
+	RequireMinGlibc   = "min_glibc"
+	RequireCommand    = "command"
+	RequireCPUFeature = "cpu_feature"
+	RequireArch       = "arch"
+	RequireFileExists = "file_exists"
+)
+
+// Requirement is one entry of a formula's requirements: block: a check of
+// the machine the formula is installed on, made before anything is
+// downloaded. Each entry sets exactly one field.
+//
+//	requirements:
+//	  - min_kernel: "4.18"   # running kernel (uname -r)
+//	  - min_glibc: "2.28"    # Linux only; other hosts skip it
+//	  - command: java        # on PATH, or an absolute path
+//	  - cpu_feature: avx2    # as named in /proc/cpuinfo flags
+//	  - arch: amd64          # amd64 or arm64
+//	  - file_exists: /dev/kvm
+type Requirement struct {
+	MinKernel  string `yaml:"min_kernel"`
+	MinGlibc   string `yaml:"min_glibc"`
+	Command    string `yaml:"command"`
+	CPUFeature string `yaml:"cpu_feature"`
+	Arch       string `yaml:"arch"`
+	FileExists string `yaml:"file_exists"`
+}
+
+var (
+	minVersionRe = regexp.MustCompile(`^\d+(\.\d+)*$`)
+	cpuFeatureRe = regexp.MustCompile(`^[a-z0-9_.]+$`)
+	commandRe    = regexp.MustCompile(`^[A-Za-z0-9._+-]+$`)
+)
+
+// Kind returns the kind of the requirement and the value it checks for.
+// An entry that sets no field, or several, returns "".
+func (r Requirement) Kind() (string, string) {
+	kind, value, n := "", "", 0
+	for _, kv := range [][2]string{
+		{RequireMinKernel, r.MinKernel},
+		{RequireMinGlibc, r.MinGlibc},
+		{RequireCommand, r.Command},
+		{RequireCPUFeature, r.CPUFeature},
+		{RequireArch, r.Arch},
+		{RequireFileExists, r.FileExists},
+	} {
+		if kv[1] != "" {
+			kind, value = kv[0], kv[1]
+			n++
+		}
+	}
+	if n != 1 {
+		return "", ""
+	}
+	return kind, value
+}
+
+// String returns the requirement as written, e.g. "min_kernel: 4.18".
+func (r Requirement) String() string {
+	kind, value := r.Kind()
+	return kind + ": " + value
+}
+
+// Validate checks that exactly one field is set and that its value is
+// well formed.
+func (r Requirement) Validate() error {
+	kind, value := r.Kind()
+	switch kind {
+	case "":
+		return fmt.Errorf("each entry needs exactly one of %s", strings.Join(RequirementKinds(), ", "))
+	case RequireMinKernel, RequireMinGlibc:
+		if !minVersionRe.MatchString(value) {
+			return fmt.Errorf("%s: %q is not a version such as 2.28", kind, value)
+		}
+	case RequireCommand:
+		if !filepath.IsAbs(value) && !commandRe.MatchString(value) {
+			return fmt.Errorf("%s: %q must be a command name or an absolute path", kind, value)
+		}
+	case RequireCPUFeature:
+		if !cpuFeatureRe.MatchString(value) {
+			return fmt.Errorf("%s: %q must be a lower-case feature name such as avx2", kind, value)
+		}
+	case RequireArch:
+		if value != "amd64" && value != "arm64" {
+			return fmt.Errorf("%s: unknown architecture %q (expected amd64 or arm64)", kind, value)
+		}
+	case RequireFileExists:
+		if !filepath.IsAbs(value) {
+			return fmt.Errorf("%s: %q must be an absolute path", kind, value)
+		}
+	}
+	return nil
+}
+
+// RequirementKinds returns the accepted requirement kinds.
+func RequirementKinds() []string {
+	return []string{RequireArch, RequireCommand, RequireCPUFeature, RequireFileExists, RequireMinGlibc, RequireMinKernel}
+}
+
+func validateRequirements(name, field string, reqs []Requirement) error {
+	for i, r := range reqs {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("formula %q: %s[%d]: %w", name, field, i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package formula
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse_Requirements(t *testing.T) {
+	yml := `
+name: testpkg
+version: "1.0"
+url:
+  linux_amd64: "https://example.com/testpkg"
+install:
+  type: binary
+requirements:
+  - min_kernel: "4.18"
+  - command: java
+on_linux:
+  requirements:
+    - min_glibc: "2.28"
+`
+	f, err := ParseFor([]byte(yml), Platform{OS: "linux", Arch: "amd64"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, r := range f.Requirements {
+		got = append(got, r.String())
+	}
+	if strings.Join(got, ", ") != "min_kernel: 4.18, command: java, min_glibc: 2.28" {
+		t.Errorf("requirements = %v", got)
+	}
+
+	mac, err := ParseFor([]byte(yml), Platform{OS: "darwin", Arch: "arm64"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mac.Requirements) != 2 {
+		t.Errorf("on_linux requirements should not apply on macOS: %v", mac.Requirements)
+	}
+}
+
+func TestRequirement_Validate(t *testing.T) {
+	tests := []struct {
+		req   Requirement
+		valid bool
+	}{
+		{Requirement{MinKernel: "5.4"}, true},
+		{Requirement{MinGlibc: "2.28.1"}, true},
+		{Requirement{Command: "java"}, true},
+		{Requirement{Command: "/usr/bin/java"}, true},
+		{Requirement{CPUFeature: "avx2"}, true},
+		{Requirement{Arch: "arm64"}, true},
+		{Requirement{FileExists: "/dev/kvm"}, true},
+		{Requirement{}, false},
+		{Requirement{MinKernel: "5.4", Command: "java"}, false},
+		{Requirement{MinKernel: "latest"}, false},
+		{Requirement{Command: "bin/java"}, false},
+		{Requirement{CPUFeature: "AVX2"}, false},
+		{Requirement{Arch: "x86"}, false},
+		{Requirement{FileExists: "dev/kvm"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.req.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v: Validate() = %v, want valid %v", tt.req, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package formula
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/homegrew/grew/internal/validation"
+	"github.com/homegrew/grew/internal/vercmp"
+)
+
+// Versioned formulas are named <name>@<version>, e.g. openssl@3 or
+// python@3.12. Together with the unversioned <name> formula, if there is
+// one, they form a family: alternative versions of one package that are
+// installed side by side but linked one at a time. The name helpers
+// (FamilyName, FamilyVersion, SameFamily) live in package validation.
+
+// IsVersioned reports whether f is a versioned formula (name@version).
+func (f *Formula) IsVersioned() bool {
+	return validation.FamilyVersion(f.Name) != ""
+}
+
+// Family returns every formula of the family name belongs to: the
+// unversioned formula first, then the versioned ones, newest first. When
+// several taps have a formula of the same name, the first one wins.
+func (l *Loader) Family(name string) ([]*Formula, error) {
+	names, err := l.familyNames(name)
+	if err != nil {
+		return nil, err
+	}
+	var members []*Formula
+	for _, n := range names {
+		f, err := l.loadExact(n)
+		if err != nil {
+			l.debugf("failed to load %s: %v\n", n, err)
+			continue
+		}
+		members = append(members, f)
+	}
+	return members, nil
+}
+
+// familyNames lists the formula names of the family name belongs to, in
+// Family order. Only file names are read: <family>.yaml and
+// <family>@*.yaml in each tap, plus formulas loaded with LoadFile or Use.
+func (l *Loader) familyNames(name string) ([]string, error) {
+	family := validation.FamilyName(name)
+	if !validation.IsValidName(family) {
+		return nil, nil
+	}
+	dirs, err := l.tapDirs()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var names []string
+	add := func(n string) {
+		if validation.FamilyName(n) == family && !seen[n] {
+			seen[n] = true
+			names = append(names, n)
+		}
+	}
+	for _, n := range sortedKeys(l.overrides) {
+		add(n)
+	}
+	for _, n := range sortedKeys(l.files) {
+		add(n)
+	}
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, family+".yaml")); err == nil {
+			add(family)
+		}
+		matches, _ := filepath.Glob(filepath.Join(dir, family+"@*.yaml"))
+		for _, m := range matches {
+			add(strings.TrimSuffix(filepath.Base(m), ".yaml"))
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		vi, vj := validation.FamilyVersion(names[i]), validation.FamilyVersion(names[j])
+		if vi == "" || vj == "" {
+			return vi == "" && vj != ""
+		}
+		return vercmp.Compare(vi, vj) > 0
+	})
+	return names, nil
+}
+
+// loadFamilyMember returns the newest versioned formula of the family an
+// unversioned name with no formula of its own refers to, so a dependency
+// on openssl is satisfied by openssl@3. It returns a *notFoundError when
+// the family has no versioned member.
+func (l *Loader) loadFamilyMember(name string) (*Formula, error) {
+	if validation.FamilyVersion(name) == "" {
+		names, err := l.familyNames(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range names {
+			if validation.FamilyVersion(n) != "" {
+				l.debugf("%s is satisfied by %s\n", name, n)
+				return l.loadExact(n)
+			}
+		}
+	}
+	return nil, &notFoundError{name: name}
+}
@@ -0,0 +1,69 @@
+package formula
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoader_Family(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	for _, name := range []string{"python@3.9", "python@3.12", "python", "pythonista", "ruby@3"} {
+		writeTestFormula(t, tapDir, name)
+	}
+
+	loader := &Loader{TapDir: tmpDir}
+	members, err := loader.Family("python@3.9")
+	if err != nil {
+		t.Fatalf("Family: %v", err)
+	}
+	var names []string
+	for _, f := range members {
+		names = append(names, f.Name)
+	}
+	want := []string{"python", "python@3.12", "python@3.9"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Family = %v, want %v", names, want)
+	}
+}
+
+func TestLoadByName_FamilyMember(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeTestFormula(t, tapDir, "openssl@1.1")
+	writeTestFormula(t, tapDir, "openssl@3")
+
+	loader := &Loader{TapDir: tmpDir}
+	f, err := loader.LoadByName("openssl")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.Name != "openssl@3" {
+		t.Errorf("LoadByName(openssl).Name = %q, want openssl@3", f.Name)
+	}
+	if _, err := loader.LoadByName("openssl@2"); err == nil {
+		t.Error("expected error for a missing versioned formula")
+	}
+}
+
+func TestLoadByName_BrokenFormulaNotReplacedByFamilyMember(t *testing.T) {
+	tmpDir := t.TempDir()
+	tapDir := filepath.Join(tmpDir, "core")
+	os.MkdirAll(tapDir, 0755)
+	writeTestFormula(t, tapDir, "openssl@3")
+	os.WriteFile(filepath.Join(tapDir, "openssl.yaml"), []byte("name: openssl\nversion: [\n"), 0644)
+
+	loader := &Loader{TapDir: tmpDir}
+	f, err := loader.LoadByName("openssl")
+	if err == nil {
+		t.Fatalf("expected the parse error of openssl.yaml, got %s", f.Name)
+	}
+	if !strings.Contains(err.Error(), "openssl.yaml") {
+		t.Errorf("error should name the broken file, got: %v", err)
+	}
+}
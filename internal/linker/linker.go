@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/homegrew/grew/internal/config"
+	"github.com/homegrew/grew/internal/snapshot"
 	"github.com/homegrew/grew/internal/validation"
 )
 
@@ -22,60 +23,107 @@ type LinkOpts struct {
 	Force     bool
 }
 
-func (l *Linker) Link(name, version string, kegOnly bool) error {
+func (l *Linker) Link(name, version string, kegOnly bool) ([]snapshot.LinkEntry, error) {
 	return l.LinkWithOpts(name, version, LinkOpts{KegOnly: kegOnly})
 }
 
-func (l *Linker) LinkWithOpts(name, version string, opts LinkOpts) error {
+// LinkWithOpts links a keg into the prefix and returns the symlinks it
+// created, the opt link first. They are recorded in the keg's manifest,
+// when it has one, so that unlinking removes exactly those.
+func (l *Linker) LinkWithOpts(name, version string, opts LinkOpts) ([]snapshot.LinkEntry, error) {
 	if !validation.IsValidName(name) || !validation.IsValidVersion(version) {
-		return fmt.Errorf("invalid formula name or version")
+		return nil, fmt.Errorf("invalid formula name or version")
 	}
 
 	kegPath := filepath.Join(l.Paths.Cellar, name, version)
+	created, err := l.link(name, kegPath, opts)
+	if opts.DryRun {
+		return nil, err
+	}
+	links := make([]snapshot.LinkEntry, len(created))
+	for i, lk := range created {
+		links[i] = l.linkEntry(lk)
+	}
+	// Record even a partial link, so unlink can undo it.
+	if serr := snapshot.SaveLinks(kegPath, links); serr != nil && err == nil {
+		err = fmt.Errorf("record links in manifest: %w", serr)
+	}
+	return links, err
+}
+
+func (l *Linker) link(name, kegPath string, opts LinkOpts) ([]kegLink, error) {
+	var created []kegLink
 
 	// Verify keg exists and resolves within the cellar.
 	realKeg, err := filepath.EvalSymlinks(kegPath)
 	if err != nil {
-		return fmt.Errorf("keg not found: %s", kegPath)
+		return nil, fmt.Errorf("keg not found: %s", kegPath)
 	}
 	realCellar, err := filepath.EvalSymlinks(l.Paths.Cellar)
 	if err != nil {
-		return fmt.Errorf("cellar path invalid: %w", err)
+		return nil, fmt.Errorf("cellar path invalid: %w", err)
 	}
 	if !strings.HasPrefix(realKeg, realCellar+string(filepath.Separator)) {
-		return fmt.Errorf("keg %s resolves outside cellar: %s", kegPath, realKeg)
+		return nil, fmt.Errorf("keg %s resolves outside cellar: %s", kegPath, realKeg)
 	}
 
-	// Always create opt symlink
+	// Always create opt symlink. It is replaced in one rename, so it never
+	// goes missing while another version is switched in.
 	optLink := filepath.Join(l.Paths.Opt, name)
+	previous := l.linkedKeg(name)
 	if opts.DryRun {
 		fmt.Printf("Would link: %s -> %s\n", optLink, kegPath)
 	} else {
-		os.Remove(optLink)
-		if err := os.Symlink(kegPath, optLink); err != nil {
-			return fmt.Errorf("create opt link: %w", err)
+		tmpLink := optLink + ".tmp"
+		os.Remove(tmpLink)
+		if err := os.Symlink(kegPath, tmpLink); err != nil {
+			return nil, fmt.Errorf("create opt link: %w", err)
 		}
+		if err := os.Rename(tmpLink, optLink); err != nil {
+			os.Remove(tmpLink)
+			return nil, fmt.Errorf("create opt link: %w", err)
+		}
+		created = append(created, kegLink{path: optLink, target: kegPath})
+	}
+
+	// Linking another version of the formula takes the place of the one
+	// that was linked.
+	if previous != "" && previous != kegPath {
+		l.unlinkKeg(name, previous, opts.DryRun)
 	}
 
 	if opts.KegOnly && !opts.Force {
-		return nil
+		return created, nil
 	}
 
-	subdirs := []struct {
-		src  string
-		dest string
-	}{
-		{filepath.Join(kegPath, "bin"), l.Paths.Bin},
-		{filepath.Join(kegPath, "lib"), l.Paths.Lib},
-		{filepath.Join(kegPath, "include"), l.Paths.Include},
+	// Only one member of a versioned family (openssl, openssl@3, ...) is
+	// linked at a time. With Overwrite the linked one makes way.
+	if sibling := l.LinkedSibling(name); sibling != "" {
+		if !opts.Overwrite {
+			return created, &FamilyConflictError{Name: name, Linked: sibling}
+		}
+		l.unlinkKeg(sibling, l.linkedKeg(sibling), opts.DryRun)
 	}
 
-	for _, sd := range subdirs {
-		if err := linkDirWithOpts(sd.src, sd.dest, l.Paths.Cellar, name, opts); err != nil {
-			return err
+	// Each keg directory is linked into the prefix directory of the same
+	// name; share/ carries man pages and zsh and fish completions, etc/
+	// bash completions.
+	for _, dest := range l.Paths.LinkDirs() {
+		src := filepath.Join(kegPath, filepath.Base(dest))
+		if err := linkDirWithOpts(src, dest, l.Paths.Cellar, name, opts, &created); err != nil {
+			return created, err
 		}
 	}
-	return nil
+	return created, nil
+}
+
+// linkEntry records lk for the manifest, relative to the prefix.
+func (l *Linker) linkEntry(lk kegLink) snapshot.LinkEntry {
+	src, err := filepath.Rel(l.Paths.Root, lk.path)
+	if err != nil {
+		src = lk.path
+	}
+	return snapshot.LinkEntry{Src: src, Target: lk.target}
 }
 
 // UnlinkOpts controls unlink behavior.
@@ -93,6 +141,7 @@ func (l *Linker) UnlinkWithOpts(name string, opts UnlinkOpts) error {
 	}
 
 	optLink := filepath.Join(l.Paths.Opt, name)
+	kegPath := l.linkedKeg(name)
 	if opts.DryRun {
 		if target, err := os.Readlink(optLink); err == nil {
 			fmt.Printf("Would unlink: %s -> %s\n", optLink, target)
@@ -101,31 +150,248 @@ func (l *Linker) UnlinkWithOpts(name string, opts UnlinkOpts) error {
 		os.Remove(optLink)
 	}
 
+	l.unlinkKeg(name, kegPath, opts.DryRun)
+	return nil
+}
+
+// unlinkKeg removes the links into name's keg at kegPath from the link
+// directories, leaving its opt link alone, and prunes shared directories
+// left empty. The links recorded in the keg's manifest are removed, as
+// long as they still point into the keg; a keg with none recorded, such
+// as one installed before links were recorded, is found by scanning.
+func (l *Linker) unlinkKeg(name, kegPath string, dryRun bool) {
+	optLink := filepath.Join(l.Paths.Opt, name)
+	var m *snapshot.Manifest
+	if kegPath != "" {
+		m, _ = snapshot.Load(kegPath)
+	}
+
+	var links []kegLink
+	if m != nil && len(m.Links) > 0 {
+		for _, e := range m.Links {
+			path := filepath.Join(l.Paths.Root, e.Src)
+			if path == optLink {
+				continue
+			}
+			if target, err := os.Readlink(path); err == nil && resolveLink(filepath.Dir(path), target) == e.Target {
+				links = append(links, kegLink{path: path, target: e.Target})
+			}
+		}
+	} else {
+		links = l.kegLinks(name)
+	}
+
+	for _, lk := range links {
+		if dryRun {
+			fmt.Printf("Would unlink: %s -> %s\n", lk.path, lk.target)
+			continue
+		}
+		os.Remove(lk.path)
+		l.pruneDirs(filepath.Dir(lk.path))
+	}
+	if dryRun || m == nil {
+		return
+	}
+
+	// Only the opt link, if it is still there, remains linked.
+	var kept []snapshot.LinkEntry
+	if l.linkedKeg(name) == kegPath {
+		kept = append(kept, l.linkEntry(kegLink{path: optLink, target: kegPath}))
+	}
+	snapshot.SaveLinks(kegPath, kept)
+}
+
+// linkedKeg returns the keg name's opt link points to, or "" when it has
+// none.
+func (l *Linker) linkedKeg(name string) string {
+	target, err := os.Readlink(filepath.Join(l.Paths.Opt, name))
+	if err != nil {
+		return ""
+	}
+	return resolveLink(l.Paths.Opt, target)
+}
+
+// pruneDirs removes dir and then its parents while they are empty,
+// stopping at the link directories themselves.
+func (l *Linker) pruneDirs(dir string) {
+	stop := map[string]bool{l.Paths.Root: true}
+	for _, d := range l.Paths.LinkDirs() {
+		stop[d] = true
+	}
+	for !stop[dir] && strings.HasPrefix(dir, l.Paths.Root+string(filepath.Separator)) {
+		if os.Remove(dir) != nil {
+			return // not empty
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+type kegLink struct {
+	path   string // the symlink
+	target string // resolved target inside the cellar
+}
+
+// kegLinks returns the symlinks in the link directories, including
+// shared subdirectories such as lib/pkgconfig and share/man/man1, that
+// point into name's cellar directory.
+func (l *Linker) kegLinks(name string) []kegLink {
 	cellarPrefix := filepath.Join(l.Paths.Cellar, name) + string(filepath.Separator)
-	dirs := []string{l.Paths.Bin, l.Paths.Lib, l.Paths.Include}
 
-	for _, dir := range dirs {
-		entries, err := os.ReadDir(dir)
+	var links []kegLink
+	for _, dir := range l.Paths.LinkDirs() {
+		WalkLinks(dir, func(path, target string) {
+			if strings.HasPrefix(target, cellarPrefix) {
+				links = append(links, kegLink{path: path, target: target})
+			}
+		})
+	}
+	return links
+}
+
+// WalkLinks calls fn for every symlink below dir, descending into real
+// subdirectories but not through symlinked ones, with the link's target
+// made absolute. A missing dir is skipped.
+func WalkLinks(dir string, fn func(path, target string)) {
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.Type()&os.ModeSymlink == 0 {
+			return nil
+		}
+		target, err := os.Readlink(path)
 		if err != nil {
-			continue // dir may not exist
+			return nil
 		}
-		for _, e := range entries {
-			fullPath := filepath.Join(dir, e.Name())
-			target, err := os.Readlink(fullPath)
-			if err != nil {
-				continue // not a symlink
-			}
-			resolved := resolveLink(dir, target)
-			if strings.HasPrefix(resolved, cellarPrefix) {
-				if opts.DryRun {
-					fmt.Printf("Would unlink: %s -> %s\n", fullPath, resolved)
-				} else {
-					os.Remove(fullPath)
-				}
+		fn(path, resolveLink(filepath.Dir(path), target))
+		return nil
+	})
+}
+
+// Conflict is a path a keg would link that is already taken.
+type Conflict struct {
+	Path   string // relative to the prefix, e.g. "bin/jq"
+	Owner  string // formula whose keg the path links into, if any
+	Target string // where a symlink not made by grew points
+}
+
+func (c Conflict) String() string {
+	switch {
+	case c.Owner != "":
+		return fmt.Sprintf("%s is linked by %s", c.Path, c.Owner)
+	case c.Target != "":
+		return fmt.Sprintf("%s is a symlink to %s, not made by grew", c.Path, c.Target)
+	}
+	return fmt.Sprintf("%s already exists and is not a symlink", c.Path)
+}
+
+// Conflicts predicts what linking name's keg would run into: of files,
+// keg-relative paths such as bin/jq, it returns those whose place in the
+// prefix is taken by something other than a link into name's own kegs.
+// Paths outside the link directories are never linked and are ignored.
+func (l *Linker) Conflicts(name string, files []string) []Conflict {
+	cellarPrefix := l.Paths.Cellar + string(filepath.Separator)
+
+	var conflicts []Conflict
+	for _, file := range files {
+		if !l.Linkable(file) {
+			continue
+		}
+		path := filepath.Join(l.Paths.Root, file)
+		info, err := os.Lstat(path)
+		if err != nil {
+			continue
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			conflicts = append(conflicts, Conflict{Path: file})
+			continue
+		}
+		target, _ := os.Readlink(path)
+		resolved := resolveLink(filepath.Dir(path), target)
+		rel, ok := strings.CutPrefix(resolved, cellarPrefix)
+		if !ok {
+			conflicts = append(conflicts, Conflict{Path: file, Target: resolved})
+			continue
+		}
+		if owner, _, _ := strings.Cut(rel, string(filepath.Separator)); owner != name {
+			conflicts = append(conflicts, Conflict{Path: file, Owner: owner})
+		}
+	}
+	return conflicts
+}
+
+// Linkable reports whether the keg-relative path file is in one of the
+// directories linked into the prefix.
+func (l *Linker) Linkable(file string) bool {
+	top, rest, _ := strings.Cut(file, "/")
+	if rest == "" {
+		return false
+	}
+	for _, d := range l.Paths.LinkDirs() {
+		if filepath.Base(d) == top {
+			return true
+		}
+	}
+	return false
+}
+
+// FamilyConflictError reports that another member of a versioned formula
+// family is already linked.
+type FamilyConflictError struct {
+	Name   string // formula being linked
+	Linked string // family member that is linked
+}
+
+func (e *FamilyConflictError) Error() string {
+	return fmt.Sprintf("cannot link %s: %s is linked, and only one version of %s can be linked at a time (use --overwrite to link %s in its place)",
+		e.Name, e.Linked, validation.FamilyName(e.Name), e.Name)
+}
+
+// LinkedSibling returns the other member of name's versioned family
+// (e.g. openssl@3 for openssl@1.1) whose keg is linked into the prefix,
+// or "" if there is none.
+func (l *Linker) LinkedSibling(name string) string {
+	return l.LinkedFamilies().Sibling(name)
+}
+
+// LinkedFamilies maps family names to the members of the family whose
+// kegs are linked into the prefix.
+type LinkedFamilies map[string][]string
+
+// LinkedFamilies returns the formulas in opt whose kegs are linked into
+// the prefix, grouped by family. It walks the link directories once, so
+// checking many formulas against it is cheaper than calling LinkedSibling
+// for each.
+func (l *Linker) LinkedFamilies() LinkedFamilies {
+	entries, err := os.ReadDir(l.Paths.Opt)
+	if err != nil {
+		return nil
+	}
+	cellarPrefix := filepath.Clean(l.Paths.Cellar) + string(filepath.Separator)
+	linked := make(map[string]bool)
+	for _, dir := range l.Paths.LinkDirs() {
+		WalkLinks(dir, func(_, target string) {
+			if rest, ok := strings.CutPrefix(target, cellarPrefix); ok {
+				name, _, _ := strings.Cut(rest, string(filepath.Separator))
+				linked[name] = true
 			}
+		})
+	}
+	families := make(LinkedFamilies)
+	for _, e := range entries {
+		if linked[e.Name()] {
+			family := validation.FamilyName(e.Name())
+			families[family] = append(families[family], e.Name())
 		}
 	}
-	return nil
+	return families
+}
+
+// Sibling returns the other linked member of name's family, or "".
+func (f LinkedFamilies) Sibling(name string) string {
+	for _, member := range f[validation.FamilyName(name)] {
+		if member != name {
+			return member
+		}
+	}
+	return ""
 }
 
 func (l *Linker) IsLinked(name string) bool {
@@ -160,7 +426,9 @@ func destIsDir(destPath string) bool {
 	return err == nil && fi.IsDir()
 }
 
-func linkDirWithOpts(srcDir, destDir, cellarPath, formulaName string, opts LinkOpts) error {
+// linkDirWithOpts links the entries of srcDir into destDir, recursing into
+// shared subdirectories, and appends each symlink it creates to created.
+func linkDirWithOpts(srcDir, destDir, cellarPath, formulaName string, opts LinkOpts, created *[]kegLink) error {
 	entries, err := os.ReadDir(srcDir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -168,6 +436,11 @@ func linkDirWithOpts(srcDir, destDir, cellarPath, formulaName string, opts LinkO
 		}
 		return fmt.Errorf("read %s: %w", srcDir, err)
 	}
+	if len(entries) > 0 && !opts.DryRun {
+		if err := os.MkdirAll(destDir, 0755); err != nil {
+			return fmt.Errorf("create %s: %w", destDir, err)
+		}
+	}
 
 	for _, e := range entries {
 		srcPath := filepath.Join(srcDir, e.Name())
@@ -204,7 +477,7 @@ func linkDirWithOpts(srcDir, destDir, cellarPath, formulaName string, opts LinkO
 					return fmt.Errorf("create shared dir %s: %w", destPath, err)
 				}
 			}
-			if err := linkDirWithOpts(srcPath, destPath, cellarPath, formulaName, opts); err != nil {
+			if err := linkDirWithOpts(srcPath, destPath, cellarPath, formulaName, opts, created); err != nil {
 				return err
 			}
 			continue
@@ -246,6 +519,7 @@ func linkDirWithOpts(srcDir, destDir, cellarPath, formulaName string, opts LinkO
 			if err := os.Symlink(srcPath, destPath); err != nil {
 				return fmt.Errorf("symlink %s -> %s: %w", destPath, srcPath, err)
 			}
+			*created = append(*created, kegLink{path: destPath, target: srcPath})
 		}
 	}
 	return nil
@@ -1,24 +1,20 @@
 package linker
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/homegrew/grew/internal/config"
+	"github.com/homegrew/grew/internal/snapshot"
 )
 
 func setupTestLinker(t *testing.T) (*Linker, config.Paths) {
 	t.Helper()
 	tmpDir := t.TempDir()
-	paths := config.Paths{
-		Root:    tmpDir,
-		Cellar:  filepath.Join(tmpDir, "Cellar"),
-		Opt:     filepath.Join(tmpDir, "opt"),
-		Bin:     filepath.Join(tmpDir, "bin"),
-		Lib:     filepath.Join(tmpDir, "lib"),
-		Include: filepath.Join(tmpDir, "include"),
-	}
+	paths := config.FromRoot(tmpDir, filepath.Join(tmpDir, "Applications"))
 	for _, d := range []string{paths.Cellar, paths.Opt, paths.Bin, paths.Lib, paths.Include} {
 		os.MkdirAll(d, 0755)
 	}
@@ -36,7 +32,7 @@ func TestLink_CreatesSymlinks(t *testing.T) {
 	lnk, paths := setupTestLinker(t)
 	createTestKeg(t, paths.Cellar, "mypkg", "1.0.0")
 
-	if err := lnk.Link("mypkg", "1.0.0", false); err != nil {
+	if _, err := lnk.Link("mypkg", "1.0.0", false); err != nil {
 		t.Fatalf("link failed: %v", err)
 	}
 
@@ -67,7 +63,7 @@ func TestLink_KegOnly(t *testing.T) {
 	lnk, paths := setupTestLinker(t)
 	createTestKeg(t, paths.Cellar, "mypkg", "1.0.0")
 
-	if err := lnk.Link("mypkg", "1.0.0", true); err != nil {
+	if _, err := lnk.Link("mypkg", "1.0.0", true); err != nil {
 		t.Fatalf("link failed: %v", err)
 	}
 
@@ -99,6 +95,141 @@ func TestUnlink_RemovesSymlinks(t *testing.T) {
 	}
 }
 
+func TestLink_ShareAndCompletions(t *testing.T) {
+	lnk, paths := setupTestLinker(t)
+	createTestKeg(t, paths.Cellar, "mypkg", "1.0.0")
+	createTestKeg(t, paths.Cellar, "other", "2.0")
+	keg := filepath.Join(paths.Cellar, "mypkg", "1.0.0")
+	files := []string{
+		"sbin/mypkgd",
+		"etc/bash_completion.d/mypkg",
+		"share/man/man1/mypkg.1",
+		"share/zsh/site-functions/_mypkg",
+		"share/fish/vendor_completions.d/mypkg.fish",
+	}
+	for _, f := range files {
+		os.MkdirAll(filepath.Dir(filepath.Join(keg, f)), 0755)
+		os.WriteFile(filepath.Join(keg, f), []byte("x"), 0644)
+	}
+	otherMan := filepath.Join(paths.Cellar, "other", "2.0", "share", "man", "man1")
+	os.MkdirAll(otherMan, 0755)
+	os.WriteFile(filepath.Join(otherMan, "other.1"), []byte("x"), 0644)
+
+	if _, err := lnk.Link("other", "2.0", false); err != nil {
+		t.Fatalf("link other: %v", err)
+	}
+	if _, err := lnk.Link("mypkg", "1.0.0", false); err != nil {
+		t.Fatalf("link mypkg: %v", err)
+	}
+	for _, f := range files {
+		target, err := os.Readlink(filepath.Join(paths.Root, f))
+		if err != nil || target != filepath.Join(keg, f) {
+			t.Errorf("%s -> %q (%v), want a link into the keg", f, target, err)
+		}
+	}
+	if info, err := os.Lstat(paths.Man); err != nil || !info.IsDir() {
+		t.Fatalf("share/man should be a real shared directory")
+	}
+	if _, err := os.Lstat(filepath.Join(paths.ZshCompletion, "_mypkg")); err != nil {
+		t.Errorf("zsh completion not linked: %v", err)
+	}
+
+	if err := lnk.Unlink("mypkg"); err != nil {
+		t.Fatalf("unlink: %v", err)
+	}
+	for _, f := range files {
+		if _, err := os.Lstat(filepath.Join(paths.Root, f)); err == nil {
+			t.Errorf("%s should be unlinked", f)
+		}
+	}
+	if _, err := os.Readlink(filepath.Join(paths.Man, "man1", "other.1")); err != nil {
+		t.Errorf("other's man page should stay linked: %v", err)
+	}
+}
+
+func TestLink_RecordsLinksInManifest(t *testing.T) {
+	lnk, paths := setupTestLinker(t)
+	createTestKeg(t, paths.Cellar, "mypkg", "1.0.0")
+	keg := filepath.Join(paths.Cellar, "mypkg", "1.0.0")
+	pc := filepath.Join(keg, "lib", "pkgconfig", "mypkg.pc")
+	os.MkdirAll(filepath.Dir(pc), 0755)
+	os.WriteFile(pc, []byte("x"), 0644)
+	snapshot.Save(&snapshot.Manifest{Name: "mypkg", Version: "1.0.0"}, keg)
+
+	links, err := lnk.Link("mypkg", "1.0.0", false)
+	if err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	want := []snapshot.LinkEntry{
+		{Src: "opt/mypkg", Target: keg},
+		{Src: "bin/mypkg", Target: filepath.Join(keg, "bin", "mypkg")},
+		{Src: "lib/pkgconfig/mypkg.pc", Target: pc},
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("links = %v, want %v", links, want)
+	}
+	if m, _ := snapshot.Load(keg); m == nil || !reflect.DeepEqual(m.Links, want) {
+		t.Errorf("manifest links = %v, want %v", m, want)
+	}
+
+	if err := lnk.Unlink("mypkg"); err != nil {
+		t.Fatalf("unlink: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(paths.Lib, "pkgconfig")); !os.IsNotExist(err) {
+		t.Error("empty lib/pkgconfig should be pruned")
+	}
+	if _, err := os.Stat(paths.Lib); err != nil {
+		t.Error("lib/ itself should stay")
+	}
+	if m, _ := snapshot.Load(keg); m == nil || len(m.Links) != 0 {
+		t.Errorf("manifest should record no links after unlink, got %v", m)
+	}
+}
+
+func TestUnlink_LeavesHijackedLinks(t *testing.T) {
+	lnk, paths := setupTestLinker(t)
+	createTestKeg(t, paths.Cellar, "mypkg", "1.0.0")
+	keg := filepath.Join(paths.Cellar, "mypkg", "1.0.0")
+	snapshot.Save(&snapshot.Manifest{Name: "mypkg", Version: "1.0.0"}, keg)
+	if _, err := lnk.Link("mypkg", "1.0.0", false); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+
+	binLink := filepath.Join(paths.Bin, "mypkg")
+	os.Remove(binLink)
+	os.Symlink("/usr/bin/true", binLink)
+
+	if err := lnk.Unlink("mypkg"); err != nil {
+		t.Fatalf("unlink: %v", err)
+	}
+	if target, err := os.Readlink(binLink); err != nil || target != "/usr/bin/true" {
+		t.Errorf("a link taken over by something else should be left alone, got %q (%v)", target, err)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	lnk, paths := setupTestLinker(t)
+	createTestKeg(t, paths.Cellar, "mypkg", "1.0.0")
+	createTestKeg(t, paths.Cellar, "other", "2.0")
+	lnk.Link("mypkg", "1.0.0", false)
+	lnk.Link("other", "2.0", false)
+	os.WriteFile(filepath.Join(paths.Bin, "stray"), []byte("x"), 0755)
+	os.Symlink("/usr/bin/true", filepath.Join(paths.Bin, "foreign"))
+
+	got := lnk.Conflicts("mypkg", []string{"bin/mypkg", "bin/other", "bin/stray", "bin/foreign", "bin/free", "README"})
+	want := []Conflict{
+		{Path: "bin/other", Owner: "other"},
+		{Path: "bin/stray"},
+		{Path: "bin/foreign", Target: "/usr/bin/true"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Conflicts = %v, want %v", got, want)
+	}
+	if s := got[0].String(); s != "bin/other is linked by other" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
 func TestIsLinked(t *testing.T) {
 	lnk, paths := setupTestLinker(t)
 	createTestKeg(t, paths.Cellar, "mypkg", "1.0.0")
@@ -122,8 +253,97 @@ func TestLink_ConflictDetection(t *testing.T) {
 
 	createTestKeg(t, paths.Cellar, "mypkg", "1.0.0")
 
-	err := lnk.Link("mypkg", "1.0.0", false)
+	_, err := lnk.Link("mypkg", "1.0.0", false)
 	if err == nil {
 		t.Fatal("expected conflict error")
 	}
 }
+
+func TestLink_FamilyConflict(t *testing.T) {
+	lnk, paths := setupTestLinker(t)
+	createTestKeg(t, paths.Cellar, "tool@1", "1.4")
+	createTestKeg(t, paths.Cellar, "tool@2", "2.0")
+
+	if _, err := lnk.Link("tool@2", "2.0", false); err != nil {
+		t.Fatalf("link tool@2: %v", err)
+	}
+	if got := lnk.LinkedSibling("tool@1"); got != "tool@2" {
+		t.Fatalf("LinkedSibling(tool@1) = %q, want tool@2", got)
+	}
+
+	_, err := lnk.Link("tool@1", "1.4", false)
+	var fc *FamilyConflictError
+	if !errors.As(err, &fc) || fc.Linked != "tool@2" {
+		t.Fatalf("expected a family conflict with tool@2, got %v", err)
+	}
+
+	if _, err := lnk.LinkWithOpts("tool@1", "1.4", LinkOpts{Overwrite: true}); err != nil {
+		t.Fatalf("link --overwrite: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(paths.Bin, "tool@2")); err == nil {
+		t.Error("tool@2 should be unlinked from bin/")
+	}
+	if _, err := os.Readlink(filepath.Join(paths.Opt, "tool@2")); err != nil {
+		t.Error("tool@2 should keep its opt link")
+	}
+	if _, err := os.Readlink(filepath.Join(paths.Bin, "tool@1")); err != nil {
+		t.Error("tool@1 should be linked into bin/")
+	}
+}
+
+func TestLinkedFamilies(t *testing.T) {
+	lnk, paths := setupTestLinker(t)
+	createTestKeg(t, paths.Cellar, "tool@1", "1.4")
+	createTestKeg(t, paths.Cellar, "tool@2", "2.0")
+	createTestKeg(t, paths.Cellar, "other", "1.0")
+
+	if _, err := lnk.Link("tool@2", "2.0", false); err != nil {
+		t.Fatalf("link tool@2: %v", err)
+	}
+	if _, err := lnk.Link("tool@1", "1.4", true); err != nil {
+		t.Fatalf("link tool@1 keg-only: %v", err)
+	}
+	if _, err := lnk.Link("other", "1.0", false); err != nil {
+		t.Fatalf("link other: %v", err)
+	}
+
+	families := lnk.LinkedFamilies()
+	if got := families["tool"]; len(got) != 1 || got[0] != "tool@2" {
+		t.Errorf("tool family = %v, want only tool@2 (tool@1 is keg-only)", got)
+	}
+	tests := map[string]string{"tool@1": "tool@2", "tool": "tool@2", "tool@2": "", "other": ""}
+	for name, want := range tests {
+		if got := families.Sibling(name); got != want {
+			t.Errorf("Sibling(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLink_SwitchesVersion(t *testing.T) {
+	lnk, paths := setupTestLinker(t)
+	createTestKeg(t, paths.Cellar, "mypkg", "1.0.0")
+	createTestKeg(t, paths.Cellar, "mypkg", "2.0.0")
+	oldOnly := filepath.Join(paths.Cellar, "mypkg", "1.0.0", "bin", "mypkg-legacy")
+	os.WriteFile(oldOnly, []byte("binary"), 0755)
+
+	if _, err := lnk.Link("mypkg", "1.0.0", false); err != nil {
+		t.Fatalf("link 1.0.0: %v", err)
+	}
+	if _, err := lnk.Link("mypkg", "2.0.0", false); err != nil {
+		t.Fatalf("link 2.0.0: %v", err)
+	}
+
+	newKeg := filepath.Join(paths.Cellar, "mypkg", "2.0.0")
+	if target, _ := os.Readlink(filepath.Join(paths.Opt, "mypkg")); target != newKeg {
+		t.Errorf("opt symlink target = %q, want %q", target, newKeg)
+	}
+	if target, _ := os.Readlink(filepath.Join(paths.Bin, "mypkg")); target != filepath.Join(newKeg, "bin", "mypkg") {
+		t.Errorf("bin symlink target = %q, want the 2.0.0 binary", target)
+	}
+	if _, err := os.Lstat(filepath.Join(paths.Bin, "mypkg-legacy")); !os.IsNotExist(err) {
+		t.Error("link into the 1.0.0 keg was left behind")
+	}
+	if _, err := os.Lstat(filepath.Join(paths.Opt, "mypkg.tmp")); !os.IsNotExist(err) {
+		t.Error("temporary opt link was left behind")
+	}
+}
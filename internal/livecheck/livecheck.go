@@ -0,0 +1,183 @@
+// Package livecheck finds the newest upstream release of a formula with
+// the strategy its livecheck: block selects, and compares it with the
+// packaged version.
+package livecheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/homegrew/grew/internal/formula"
+	"github.com/homegrew/grew/internal/vercmp"
+)
+
+// maxBody limits how much of a page is read.
+const maxBody = 8 << 20
+
+// defaultClient bounds each check, so one slow upstream page cannot stall
+// a livecheck of the whole tap.
+var defaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// Result is the outcome of checking one formula.
+type Result struct {
+	Name     string `json:"name"`
+	Current  string `json:"current"`
+	Latest   string `json:"latest,omitempty"`
+	Outdated bool   `json:"outdated"`
+	URL      string `json:"url,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Checker fetches livecheck pages. A nil Client means a client with a
+// 30 second timeout.
+type Checker struct {
+	Client *http.Client
+}
+
+// Check finds the latest upstream version of f. Errors (no livecheck
+// block, fetch failures, nothing matched) are reported in Result.Error so
+// a batch of checks can carry on.
+func (c *Checker) Check(f *formula.Formula) *Result {
+	r := &Result{Name: f.Name, Current: f.Version}
+	if f.Livecheck == nil {
+		r.Error = "no livecheck block"
+		return r
+	}
+	u, re, err := f.Livecheck.Resolve(f)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.URL = u
+	versions, err := c.versions(f.Livecheck.Strategy, u, re)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	if len(versions) == 0 {
+		r.Error = fmt.Sprintf("no versions found at %s", u)
+		return r
+	}
+	r.Latest = vercmp.Latest(versions)
+	r.Outdated = vercmp.Less(f.Version, r.Latest)
+	return r
+}
+
+func (c *Checker) versions(strategy, u string, re *regexp.Regexp) ([]string, error) {
+	body, err := c.fetch(u, strategy == formula.LivecheckGitHubReleases)
+	if err != nil {
+		return nil, err
+	}
+	switch strategy {
+	case formula.LivecheckPage:
+		return pageVersions(body, re), nil
+	case formula.LivecheckGitHubReleases:
+		return releaseVersions(body, re)
+	case formula.LivecheckDirectory:
+		return directoryVersions(body, re), nil
+	}
+	return nil, fmt.Errorf("unknown strategy %q", strategy)
+}
+
+func (c *Checker) fetch(u string, isJSON bool) ([]byte, error) {
+	client := c.Client
+	if client == nil {
+		client = defaultClient
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", u, err)
+	}
+	if isJSON {
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: HTTP %d", u, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", u, err)
+	}
+	return body, nil
+}
+
+// match returns the version in a regex match: its capture group, or the
+// whole match when the regex has none.
+func match(m []string) string {
+	if len(m) > 1 {
+		return m[1]
+	}
+	return m[0]
+}
+
+// pageVersions returns every match of re in a page body.
+func pageVersions(body []byte, re *regexp.Regexp) []string {
+	var versions []string
+	for _, m := range re.FindAllStringSubmatch(string(body), -1) {
+		if v := match(m); v != "" {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// releaseVersions reads a GitHub releases feed, skipping drafts and
+// prereleases, and matches re against each tag.
+func releaseVersions(body []byte, re *regexp.Regexp) ([]string, error) {
+	var releases []struct {
+		TagName    string `json:"tag_name"`
+		Draft      bool   `json:"draft"`
+		Prerelease bool   `json:"prerelease"`
+	}
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return nil, fmt.Errorf("parse releases: %w", err)
+	}
+	var versions []string
+	for _, rel := range releases {
+		if rel.Draft || rel.Prerelease {
+			continue
+		}
+		if m := re.FindStringSubmatch(rel.TagName); m != nil {
+			if v := match(m); v != "" {
+				versions = append(versions, v)
+			}
+		}
+	}
+	return versions, nil
+}
+
+var hrefRe = regexp.MustCompile(`(?i)href\s*=\s*["']([^"'?#]+)`)
+
+// directoryVersions matches re against the last path element of each link
+// in a directory listing. Links to directories keep their trailing slash.
+func directoryVersions(body []byte, re *regexp.Regexp) []string {
+	var versions []string
+	for _, m := range hrefRe.FindAllStringSubmatch(string(body), -1) {
+		href := m[1]
+		if unescaped, err := url.PathUnescape(href); err == nil {
+			href = unescaped
+		}
+		name := path.Base(strings.TrimSuffix(href, "/"))
+		if strings.HasSuffix(href, "/") {
+			name += "/"
+		}
+		if mm := re.FindStringSubmatch(name); mm != nil {
+			if v := match(mm); v != "" {
+				versions = append(versions, v)
+			}
+		}
+	}
+	return versions
+}
@@ -0,0 +1,153 @@
+package livecheck
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/homegrew/grew/internal/formula"
+)
+
+const testSHA = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// upstream stands in for release pages, feeds and directory listings.
+func upstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/download.html", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="foo-1.9.tar.gz">1.9</a> <a href="foo-1.10.tar.gz">1.10</a> <a href="foo-1.2.tar.gz">old</a>`)
+	})
+	mux.HandleFunc("/repos/o/foo/releases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"tag_name": "v3.0.0-rc1", "prerelease": true},
+			{"tag_name": "v2.5.0", "draft": true},
+			{"tag_name": "v2.1.0"},
+			{"tag_name": "v2.0.3"}
+		]`)
+	})
+	mux.HandleFunc("/dist/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><pre>
+<a href="../">../</a>
+<a href="foo-1.0.tar.gz">foo-1.0.tar.gz</a>
+<a href="foo-1.4.2.tar.xz">foo-1.4.2.tar.xz</a>
+<a href="foo-1.4.2.tar.xz.sig">foo-1.4.2.tar.xz.sig</a>
+<a href="foobar-9.0.tar.gz">foobar-9.0.tar.gz</a>
+<a href="1.5/">1.5/</a>
+</pre></body></html>`)
+	})
+	mux.HandleFunc("/broken.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{not json`)
+	})
+	srv := httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func parse(t *testing.T, version, extra string) *formula.Formula {
+	t.Helper()
+	f, err := formula.Parse([]byte(`name: foo
+version: "` + version + `"
+install:
+  type: binary
+` + extra))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return f
+}
+
+func TestCheck_Strategies(t *testing.T) {
+	srv := upstream(t)
+	c := &Checker{Client: srv.Client()}
+
+	tests := []struct {
+		name, version, extra string
+		latest               string
+		outdated             bool
+	}{
+		{
+			name:    "page regex",
+			version: "1.9",
+			extra: `url:
+  linux_amd64: ` + srv.URL + `/foo
+sha256:
+  linux_amd64: ` + testSHA + `
+livecheck:
+  strategy: page
+  url: ` + srv.URL + `/download.html
+  regex: foo-(\d+(?:\.\d+)+)\.tar\.gz
+`,
+			latest:   "1.10",
+			outdated: true,
+		},
+		{
+			name:    "github releases",
+			version: "2.1.0",
+			extra: `url:
+  linux_amd64: ` + srv.URL + `/foo
+sha256:
+  linux_amd64: ` + testSHA + `
+livecheck:
+  strategy: github_releases
+  url: ` + srv.URL + `/repos/o/foo/releases
+`,
+			latest:   "2.1.0",
+			outdated: false,
+		},
+		{
+			name:    "directory from source url",
+			version: "1.0",
+			extra: `source:
+  url: ` + srv.URL + `/dist/foo-1.0.tar.gz
+  sha256: ` + testSHA + `
+livecheck:
+  strategy: directory
+`,
+			latest:   "1.5",
+			outdated: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := c.Check(parse(t, tt.version, tt.extra))
+			if r.Error != "" {
+				t.Fatalf("unexpected error: %s", r.Error)
+			}
+			if r.Latest != tt.latest || r.Outdated != tt.outdated {
+				t.Errorf("Latest = %q, Outdated = %v; want %q, %v", r.Latest, r.Outdated, tt.latest, tt.outdated)
+			}
+		})
+	}
+}
+
+func TestCheck_Errors(t *testing.T) {
+	srv := upstream(t)
+	c := &Checker{Client: srv.Client()}
+	base := `url:
+  linux_amd64: ` + srv.URL + `/foo
+sha256:
+  linux_amd64: ` + testSHA + `
+`
+	tests := []struct {
+		name, extra, want string
+	}{
+		{"no block", "", "no livecheck block"},
+		{"not found", "livecheck:\n  strategy: page\n  url: " + srv.URL + "/missing\n  regex: x\n", "HTTP 404"},
+		{"no match", "livecheck:\n  strategy: page\n  url: " + srv.URL + "/download.html\n  regex: bar-(\\d+)\n", "no versions found"},
+		{"bad feed", "livecheck:\n  strategy: github_releases\n  url: " + srv.URL + "/broken.json\n", "parse releases"},
+		{"no url", "livecheck:\n  strategy: github_releases\n", "no url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := c.Check(parse(t, "1.0", base+tt.extra))
+			if !strings.Contains(r.Error, tt.want) {
+				t.Errorf("Error = %q, want it to contain %q", r.Error, tt.want)
+			}
+			if r.Outdated {
+				t.Error("a failed check must not report outdated")
+			}
+		})
+	}
+}
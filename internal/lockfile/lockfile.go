@@ -9,7 +9,9 @@ import (
 
 	"github.com/homegrew/grew/internal/cellar"
 	"github.com/homegrew/grew/internal/formula"
+	"github.com/homegrew/grew/internal/pin"
 	"github.com/homegrew/grew/internal/snapshot"
+	"github.com/homegrew/grew/internal/vercmp"
 )
 
 // LockFileName is the name of the lockfile stored at the grew root.
@@ -23,18 +25,28 @@ type LockFile struct {
 
 // Entry records one installed formula.
 type Entry struct {
-	Version      string   `json:"version"`
-	SHA256       string   `json:"sha256"`                    // download hash
-	DownloadURL  string   `json:"download_url"`
-	Platform     string   `json:"platform"`
-	Dependencies []string `json:"dependencies,omitempty"`
-	KegSHA256    string   `json:"keg_sha256,omitempty"` // from snapshot manifest if available
+	Version       string   `json:"version"`
+	VersionScheme int      `json:"version_scheme,omitempty"`
+	Revision      int      `json:"revision,omitempty"`
+	SHA256        string   `json:"sha256"`                    // download hash
+	DownloadURL   string   `json:"download_url"`
+	Platform      string   `json:"platform"`
+	Dependencies  []string `json:"dependencies,omitempty"`
+	KegSHA256     string   `json:"keg_sha256,omitempty"`  // from snapshot manifest if available
+	HeadCommit    string   `json:"head_commit,omitempty"` // git commit of a --HEAD build
+	Pinned        bool     `json:"pinned,omitempty"`      // pinned against upgrades
+}
+
+// PkgVersion returns the keg version the entry locks: the version and,
+// if non-zero, the formula revision as "<version>_<revision>".
+func (e Entry) PkgVersion() string {
+	return formula.PkgVersion(e.Version, e.Revision)
 }
 
 // Discrepancy describes one difference between the lockfile and installed state.
 type Discrepancy struct {
 	Name   string // formula name
-	Kind   string // "missing", "extra", "version_mismatch", "hash_mismatch"
+	Kind   string // "missing", "extra", "version_mismatch", "revision_mismatch", "hash_mismatch"
 	Detail string // human-readable description
 }
 
@@ -44,7 +56,8 @@ func LockFilePath(grewRoot string) string {
 }
 
 // Load reads and parses the lockfile. Returns an empty LockFile (not an error)
-// if the file does not exist.
+// if the file does not exist. A head_commit that is not a full git hash is
+// an error.
 func Load(grewRoot string) (*LockFile, error) {
 	path := LockFilePath(grewRoot)
 	data, err := os.ReadFile(path)
@@ -62,6 +75,11 @@ func Load(grewRoot string) (*LockFile, error) {
 	if lf.Entries == nil {
 		lf.Entries = make(map[string]Entry)
 	}
+	for name, e := range lf.Entries {
+		if e.HeadCommit != "" && !formula.IsCommit(e.HeadCommit) {
+			return nil, fmt.Errorf("parse lockfile: %s: invalid head_commit %q", name, e.HeadCommit)
+		}
+	}
 	return &lf, nil
 }
 
@@ -98,6 +116,30 @@ func Save(lf *LockFile, grewRoot string) error {
 	return os.Rename(tmpPath, dest)
 }
 
+// RenameEntry moves the entry for a formula renamed in the tap to its new
+// name and updates the dependency lists that mention it. It reports
+// whether the lockfile changed.
+func RenameEntry(lf *LockFile, oldName, newName string) bool {
+	changed := false
+	if entry, ok := lf.Entries[oldName]; ok {
+		if _, exists := lf.Entries[newName]; !exists {
+			lf.Entries[newName] = entry
+		}
+		delete(lf.Entries, oldName)
+		changed = true
+	}
+	for name, entry := range lf.Entries {
+		for i, dep := range entry.Dependencies {
+			if dep == oldName {
+				entry.Dependencies[i] = newName
+				lf.Entries[name] = entry
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
 // marshalSorted produces JSON with entries sorted by name for deterministic output.
 func marshalSorted(lf *LockFile) ([]byte, error) {
 	// Build an ordered representation.
@@ -143,13 +185,17 @@ func marshalSorted(lf *LockFile) ([]byte, error) {
 }
 
 // Generate walks the cellar and builds a complete lockfile from the currently
-// installed state. Snapshot manifests are read where available.
-func Generate(grewRoot string, cellarPath string) (*LockFile, error) {
-	cel := &cellar.Cellar{Path: cellarPath}
+// installed state. Snapshot manifests are read where available, and pinned
+// formulas are marked.
+func Generate(grewRoot string, cel *cellar.Cellar) (*LockFile, error) {
 	pkgs, err := cel.List()
 	if err != nil {
 		return nil, fmt.Errorf("list cellar: %w", err)
 	}
+	pins, err := pin.Load(grewRoot)
+	if err != nil {
+		return nil, err
+	}
 
 	platform := formula.PlatformKey()
 	lf := &LockFile{
@@ -158,20 +204,31 @@ func Generate(grewRoot string, cellarPath string) (*LockFile, error) {
 	}
 
 	for _, pkg := range pkgs {
+		version, revision := vercmp.SplitRevision(pkg.Version)
 		entry := Entry{
-			Version:  pkg.Version,
+			Version:  version,
+			Revision: revision,
 			Platform: platform,
+			Pinned:   pins[pkg.Name],
 		}
 
 		kegPath := cel.KegPath(pkg.Name, pkg.Version)
 		if snapshot.Exists(kegPath) {
 			m, err := snapshot.Load(kegPath)
 			if err == nil {
+				// The manifest knows the revision; the keg name alone
+				// is ambiguous for versions containing '_'.
+				entry.Version = vercmp.TrimRevision(pkg.Version, m.Revision)
+				entry.Revision = m.Revision
 				entry.SHA256 = m.DownloadSHA256
 				entry.DownloadURL = m.DownloadURL
 				entry.Platform = m.Platform
 				entry.Dependencies = m.Dependencies
 				entry.KegSHA256 = m.KegSHA256
+				entry.VersionScheme = m.VersionScheme
+				if formula.IsCommit(m.HeadCommit) {
+					entry.HeadCommit = m.HeadCommit
+				}
 			}
 		}
 
@@ -181,10 +238,80 @@ func Generate(grewRoot string, cellarPath string) (*LockFile, error) {
 	return lf, nil
 }
 
+// GenerateFor builds a lockfile for installing the current set of
+// formulas on another platform. Each installed formula is loaded with load,
+// evaluated for p, and recorded at its tap version with the download for
+// p; dependencies that only p needs are added. Formulas with no bottle or
+// source download are left out and returned as skipped.
+func GenerateFor(cel *cellar.Cellar, p formula.Platform, load func(name string) (*formula.Formula, error)) (*LockFile, []string, error) {
+	pkgs, err := cel.List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("list cellar: %w", err)
+	}
+
+	lf := &LockFile{
+		Version: 1,
+		Entries: make(map[string]Entry),
+	}
+	var skipped []string
+	seen := make(map[string]bool)
+	queue := make([]string, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		queue = append(queue, pkg.Name)
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		f, err := load(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load %s: %w", name, err)
+		}
+		if f, err = f.ForPlatform(p); err != nil {
+			return nil, nil, err
+		}
+		url, sha, err := downloadFor(f)
+		if err != nil {
+			skipped = append(skipped, f.Name)
+			continue
+		}
+		lf.Entries[f.Name] = Entry{
+			Version:       f.Version,
+			VersionScheme: f.VersionScheme,
+			Revision:      f.Revision,
+			SHA256:        sha,
+			DownloadURL:   url,
+			Platform:      p.Key(),
+			Dependencies:  f.DependencyNames(),
+		}
+		queue = append(queue, f.DependencyNames()...)
+	}
+	sort.Strings(skipped)
+	return lf, skipped, nil
+}
+
+// downloadFor returns the bottle download of f, falling back to its
+// source archive.
+func downloadFor(f *formula.Formula) (string, string, error) {
+	if url, err := f.GetURL(); err == nil {
+		sha, _ := f.GetSHA256()
+		return url, sha, nil
+	}
+	url, err := f.GetSourceURL()
+	if err != nil {
+		return "", "", err
+	}
+	sha, _ := f.GetSourceSHA256()
+	return url, sha, nil
+}
+
 // Check compares the lockfile against the currently installed packages and
 // returns any discrepancies found.
-func Check(lf *LockFile, cellarPath string) ([]Discrepancy, error) {
-	cel := &cellar.Cellar{Path: cellarPath}
+func Check(lf *LockFile, cel *cellar.Cellar) ([]Discrepancy, error) {
 	pkgs, err := cel.List()
 	if err != nil {
 		return nil, fmt.Errorf("list cellar: %w", err)
@@ -212,16 +339,28 @@ func Check(lf *LockFile, cellarPath string) ([]Discrepancy, error) {
 			discrepancies = append(discrepancies, Discrepancy{
 				Name:   name,
 				Kind:   "missing",
-				Detail: fmt.Sprintf("locked at %s but not installed", entry.Version),
+				Detail: fmt.Sprintf("locked at %s but not installed", entry.PkgVersion()),
 			})
 			continue
 		}
 
-		if pkg.Version != entry.Version {
+		if locked := entry.PkgVersion(); pkg.Version != locked {
+			direction := "upgraded"
+			installedVer := cel.KegVersion(name, pkg.Version)
+			lockedVer := vercmp.Parse(entry.Version).WithScheme(entry.VersionScheme).WithRevision(entry.Revision)
+			if installedVer.Compare(lockedVer) < 0 {
+				direction = "downgraded"
+			}
+			// Same version at another revision: the formula was rebuilt
+			// without an upstream release.
+			kind := "version_mismatch"
+			if installedVer.WithRevision(0).Compare(lockedVer.WithRevision(0)) == 0 {
+				kind = "revision_mismatch"
+			}
 			discrepancies = append(discrepancies, Discrepancy{
 				Name:   name,
-				Kind:   "version_mismatch",
-				Detail: fmt.Sprintf("locked at %s but installed %s", entry.Version, pkg.Version),
+				Kind:   kind,
+				Detail: fmt.Sprintf("locked at %s but installed %s (%s)", locked, pkg.Version, direction),
 			})
 			continue
 		}
@@ -3,8 +3,12 @@ package lockfile
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/homegrew/grew/internal/cellar"
+	"github.com/homegrew/grew/internal/formula"
+	"github.com/homegrew/grew/internal/pin"
 	"github.com/homegrew/grew/internal/snapshot"
 )
 
@@ -53,7 +57,7 @@ func TestSaveAndLoad(t *testing.T) {
 	cellarPath := filepath.Join(root, "Cellar")
 
 	// Generate.
-	lf, err := Generate(root, cellarPath)
+	lf, err := Generate(root, &cellar.Cellar{Path: cellarPath})
 	if err != nil {
 		t.Fatalf("Generate: %v", err)
 	}
@@ -123,12 +127,12 @@ func TestCheck_Clean(t *testing.T) {
 	})
 	cellarPath := filepath.Join(root, "Cellar")
 
-	lf, err := Generate(root, cellarPath)
+	lf, err := Generate(root, &cellar.Cellar{Path: cellarPath})
 	if err != nil {
 		t.Fatalf("Generate: %v", err)
 	}
 
-	discs, err := Check(lf, cellarPath)
+	discs, err := Check(lf, &cellar.Cellar{Path: cellarPath})
 	if err != nil {
 		t.Fatalf("Check: %v", err)
 	}
@@ -137,6 +141,58 @@ func TestCheck_Clean(t *testing.T) {
 	}
 }
 
+func TestGenerate_HeadCommit(t *testing.T) {
+	commit := "0123456789abcdef0123456789abcdef01234567"
+	root := setupCellar(t, map[string]struct {
+		version  string
+		manifest *snapshot.Manifest
+	}{
+		"tool": {
+			version: "HEAD-0123456",
+			manifest: &snapshot.Manifest{
+				Name:        "tool",
+				Version:     "HEAD-0123456",
+				Platform:    "linux_amd64",
+				DownloadURL: "https://example.com/tool.git",
+				HeadCommit:  commit,
+			},
+		},
+	})
+
+	lf, err := Generate(root, &cellar.Cellar{Path: filepath.Join(root, "Cellar")})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	entry := lf.Entries["tool"]
+	if entry.Version != "HEAD-0123456" || entry.HeadCommit != commit {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+}
+
+func TestGenerate_Pinned(t *testing.T) {
+	root := setupCellar(t, map[string]struct {
+		version  string
+		manifest *snapshot.Manifest
+	}{
+		"jq":   {version: "1.6"},
+		"curl": {version: "8.5.0"},
+	})
+	if err := pin.Save(pin.Pins{"jq": true}, root); err != nil {
+		t.Fatal(err)
+	}
+
+	lf, err := Generate(root, &cellar.Cellar{Path: filepath.Join(root, "Cellar")})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !lf.Entries["jq"].Pinned {
+		t.Error("jq should be marked pinned")
+	}
+	if lf.Entries["curl"].Pinned {
+		t.Error("curl should not be marked pinned")
+	}
+}
+
 func TestCheck_Missing(t *testing.T) {
 	// Create an empty cellar but a lockfile with an entry.
 	root := t.TempDir()
@@ -155,7 +211,7 @@ func TestCheck_Missing(t *testing.T) {
 		},
 	}
 
-	discs, err := Check(lf, cellarPath)
+	discs, err := Check(lf, &cellar.Cellar{Path: cellarPath})
 	if err != nil {
 		t.Fatalf("Check: %v", err)
 	}
@@ -185,7 +241,7 @@ func TestCheck_Extra(t *testing.T) {
 		Entries: make(map[string]Entry),
 	}
 
-	discs, err := Check(lf, cellarPath)
+	discs, err := Check(lf, &cellar.Cellar{Path: cellarPath})
 	if err != nil {
 		t.Fatalf("Check: %v", err)
 	}
@@ -219,7 +275,7 @@ func TestCheck_VersionMismatch(t *testing.T) {
 		},
 	}
 
-	discs, err := Check(lf, cellarPath)
+	discs, err := Check(lf, &cellar.Cellar{Path: cellarPath})
 	if err != nil {
 		t.Fatalf("Check: %v", err)
 	}
@@ -229,6 +285,166 @@ func TestCheck_VersionMismatch(t *testing.T) {
 	if discs[0].Kind != "version_mismatch" {
 		t.Errorf("kind = %q, want version_mismatch", discs[0].Kind)
 	}
+	if !strings.Contains(discs[0].Detail, "upgraded") {
+		t.Errorf("detail = %q, want it to mention the upgrade", discs[0].Detail)
+	}
+}
+
+func TestCheck_Downgrade(t *testing.T) {
+	root := setupCellar(t, map[string]struct {
+		version  string
+		manifest *snapshot.Manifest
+	}{
+		"jq": {version: "1.9", manifest: nil},
+	})
+	cellarPath := filepath.Join(root, "Cellar")
+
+	lf := &LockFile{
+		Version: 1,
+		Entries: map[string]Entry{
+			"jq": {Version: "1.10", Platform: "darwin_arm64"},
+		},
+	}
+
+	discs, err := Check(lf, &cellar.Cellar{Path: cellarPath})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(discs) != 1 {
+		t.Fatalf("expected 1 discrepancy, got %d", len(discs))
+	}
+	if !strings.Contains(discs[0].Detail, "downgraded") {
+		t.Errorf("detail = %q, want it to mention the downgrade", discs[0].Detail)
+	}
+}
+
+func TestCheck_RevisionMismatch(t *testing.T) {
+	root := setupCellar(t, map[string]struct {
+		version  string
+		manifest *snapshot.Manifest
+	}{
+		"jq": {version: "1.7.1_1", manifest: nil},
+	})
+	cellarPath := filepath.Join(root, "Cellar")
+
+	lf, err := Generate(root, &cellar.Cellar{Path: cellarPath})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if e := lf.Entries["jq"]; e.Version != "1.7.1" || e.Revision != 1 {
+		t.Fatalf("entry = %+v, want version 1.7.1 revision 1", e)
+	}
+	if discs, err := Check(lf, &cellar.Cellar{Path: cellarPath}); err != nil || len(discs) != 0 {
+		t.Fatalf("Check on generated lockfile = %v, %v", discs, err)
+	}
+
+	lf.Entries["jq"] = Entry{Version: "1.7.1", Platform: "darwin_arm64"}
+	discs, err := Check(lf, &cellar.Cellar{Path: cellarPath})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(discs) != 1 {
+		t.Fatalf("expected 1 discrepancy, got %d", len(discs))
+	}
+	if discs[0].Kind != "revision_mismatch" {
+		t.Errorf("kind = %q, want revision_mismatch", discs[0].Kind)
+	}
+	if !strings.Contains(discs[0].Detail, "upgraded") {
+		t.Errorf("detail = %q, want it to mention the upgrade", discs[0].Detail)
+	}
+}
+
+func TestGenerate_UnderscoreVersion(t *testing.T) {
+	root := setupCellar(t, map[string]struct {
+		version  string
+		manifest *snapshot.Manifest
+	}{
+		"boost": {version: "1_84_1", manifest: &snapshot.Manifest{Name: "boost", Version: "1_84_1"}},
+	})
+	cellarPath := filepath.Join(root, "Cellar")
+
+	lf, err := Generate(root, &cellar.Cellar{Path: cellarPath})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if e := lf.Entries["boost"]; e.Version != "1_84_1" || e.Revision != 0 {
+		t.Fatalf("entry = %+v, want version 1_84_1 revision 0", e)
+	}
+	if discs, err := Check(lf, &cellar.Cellar{Path: cellarPath}); err != nil || len(discs) != 0 {
+		t.Fatalf("Check on generated lockfile = %v, %v", discs, err)
+	}
+}
+
+func TestGenerateFor(t *testing.T) {
+	root := setupCellar(t, map[string]struct {
+		version  string
+		manifest *snapshot.Manifest
+	}{
+		"app":     {version: "1.0"},
+		"sourced": {version: "2.0"},
+		"gone":    {version: "0.1"},
+	})
+	sha := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	tapDir := filepath.Join(root, "Taps", "core")
+	os.MkdirAll(tapDir, 0755)
+	formulas := map[string]string{
+		"app":      "name: app\nversion: \"1.1\"\nurl:\n  darwin_arm64: \"https://example.com/app-mac\"\n  linux_amd64: \"https://example.com/app-linux\"\nsha256:\n  darwin_arm64: \"" + sha + "\"\n  linux_amd64: \"" + sha + "\"\ninstall:\n  type: binary\non_macos:\n  dependencies:\n    - libiconv\n",
+		"libiconv": "name: libiconv\nversion: \"1.17\"\nurl:\n  darwin_arm64: \"https://example.com/libiconv\"\ninstall:\n  type: binary\n",
+		"sourced":  "name: sourced\nversion: \"2.0\"\nsource:\n  url: \"https://example.com/sourced.tar.gz\"\n  sha256: \"" + sha + "\"\nbuild:\n  system: make\n",
+		"gone":     "name: gone\nversion: \"0.1\"\nurl:\n  linux_amd64: \"https://example.com/gone\"\ninstall:\n  type: binary\n",
+	}
+	for name, data := range formulas {
+		if err := os.WriteFile(filepath.Join(tapDir, name+".yaml"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loader := &formula.Loader{TapDir: filepath.Join(root, "Taps")}
+
+	lf, skipped, err := GenerateFor(&cellar.Cellar{Path: filepath.Join(root, "Cellar")}, formula.Platform{OS: "darwin", Arch: "arm64"}, loader.LoadByName)
+	if err != nil {
+		t.Fatalf("GenerateFor: %v", err)
+	}
+	if len(skipped) != 1 || skipped[0] != "gone" {
+		t.Errorf("skipped = %v, want [gone]", skipped)
+	}
+	app, ok := lf.Entries["app"]
+	if !ok {
+		t.Fatal("app missing from lockfile")
+	}
+	if app.Version != "1.1" || app.DownloadURL != "https://example.com/app-mac" || app.Platform != "darwin_arm64" {
+		t.Errorf("unexpected app entry: %+v", app)
+	}
+	if len(app.Dependencies) != 1 || app.Dependencies[0] != "libiconv" {
+		t.Errorf("app dependencies = %v, want [libiconv]", app.Dependencies)
+	}
+	if _, ok := lf.Entries["libiconv"]; !ok {
+		t.Error("darwin-only dependency libiconv should be added")
+	}
+	if got := lf.Entries["sourced"].DownloadURL; got != "https://example.com/sourced.tar.gz" {
+		t.Errorf("sourced download = %q, want the source archive", got)
+	}
+}
+
+func TestRenameEntry(t *testing.T) {
+	lf := &LockFile{Version: 1, Entries: map[string]Entry{
+		"oldpkg": {Version: "1.0"},
+		"app":    {Version: "2.0", Dependencies: []string{"zlib", "oldpkg"}},
+	}}
+	if !RenameEntry(lf, "oldpkg", "newpkg") {
+		t.Fatal("RenameEntry should report a change")
+	}
+	if _, ok := lf.Entries["oldpkg"]; ok {
+		t.Error("old entry should be removed")
+	}
+	if lf.Entries["newpkg"].Version != "1.0" {
+		t.Errorf("new entry = %+v, want the old entry", lf.Entries["newpkg"])
+	}
+	if deps := lf.Entries["app"].Dependencies; deps[1] != "newpkg" {
+		t.Errorf("app dependencies = %v, want oldpkg renamed", deps)
+	}
+	if RenameEntry(lf, "oldpkg", "newpkg") {
+		t.Error("renaming again should be a no-op")
+	}
 }
 
 func TestLoadNonexistent(t *testing.T) {
@@ -244,3 +460,14 @@ func TestLoadNonexistent(t *testing.T) {
 		t.Errorf("expected empty entries, got %d", len(lf.Entries))
 	}
 }
+
+func TestLoad_InvalidHeadCommit(t *testing.T) {
+	root := t.TempDir()
+	data := `{"version": 1, "entries": {"tool": {"version": "HEAD-0123456", "head_commit": "--upload-pack=x"}}}`
+	if err := os.WriteFile(LockFilePath(root), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), "invalid head_commit") {
+		t.Errorf("Load error = %v, want invalid head_commit", err)
+	}
+}
@@ -0,0 +1,102 @@
+// Package pin records the formulas pinned against upgrades. The pins are
+// stored in the prefix, at <grew_root>/pinned.json, as a sorted list of
+// formula names.
+package pin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FileName is the name of the pins file stored at the grew root.
+const FileName = "pinned.json"
+
+// Pins is the set of pinned formula names.
+type Pins map[string]bool
+
+// Path returns the path to the pins file for the given grew root.
+func Path(grewRoot string) string {
+	return filepath.Join(grewRoot, FileName)
+}
+
+// Load reads the pins. A missing file means nothing is pinned.
+func Load(grewRoot string) (Pins, error) {
+	p := make(Pins)
+	data, err := os.ReadFile(Path(grewRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return p, nil
+		}
+		return nil, fmt.Errorf("read pins: %w", err)
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("parse pins: %w", err)
+	}
+	for _, name := range names {
+		p[name] = true
+	}
+	return p, nil
+}
+
+// Save atomically writes the pins. With nothing pinned, the file is
+// removed.
+func Save(p Pins, grewRoot string) error {
+	dest := Path(grewRoot)
+	if len(p) == 0 {
+		if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(p.Names(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal pins: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".pinned-tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp pins file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("write temp pins file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, dest)
+}
+
+// Names returns the pinned formula names, sorted.
+func (p Pins) Names() []string {
+	names := make([]string, 0, len(p))
+	for name, pinned := range p {
+		if pinned {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Rename moves the pin of a formula renamed in the tap to its new name.
+// It reports whether the pins changed.
+func (p Pins) Rename(oldName, newName string) bool {
+	if !p[oldName] {
+		return false
+	}
+	delete(p, oldName)
+	p[newName] = true
+	return true
+}
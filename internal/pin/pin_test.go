@@ -0,0 +1,70 @@
+package pin
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	root := t.TempDir()
+
+	p, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load without a file: %v", err)
+	}
+	if len(p) != 0 {
+		t.Errorf("expected no pins, got %v", p.Names())
+	}
+
+	p["jq"] = true
+	p["curl"] = true
+	if err := Save(p, root); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(Path(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[\n  \"curl\",\n  \"jq\"\n]\n"; string(data) != want {
+		t.Errorf("pins file = %q, want %q", data, want)
+	}
+
+	loaded, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := loaded.Names(); !reflect.DeepEqual(got, []string{"curl", "jq"}) {
+		t.Errorf("Names = %v, want [curl jq]", got)
+	}
+
+	delete(loaded, "curl")
+	delete(loaded, "jq")
+	if err := Save(loaded, root); err != nil {
+		t.Fatalf("Save empty: %v", err)
+	}
+	if _, err := os.Stat(Path(root)); !os.IsNotExist(err) {
+		t.Error("saving no pins should remove the file")
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(Path(root), []byte("{not json"), 0644)
+	if _, err := Load(root); err == nil {
+		t.Error("expected error for an invalid pins file")
+	}
+}
+
+func TestRename(t *testing.T) {
+	p := Pins{"oldtool": true, "jq": true}
+	if !p.Rename("oldtool", "newtool") {
+		t.Error("Rename of a pinned formula should report a change")
+	}
+	if got := p.Names(); !reflect.DeepEqual(got, []string{"jq", "newtool"}) {
+		t.Errorf("Names = %v, want [jq newtool]", got)
+	}
+	if p.Rename("other", "another") {
+		t.Error("Rename of an unpinned formula should report no change")
+	}
+}
@@ -0,0 +1,260 @@
+// Package requirements checks the requirements: block of a formula
+// against the machine it is about to be installed on: kernel and glibc
+// versions, commands, CPU features, architecture and files.
+package requirements
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/homegrew/grew/internal/formula"
+	"github.com/homegrew/grew/internal/vercmp"
+)
+
+// Unmet is a requirement the host does not meet.
+type Unmet struct {
+	Requirement formula.Requirement
+	Reason      string // what was found instead, e.g. "running 4.4.0"
+}
+
+func (u Unmet) String() string {
+	return fmt.Sprintf("%s (%s)", u.Requirement, u.Reason)
+}
+
+// Error reports the requirements of a formula that the host does not
+// meet.
+type Error struct {
+	Name  string
+	Unmet []Unmet
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s cannot be installed on this machine: unmet requirements", e.Name)
+	for _, u := range e.Unmet {
+		fmt.Fprintf(&b, "\n  %s", u)
+	}
+	return b.String()
+}
+
+// Checker checks requirements against a host. The zero value checks the
+// running machine; tests replace the probes. Probe results are cached,
+// so one Checker can check many formulas cheaply.
+type Checker struct {
+	// OS and Arch default to the running platform (GOOS and GOARCH).
+	OS   string
+	Arch string
+	// KernelVersion, GlibcVersion and CPUFeatures read the host. A
+	// GlibcVersion error means the host has no glibc.
+	KernelVersion func() (string, error)
+	GlibcVersion  func() (string, error)
+	CPUFeatures   func() (map[string]bool, error)
+	// LookPath finds a command; it defaults to exec.LookPath.
+	LookPath func(file string) (string, error)
+
+	probes map[string]probe
+}
+
+type probe struct {
+	value    string
+	features map[string]bool
+	err      error
+}
+
+// Check returns an *Error listing the requirements of f the host does
+// not meet, or nil when all are met.
+func (c *Checker) Check(f *formula.Formula) error {
+	var unmet []Unmet
+	for _, r := range f.Requirements {
+		if reason, ok := c.check(r); !ok {
+			unmet = append(unmet, Unmet{Requirement: r, Reason: reason})
+		}
+	}
+	if len(unmet) == 0 {
+		return nil
+	}
+	return &Error{Name: f.Name, Unmet: unmet}
+}
+
+// check reports whether r is met, and if not, why.
+func (c *Checker) check(r formula.Requirement) (string, bool) {
+	kind, want := r.Kind()
+	switch kind {
+	case formula.RequireMinKernel:
+		p := c.probe("kernel", func() probe { return versionProbe(c.KernelVersion, kernelVersion) })
+		if p.err != nil {
+			return fmt.Sprintf("cannot read the kernel version: %v", p.err), false
+		}
+		if vercmp.Less(leadingVersion(p.value), want) {
+			return "running " + p.value, false
+		}
+	case formula.RequireMinGlibc:
+		// glibc only exists on Linux; elsewhere the requirement is moot.
+		if c.goos() != "linux" {
+			return "", true
+		}
+		p := c.probe("glibc", func() probe { return versionProbe(c.GlibcVersion, glibcVersion) })
+		if p.err != nil {
+			return "no glibc found", false
+		}
+		if vercmp.Less(leadingVersion(p.value), want) {
+			return "found " + p.value, false
+		}
+	case formula.RequireCommand:
+		lookPath := c.LookPath
+		if lookPath == nil {
+			lookPath = exec.LookPath
+		}
+		if _, err := lookPath(want); err != nil {
+			return "not found on PATH", false
+		}
+	case formula.RequireCPUFeature:
+		p := c.probe("cpu", func() probe {
+			probeFeatures := c.CPUFeatures
+			if probeFeatures == nil {
+				probeFeatures = cpuFeatures
+			}
+			features, err := probeFeatures()
+			return probe{features: features, err: err}
+		})
+		if p.err != nil {
+			return fmt.Sprintf("cannot read CPU features: %v", p.err), false
+		}
+		if !p.features[want] {
+			return "not supported by this CPU", false
+		}
+	case formula.RequireArch:
+		if arch := c.arch(); arch != want {
+			return "this machine is " + arch, false
+		}
+	case formula.RequireFileExists:
+		if _, err := os.Stat(want); err != nil {
+			return "no such file", false
+		}
+	default:
+		return "unknown requirement", false
+	}
+	return "", true
+}
+
+func (c *Checker) probe(key string, read func() probe) probe {
+	if p, ok := c.probes[key]; ok {
+		return p
+	}
+	if c.probes == nil {
+		c.probes = make(map[string]probe)
+	}
+	p := read()
+	c.probes[key] = p
+	return p
+}
+
+func (c *Checker) goos() string {
+	if c.OS != "" {
+		return c.OS
+	}
+	return runtime.GOOS
+}
+
+func (c *Checker) arch() string {
+	if c.Arch != "" {
+		return c.Arch
+	}
+	return runtime.GOARCH
+}
+
+// versionProbe reads a version with fn, or def when fn is nil.
+func versionProbe(fn, def func() (string, error)) probe {
+	if fn == nil {
+		fn = def
+	}
+	v, err := fn()
+	return probe{value: v, err: err}
+}
+
+var leadingVersionRe = regexp.MustCompile(`^\d+(\.\d+)*`)
+
+// leadingVersion returns the numeric part a kernel or glibc version
+// starts with, dropping distribution suffixes: "6.1.0-18-amd64" gives
+// "6.1.0".
+func leadingVersion(s string) string {
+	if v := leadingVersionRe.FindString(s); v != "" {
+		return v
+	}
+	return s
+}
+
+// kernelVersion returns the release of the running kernel.
+func kernelVersion() (string, error) {
+	out, err := exec.Command("uname", "-r").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// glibcVersion returns the version of the system glibc, as reported by
+// getconf GNU_LIBC_VERSION ("glibc 2.36"). It fails on musl systems.
+func glibcVersion() (string, error) {
+	out, err := exec.Command("getconf", "GNU_LIBC_VERSION").Output()
+	if err != nil {
+		return "", err
+	}
+	name, version, ok := strings.Cut(strings.TrimSpace(string(out)), " ")
+	if !ok || name != "glibc" {
+		return "", fmt.Errorf("unexpected getconf output %q", out)
+	}
+	return version, nil
+}
+
+// cpuFeatures returns the CPU feature flags of the host, lower-cased: the
+// flags (x86) or Features (ARM) line of /proc/cpuinfo on Linux, and the
+// machdep.cpu feature lists and hw.optional flags on macOS.
+func cpuFeatures() (map[string]bool, error) {
+	features := make(map[string]bool)
+	switch runtime.GOOS {
+	case "linux":
+		f, err := os.Open("/proc/cpuinfo")
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			key, value, ok := strings.Cut(sc.Text(), ":")
+			key = strings.TrimSpace(key)
+			if !ok || (key != "flags" && key != "Features") {
+				continue
+			}
+			for _, flag := range strings.Fields(value) {
+				features[strings.ToLower(flag)] = true
+			}
+			break
+		}
+		return features, sc.Err()
+	case "darwin":
+		out, _ := exec.Command("sysctl", "-n", "machdep.cpu.features", "machdep.cpu.leaf7_features").Output()
+		for _, flag := range strings.Fields(string(out)) {
+			features[strings.ToLower(flag)] = true
+		}
+		out, err := exec.Command("sysctl", "hw.optional").Output()
+		if err != nil && len(features) == 0 {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			key, value, ok := strings.Cut(line, ":")
+			if !ok || strings.TrimSpace(value) != "1" {
+				continue
+			}
+			name := key[strings.LastIndexByte(key, '.')+1:]
+			features[strings.ToLower(strings.TrimPrefix(name, "FEAT_"))] = true
+		}
+		return features, nil
+	}
+	return nil, fmt.Errorf("not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,100 @@
+package requirements
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/homegrew/grew/internal/formula"
+)
+
+func fakeChecker() *Checker {
+	return &Checker{
+		OS:            "linux",
+		Arch:          "amd64",
+		KernelVersion: func() (string, error) { return "5.15.0-91-generic", nil },
+		GlibcVersion:  func() (string, error) { return "2.35", nil },
+		CPUFeatures: func() (map[string]bool, error) {
+			return map[string]bool{"sse4_2": true, "avx": true}, nil
+		},
+		LookPath: func(file string) (string, error) {
+			if file == "sh" {
+				return "/bin/sh", nil
+			}
+			return "", errors.New("not found")
+		},
+	}
+}
+
+func TestCheck_Met(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "device")
+	os.WriteFile(file, nil, 0644)
+	f := &formula.Formula{Name: "tool", Requirements: []formula.Requirement{
+		{MinKernel: "5.4"},
+		{MinGlibc: "2.28"},
+		{Command: "sh"},
+		{CPUFeature: "avx"},
+		{Arch: "amd64"},
+		{FileExists: file},
+	}}
+	if err := fakeChecker().Check(f); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCheck_Unmet(t *testing.T) {
+	f := &formula.Formula{Name: "tool", Requirements: []formula.Requirement{
+		{MinKernel: "6.1"},
+		{MinGlibc: "2.38"},
+		{Command: "java"},
+		{CPUFeature: "avx2"},
+		{Arch: "arm64"},
+		{FileExists: "/nonexistent/kvm"},
+	}}
+	err := fakeChecker().Check(f)
+	var re *Error
+	if !errors.As(err, &re) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if len(re.Unmet) != 6 {
+		t.Fatalf("unmet = %v, want all six requirements", re.Unmet)
+	}
+	for _, want := range []string{"min_kernel: 6.1 (running 5.15.0-91-generic)", "command: java (not found on PATH)", "arch: arm64 (this machine is amd64)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestCheck_GlibcOnlyOnLinux(t *testing.T) {
+	c := fakeChecker()
+	c.OS = "darwin"
+	c.GlibcVersion = func() (string, error) { return "", errors.New("no glibc") }
+	f := &formula.Formula{Name: "tool", Requirements: []formula.Requirement{{MinGlibc: "2.28"}}}
+	if err := c.Check(f); err != nil {
+		t.Errorf("min_glibc should not apply on macOS: %v", err)
+	}
+
+	c.OS = "linux"
+	if err := c.Check(f); err == nil || !strings.Contains(err.Error(), "no glibc found") {
+		t.Errorf("expected a missing glibc to be reported, got %v", err)
+	}
+}
+
+func TestCheck_CachesProbes(t *testing.T) {
+	calls := 0
+	c := fakeChecker()
+	c.KernelVersion = func() (string, error) {
+		calls++
+		return "6.8.0", nil
+	}
+	f := &formula.Formula{Name: "tool", Requirements: []formula.Requirement{{MinKernel: "5.4"}}}
+	for range 3 {
+		c.Check(f)
+	}
+	if calls != 1 {
+		t.Errorf("kernel version read %d times, want 1", calls)
+	}
+}
@@ -12,6 +12,7 @@ type BuildConfig struct {
 	BuildDir string   // source tree (read-write)
 	KegDir   string   // install prefix (read-write)
 	DepPaths []string // dependency cellar/opt dirs (read-only; informational on macOS)
+	BinPaths []string // dependency bin dirs, prepended to PATH
 }
 
 // Command wraps a build step in platform-specific sandboxing.
@@ -39,8 +40,9 @@ func Command(cfg BuildConfig, name string, args ...string) *exec.Cmd {
 //   - Read access to the keg and system paths
 //   - Minimal environment (no compiler vars)
 type PostInstallConfig struct {
-	KegDir string // keg path (read-only)
-	TmpDir string // writable scratch space for the script
+	KegDir   string   // keg path (read-only)
+	TmpDir   string   // writable scratch space for the script
+	BinPaths []string // extra bin dirs, prepended to PATH
 }
 
 // PostInstallCommand wraps a post-install step in platform-specific sandboxing.
@@ -60,7 +62,10 @@ func postInstallEnv(cfg PostInstallConfig) []string {
 	}
 	var env []string
 	for _, kv := range os.Environ() {
-		key, _, _ := strings.Cut(kv, "=")
+		key, val, _ := strings.Cut(kv, "=")
+		if key == "PATH" {
+			kv = "PATH=" + prependPath(cfg.BinPaths, val)
+		}
 		if allow[key] {
 			env = append(env, kv)
 		}
@@ -86,7 +91,10 @@ func cleanEnv(cfg BuildConfig) []string {
 
 	var env []string
 	for _, kv := range os.Environ() {
-		key, _, _ := strings.Cut(kv, "=")
+		key, val, _ := strings.Cut(kv, "=")
+		if key == "PATH" {
+			kv = "PATH=" + prependPath(cfg.BinPaths, val)
+		}
 		if allow[key] {
 			env = append(env, kv)
 		}
@@ -99,3 +107,11 @@ func cleanEnv(cfg BuildConfig) []string {
 
 	return env
 }
+
+// prependPath puts dirs in front of the PATH value path.
+func prependPath(dirs []string, path string) string {
+	if len(dirs) == 0 {
+		return path
+	}
+	return strings.Join(append(append([]string(nil), dirs...), path), string(os.PathListSeparator))
+}
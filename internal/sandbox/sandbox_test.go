@@ -71,3 +71,44 @@ func TestCommandReturnsNonNil(t *testing.T) {
 		t.Error("expected clean env to be set")
 	}
 }
+
+func TestCleanEnv_BinPaths(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin")
+
+	cfg := BuildConfig{
+		BuildDir: t.TempDir(),
+		KegDir:   t.TempDir(),
+		BinPaths: []string{"/grew/Cellar/cmake/3.30/bin", "/grew/Cellar/ninja/1.12/bin"},
+	}
+	var path string
+	for _, kv := range cleanEnv(cfg) {
+		if v, ok := strings.CutPrefix(kv, "PATH="); ok {
+			path = v
+		}
+	}
+	want := "/grew/Cellar/cmake/3.30/bin:/grew/Cellar/ninja/1.12/bin:/usr/bin"
+	if path != want {
+		t.Errorf("PATH = %q, want %q", path, want)
+	}
+}
+
+func TestPostInstallEnv_BinPaths(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin")
+	t.Setenv("CC", "clang")
+
+	cfg := PostInstallConfig{KegDir: "/grew/Cellar/jq/1.7", TmpDir: t.TempDir(), BinPaths: []string{"/grew/Cellar/jq/1.7/bin"}}
+	envMap := make(map[string]string)
+	for _, kv := range postInstallEnv(cfg) {
+		k, v, _ := strings.Cut(kv, "=")
+		envMap[k] = v
+	}
+	if want := "/grew/Cellar/jq/1.7/bin:/usr/bin"; envMap["PATH"] != want {
+		t.Errorf("PATH = %q, want %q", envMap["PATH"], want)
+	}
+	if envMap["TMPDIR"] != cfg.TmpDir {
+		t.Errorf("TMPDIR = %q, want %q", envMap["TMPDIR"], cfg.TmpDir)
+	}
+	if _, ok := envMap["CC"]; ok {
+		t.Error("CC should be stripped from the post-install environment")
+	}
+}
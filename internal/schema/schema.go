@@ -0,0 +1,258 @@
+// Package schema decodes formula and cask YAML strictly and describes the
+// formats as JSON Schema. Both are driven by the yaml struct tags of the
+// target type, so the schema never drifts from what Parse accepts.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Error is a problem at a position in a YAML document. File is empty until
+// a loader fills it in; Line and Column are 1-based and zero when unknown.
+type Error struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+// Error formats as "file:line:col: msg", the form editors and CI annotate,
+// or "line N, column M: msg" when no file is known.
+func (e *Error) Error() string {
+	switch {
+	case e.File != "" && e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Msg)
+	case e.File != "" && e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+	case e.File != "":
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+	}
+	return e.Msg
+}
+
+// InFile attributes err to file. Every *Error in err (which may be a
+// joined error) gets the file name; any other error is wrapped in an
+// *Error without a position.
+func InFile(err error, file string) error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, inner := range joined.Unwrap() {
+			InFile(inner, file)
+		}
+		return err
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		e.File = file
+		return err
+	}
+	return &Error{File: file, Msg: err.Error()}
+}
+
+// Decode parses YAML data into out, a pointer to a struct. Keys that no
+// yaml tag of the target declares are rejected, with their line and
+// column. Every unknown key is reported, joined into one error.
+func Decode(data []byte, out any) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return syntaxError(err)
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return &Error{Msg: "empty document"}
+	}
+	doc := root.Content[0]
+
+	var errs []error
+	checkNode(doc, reflect.TypeOf(out).Elem(), "", &errs)
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if err := doc.Decode(out); err != nil {
+		return typeError(err)
+	}
+	return nil
+}
+
+var lineMsg = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// syntaxError turns a yaml.v3 "yaml: line N: msg" error into an *Error.
+func syntaxError(err error) error {
+	if m := lineMsg.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return &Error{Line: line, Msg: m[2]}
+	}
+	return &Error{Msg: err.Error()}
+}
+
+// typeError splits a *yaml.TypeError into one *Error per problem.
+func typeError(err error) error {
+	var te *yaml.TypeError
+	if !errors.As(err, &te) {
+		return &Error{Msg: err.Error()}
+	}
+	errs := make([]error, 0, len(te.Errors))
+	for _, msg := range te.Errors {
+		errs = append(errs, syntaxError(errors.New(msg)))
+	}
+	return errors.Join(errs...)
+}
+
+func checkNode(n *yaml.Node, t reflect.Type, path string, errs *[]error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return // reported by the decoder as a type error
+		}
+		fields := fieldTypes(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			ft, ok := fields[key.Value]
+			if !ok {
+				msg := fmt.Sprintf("unknown field %q", joinPath(path, key.Value))
+				if hint := closest(key.Value, fields); hint != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", hint)
+				}
+				*errs = append(*errs, &Error{Line: key.Line, Column: key.Column, Msg: msg})
+				continue
+			}
+			checkNode(value, ft, joinPath(path, key.Value), errs)
+		}
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range n.Content {
+			checkNode(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			checkNode(n.Content[i+1], t.Elem(), joinPath(path, n.Content[i].Value), errs)
+		}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// fieldTypes maps the YAML keys of a struct to their field types, using
+// the same naming rules as yaml.v3.
+func fieldTypes(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// closest returns the known key within two edits of key, if any.
+func closest(key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		if d := editDistance(key, name); d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// Generate returns a JSON Schema (draft 2020-12) describing the YAML form
+// of v, a struct or pointer to one. Unknown keys are disallowed at every
+// level, matching Decode. required lists the mandatory top-level keys.
+func Generate(v any, title string, required ...string) map[string]any {
+	s := typeSchema(reflect.TypeOf(v))
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["title"] = title
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := fieldTypes(t)
+		props := make(map[string]any, len(fields))
+		for name, ft := range fields {
+			props[name] = typeSchema(ft)
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           props,
+			"additionalProperties": false,
+		}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
@@ -0,0 +1,110 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type inner struct {
+	Run  string `yaml:"run"`
+	Exit int    `yaml:"expect_exit"`
+}
+
+type doc struct {
+	Name   string            `yaml:"name"`
+	Deps   []string          `yaml:"dependencies"`
+	Steps  []inner           `yaml:"steps"`
+	Maps   map[string]inner  `yaml:"maps"`
+	Plain  map[string]string `yaml:"plain"`
+	Nested *inner            `yaml:"nested"`
+	Hidden string            `yaml:"-"`
+}
+
+func TestDecode_Valid(t *testing.T) {
+	var d doc
+	err := Decode([]byte("name: x\ndependencies: [a]\nsteps:\n  - run: ls\nmaps:\n  k:\n    run: y\nplain:\n  anything: goes\n"), &d)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Name != "x" || len(d.Deps) != 1 || d.Steps[0].Run != "ls" || d.Maps["k"].Run != "y" {
+		t.Errorf("unexpected decode result: %+v", d)
+	}
+}
+
+func TestDecode_UnknownFields(t *testing.T) {
+	var d doc
+	err := Decode([]byte("name: x\ndependancies: [a]\nsteps:\n  - run: ls\n    bogus: 1\nnested:\n  rnu: z\n-: no\n"), &d)
+	if err == nil {
+		t.Fatal("expected error for unknown fields")
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		`line 2, column 1: unknown field "dependancies" (did you mean "dependencies"?)`,
+		`line 5, column 5: unknown field "steps[0].bogus"`,
+		`line 7, column 3: unknown field "nested.rnu" (did you mean "run"?)`,
+		`unknown field "-"`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error should contain %q, got:\n%s", want, msg)
+		}
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Line != 2 || e.Column != 1 {
+		t.Errorf("first error should be *Error at 2:1, got %#v", e)
+	}
+}
+
+func TestDecode_SyntaxAndTypeErrors(t *testing.T) {
+	var d doc
+	err := Decode([]byte("name: x\nsteps: [\n"), &d)
+	var e *Error
+	if !errors.As(err, &e) || e.Line == 0 {
+		t.Errorf("syntax error should carry a line, got %v", err)
+	}
+
+	err = Decode([]byte("name: x\nsteps:\n  - expect_exit: lots\n"), &d)
+	if !errors.As(err, &e) || e.Line != 3 {
+		t.Errorf("type error should point at line 3, got %v", err)
+	}
+
+	if err := Decode(nil, &d); err == nil {
+		t.Error("expected error for empty document")
+	}
+}
+
+func TestInFile(t *testing.T) {
+	var d doc
+	err := InFile(Decode([]byte("name: x\nbogus: 1\nother: 2\n"), &d), "core/x.yaml")
+	if !strings.Contains(err.Error(), "core/x.yaml:2:1: unknown field") || !strings.Contains(err.Error(), "core/x.yaml:3:1:") {
+		t.Errorf("every error should name the file, got:\n%v", err)
+	}
+
+	err = InFile(errors.New("formula missing name"), "core/x.yaml")
+	if err.Error() != "core/x.yaml: formula missing name" {
+		t.Errorf("plain error = %q", err)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	s := Generate(doc{}, "test doc", "name")
+	if s["title"] != "test doc" || s["additionalProperties"] != false {
+		t.Errorf("unexpected top level: %v", s)
+	}
+	props := s["properties"].(map[string]any)
+	if _, ok := props["-"]; ok {
+		t.Error(`fields tagged "-" must not appear`)
+	}
+	deps := props["dependencies"].(map[string]any)
+	if deps["type"] != "array" || deps["items"].(map[string]any)["type"] != "string" {
+		t.Errorf("dependencies schema = %v", deps)
+	}
+	steps := props["steps"].(map[string]any)["items"].(map[string]any)
+	if steps["properties"].(map[string]any)["expect_exit"].(map[string]any)["type"] != "integer" {
+		t.Errorf("steps item schema = %v", steps)
+	}
+	maps := props["maps"].(map[string]any)
+	if maps["additionalProperties"].(map[string]any)["type"] != "object" {
+		t.Errorf("maps schema = %v", maps)
+	}
+}
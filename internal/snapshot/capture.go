@@ -76,13 +76,20 @@ func Capture(name, version, kegPath string, meta InstallMeta) (*Manifest, error)
 	m := &Manifest{
 		Name:           name,
 		Version:        version,
+		VersionScheme:  meta.VersionScheme,
+		Revision:       meta.Revision,
+		BottleRebuild:  meta.BottleRebuild,
 		Platform:       meta.Platform,
 		InstalledAt:    Now(),
 		DownloadURL:    meta.DownloadURL,
 		DownloadSHA256: meta.DownloadSHA256,
+		FormulaPath:    meta.FormulaPath,
+		HeadCommit:     meta.HeadCommit,
 		KegSHA256:      aggregateHash(files),
 		Files:          files,
 		Dependencies:   meta.Dependencies,
+		Options:        meta.Options,
+		Links:          meta.Links,
 	}
 	return m, nil
 }
@@ -15,14 +15,26 @@ const ManifestFile = ".MANIFEST.json"
 // installation. It enables integrity verification and reproducibility.
 type Manifest struct {
 	// Identity
-	Name    string `json:"name"`
-	Version string `json:"version"`
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	VersionScheme int    `json:"version_scheme,omitempty"`
+	// Revision is the formula revision the keg was built at, and
+	// BottleRebuild the rebuild counter of the bottle it was poured from.
+	Revision      int `json:"revision,omitempty"`
+	BottleRebuild int `json:"bottle_rebuild,omitempty"`
 
 	// Provenance
 	Platform       string `json:"platform"`
 	InstalledAt    string `json:"installed_at"`
 	DownloadURL    string `json:"download_url"`
 	DownloadSHA256 string `json:"download_sha256"`
+	// FormulaPath is the formula file the keg was installed from, so
+	// installs from a local file or --tap-path can be told apart from
+	// tap installs.
+	FormulaPath string `json:"formula_path,omitempty"`
+	// HeadCommit is the git commit a keg built with --HEAD was built
+	// from; DownloadURL is then the repository URL.
+	HeadCommit string `json:"head_commit,omitempty"`
 
 	// Aggregate integrity hash (SHA-256 of all file hashes concatenated in order).
 	KegSHA256 string `json:"keg_sha256"`
@@ -30,11 +42,15 @@ type Manifest struct {
 	// Per-file inventory, sorted by path.
 	Files []FileEntry `json:"files"`
 
-	// Symlinks created by the linker (opt, bin, lib, include).
+	// Symlinks created by the linker (opt, bin, sbin, lib, include, etc, share).
 	Links []LinkEntry `json:"links,omitempty"`
 
 	// Formula dependency names at install time.
 	Dependencies []string `json:"dependencies,omitempty"`
+
+	// Options are the build option flags that differ from the formula's
+	// defaults (e.g. "with-tls"), so a rebuild can reproduce the keg.
+	Options []string `json:"options,omitempty"`
 }
 
 // FileEntry records one file or symlink inside the keg.
@@ -57,7 +73,14 @@ type InstallMeta struct {
 	Platform       string
 	DownloadURL    string
 	DownloadSHA256 string
+	FormulaPath    string
+	HeadCommit     string
 	Dependencies   []string
+	Options        []string
+	Links          []LinkEntry
+	VersionScheme  int
+	Revision       int
+	BottleRebuild  int
 }
 
 // Save atomically writes the manifest to kegPath/.MANIFEST.json.
@@ -107,6 +130,20 @@ func Load(kegPath string) (*Manifest, error) {
 	return &m, nil
 }
 
+// SaveLinks replaces the links recorded in the manifest at kegPath. A keg
+// without a manifest is left alone.
+func SaveLinks(kegPath string, links []LinkEntry) error {
+	if !Exists(kegPath) {
+		return nil
+	}
+	m, err := Load(kegPath)
+	if err != nil {
+		return err
+	}
+	m.Links = links
+	return Save(m, kegPath)
+}
+
 // Exists returns true if a manifest exists for the given keg.
 func Exists(kegPath string) bool {
 	_, err := os.Stat(filepath.Join(kegPath, ManifestFile))
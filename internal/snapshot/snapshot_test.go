@@ -24,7 +24,12 @@ func TestCaptureAndSave(t *testing.T) {
 		Platform:       "darwin_arm64",
 		DownloadURL:    "https://example.com/mypkg-1.0.0.tar.gz",
 		DownloadSHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		FormulaPath:    "/src/mypkg.yaml",
+		HeadCommit:     "0123456789abcdef0123456789abcdef01234567",
+		Options:        []string{"with-tls"},
 		Dependencies:   []string{"dep1"},
+		Revision:       2,
+		BottleRebuild:  1,
 	}
 
 	m, err := Capture("mypkg", "1.0.0", keg, meta)
@@ -41,6 +46,18 @@ func TestCaptureAndSave(t *testing.T) {
 	if m.Platform != "darwin_arm64" {
 		t.Errorf("platform = %q, want %q", m.Platform, "darwin_arm64")
 	}
+	if m.FormulaPath != "/src/mypkg.yaml" {
+		t.Errorf("formula_path = %q, want %q", m.FormulaPath, "/src/mypkg.yaml")
+	}
+	if m.HeadCommit != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("head_commit = %q", m.HeadCommit)
+	}
+	if len(m.Options) != 1 || m.Options[0] != "with-tls" {
+		t.Errorf("options = %v, want [with-tls]", m.Options)
+	}
+	if m.Revision != 2 || m.BottleRebuild != 1 {
+		t.Errorf("revision = %d, bottle_rebuild = %d, want 2 and 1", m.Revision, m.BottleRebuild)
+	}
 	if m.KegSHA256 == "" {
 		t.Error("keg_sha256 should not be empty")
 	}
@@ -173,3 +190,37 @@ func TestVerify_Added(t *testing.T) {
 		t.Error("expected added files list to be non-empty")
 	}
 }
+
+func TestVerifyLinks(t *testing.T) {
+	keg := createTestKeg(t)
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "bin"), 0755)
+	os.MkdirAll(filepath.Join(root, "lib", "pkgconfig"), 0755)
+
+	m, err := Capture("mypkg", "1.0.0", keg, InstallMeta{})
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	Save(m, keg)
+	links := []LinkEntry{
+		{Src: "bin/mybin", Target: filepath.Join(keg, "bin", "mybin")},
+		{Src: "lib/pkgconfig/mypkg.pc", Target: filepath.Join(keg, "lib", "pkgconfig", "mypkg.pc")},
+		{Src: "bin/gone", Target: filepath.Join(keg, "bin", "gone")},
+	}
+	if err := SaveLinks(keg, links); err != nil {
+		t.Fatalf("SaveLinks: %v", err)
+	}
+	os.Symlink(links[0].Target, filepath.Join(root, "bin", "mybin"))
+	os.Symlink("/elsewhere/mypkg.pc", filepath.Join(root, "lib", "pkgconfig", "mypkg.pc"))
+
+	missing, hijacked, err := VerifyLinks(keg, root)
+	if err != nil {
+		t.Fatalf("VerifyLinks: %v", err)
+	}
+	if len(missing) != 1 || missing[0] != "bin/gone" {
+		t.Errorf("missing = %v, want [bin/gone]", missing)
+	}
+	if len(hijacked) != 1 || hijacked[0] != "lib/pkgconfig/mypkg.pc (-> /elsewhere/mypkg.pc)" {
+		t.Errorf("hijacked = %v", hijacked)
+	}
+}
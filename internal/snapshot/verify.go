@@ -113,3 +113,40 @@ func Verify(kegPath string) (*VerifyResult, error) {
 	result.OK = len(result.Missing) == 0 && len(result.Modified) == 0 && len(result.Added) == 0 && len(result.Errors) == 0
 	return result, nil
 }
+
+// VerifyLinks checks the links recorded in the manifest at kegPath
+// against the prefix at root. A link that no longer exists is missing;
+// one that now points somewhere else, or was replaced by a regular file,
+// is hijacked.
+func VerifyLinks(kegPath, root string) (missing, hijacked []string, err error) {
+	m, err := Load(kegPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load manifest: %w", err)
+	}
+	for _, l := range m.Links {
+		path := filepath.Join(root, l.Src)
+		info, err := os.Lstat(path)
+		if err != nil {
+			missing = append(missing, l.Src)
+			continue
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			hijacked = append(hijacked, fmt.Sprintf("%s (not a symlink)", l.Src))
+			continue
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			missing = append(missing, l.Src)
+			continue
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		if filepath.Clean(target) != l.Target {
+			hijacked = append(hijacked, fmt.Sprintf("%s (-> %s)", l.Src, target))
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(hijacked)
+	return missing, hijacked, nil
+}
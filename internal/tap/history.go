@@ -0,0 +1,117 @@
+package tap
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/homegrew/grew/internal/formula"
+)
+
+// FormulaVersion returns the formula f as it was when it described the
+// given version, taken from the git history of its tap. version matches
+// either the version or the package version (with _revision). When f
+// itself is at that version it is returned unchanged.
+//
+// Taps are cloned with --depth 1, so the first lookup fetches the full
+// history; 'grew update' keeps it from then on. The commit the formula is
+// read from is verified like an update (see CheckCommit). Taps installed
+// from the API have no history to look in.
+func FormulaVersion(f *formula.Formula, version string) (*formula.Formula, error) {
+	if f.Version == version || f.PkgVersion() == version {
+		return f, nil
+	}
+	if f.Path == "" {
+		return nil, fmt.Errorf("formula %q has no file to look up the history of", f.Name)
+	}
+	dir := filepath.Dir(f.Path)
+	top, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("no history for %s: its tap is not a git clone\n"+
+			"  Set HOMEGREW_NO_INSTALL_FROM_API=1 and run 'grew update' to clone the taps", f.Name)
+	}
+	top = strings.TrimSpace(top)
+	if isShallow(top) {
+		fmt.Printf("==> Fetching the history of %s\n", top)
+		fetch := exec.Command("git", "-C", top, "fetch", "--unshallow", "origin")
+		fetch.Stdout = os.Stdout
+		fetch.Stderr = os.Stderr
+		if err := fetch.Run(); err != nil {
+			return nil, fmt.Errorf("fetch tap history: %w", err)
+		}
+	}
+
+	realPath, err := filepath.EvalSymlinks(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	realTop, err := filepath.EvalSymlinks(top)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(realTop, realPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// With --name-only each commit is followed by the path the file had
+	// in it, which changes across renames.
+	out, err := git(top, "log", "--follow", "--format=commit %H", "--name-only", "--", filepath.ToSlash(rel))
+	if err != nil {
+		return nil, fmt.Errorf("read history of %s: %w", f.Name, err)
+	}
+	var commit string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if hash, ok := strings.CutPrefix(line, "commit "); ok {
+			commit = hash
+			continue
+		}
+		if line == "" || commit == "" {
+			continue
+		}
+		data, err := git(top, "show", commit+":"+line)
+		at := commit
+		commit = ""
+		if err != nil {
+			continue // the commit deleted the file
+		}
+		old, err := formula.Parse([]byte(data))
+		if err != nil {
+			continue
+		}
+		if old.Version == version || old.PkgVersion() == version {
+			if err := CheckCommit(top, at, TapVerifyMode()); err != nil {
+				return nil, err
+			}
+			old.Name = f.Name
+			old.Path = f.Path
+			return old, nil
+		}
+	}
+	return nil, fmt.Errorf("version %s of %s not found in the history of its tap", version, f.Name)
+}
+
+// isShallow reports whether the git clone at dir has truncated history.
+func isShallow(dir string) bool {
+	out, err := git(dir, "rev-parse", "--is-shallow-repository")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// git runs a git command in dir and returns its standard output.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,81 @@
+package tap
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/homegrew/grew/internal/formula"
+)
+
+func TestFormulaVersion(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Test",
+			"GIT_AUTHOR_EMAIL=test@test.com",
+			"GIT_COMMITTER_NAME=Test",
+			"GIT_COMMITTER_EMAIL=test@test.com",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %s", args, out)
+		}
+	}
+	coreDir := filepath.Join(dir, "core")
+	os.MkdirAll(coreDir, 0755)
+	path := filepath.Join(coreDir, "mypkg.yaml")
+	write := func(version string) {
+		t.Helper()
+		data := "name: mypkg\nversion: \"" + version + "\"\ndescription: test\nhomepage: https://example.com\nlicense: MIT\n" +
+			"url:\n  linux_amd64: https://example.com/mypkg\nsha256:\n  linux_amd64: \"" +
+			"0000000000000000000000000000000000000000000000000000000000000000\"\ninstall:\n  type: binary\n  binary_name: mypkg\n"
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q")
+	write("1.6")
+	run("add", ".")
+	run("commit", "-q", "-m", "mypkg 1.6")
+	write("1.7")
+	run("add", ".")
+	run("commit", "-q", "-m", "mypkg 1.7")
+
+	loader := &formula.Loader{TapDir: dir}
+	f, err := loader.LoadByName("mypkg")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	old, err := FormulaVersion(f, "1.6")
+	if err != nil {
+		t.Fatalf("FormulaVersion: %v", err)
+	}
+	if old.Version != "1.6" || old.Name != "mypkg" {
+		t.Errorf("got %s %s, want mypkg 1.6", old.Name, old.Version)
+	}
+	if same, err := FormulaVersion(f, "1.7"); err != nil || same != f {
+		t.Errorf("FormulaVersion(current) = %v, %v, want the formula itself", same, err)
+	}
+	if _, err := FormulaVersion(f, "1.5"); err == nil {
+		t.Error("expected error for a version that was never in the tap")
+	}
+
+	// The commit the old version is read from is verified like an update.
+	t.Setenv("HOMEGREW_TAP_VERIFY", "strict")
+	if _, err := FormulaVersion(f, "1.6"); err == nil || !strings.Contains(err.Error(), "not signed") {
+		t.Errorf("FormulaVersion with strict verification of an unsigned commit = %v, want a refusal", err)
+	}
+	if isShallow(dir) {
+		t.Error("isShallow reported a full clone as shallow")
+	}
+}
@@ -83,7 +83,13 @@ func (m *Manager) Update() (int, error) {
 		}
 
 		fmt.Printf("==> Updating taps...\n")
-		fetch := exec.Command("git", "-C", m.TapsDir, "fetch", "--depth", "1", "origin", "+refs/heads/main:refs/remotes/origin/main")
+		// A clone whose full history was fetched for an older formula
+		// version keeps it; --depth 1 would truncate it again.
+		args := []string{"-C", m.TapsDir, "fetch"}
+		if isShallow(m.TapsDir) {
+			args = append(args, "--depth", "1")
+		}
+		fetch := exec.Command("git", append(args, "origin", "+refs/heads/main:refs/remotes/origin/main")...)
 		fetch.Stdout = os.Stdout
 		fetch.Stderr = os.Stderr
 		if err := fetch.Run(); err != nil {
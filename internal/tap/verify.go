@@ -50,12 +50,18 @@ func TapVerifyMode() VerifyMode {
 //   - The tap must be a git clone (not API-fetched tarballs).
 //   - The signing key must be in the user's GPG/SSH allowed signers.
 func VerifyHeadSignature(repoDir string) error {
+	return VerifyCommitSignature(repoDir, "HEAD")
+}
+
+// VerifyCommitSignature checks whether commit, any revision git accepts,
+// in the git repository at repoDir has a valid GPG/SSH signature.
+func VerifyCommitSignature(repoDir, commit string) error {
 	gitDir := filepath.Join(repoDir, ".git")
 	if _, err := os.Stat(gitDir); err != nil {
 		return fmt.Errorf("not a git repository: %s", repoDir)
 	}
 
-	cmd := exec.Command("git", "-C", repoDir, "verify-commit", "HEAD")
+	cmd := exec.Command("git", "-C", repoDir, "verify-commit", commit)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("commit signature verification failed: %s", strings.TrimSpace(string(output)))
@@ -83,11 +89,18 @@ func VerifyTagSignature(repoDir, tag string) error {
 // current verification mode. Returns an error only in strict mode when
 // verification fails. In warn mode, prints a warning to stderr.
 func CheckAfterUpdate(repoDir string, mode VerifyMode) error {
+	return CheckCommit(repoDir, "HEAD", mode)
+}
+
+// CheckCommit verifies the signature of one tap commit, such as an older
+// commit a formula version is read from, the way CheckAfterUpdate does
+// for HEAD.
+func CheckCommit(repoDir, commit string, mode VerifyMode) error {
 	if mode == VerifyOff {
 		return nil
 	}
 
-	err := VerifyHeadSignature(repoDir)
+	err := VerifyCommitSignature(repoDir, commit)
 	if err == nil {
 		return nil
 	}
@@ -98,9 +111,9 @@ func CheckAfterUpdate(repoDir string, mode VerifyMode) error {
 		fmt.Fprintf(os.Stderr, "  Set HOMEGREW_TAP_VERIFY=strict to enforce signature verification.\n")
 		return nil
 	case VerifyStrict:
-		return fmt.Errorf("refusing unsigned tap update: %w\n"+
-			"  The HEAD commit of %s is not signed.\n"+
-			"  Set HOMEGREW_TAP_VERIFY=off to disable (not recommended).", err, repoDir)
+		return fmt.Errorf("refusing unsigned tap commit: %w\n"+
+			"  The %s commit of %s is not signed.\n"+
+			"  Set HOMEGREW_TAP_VERIFY=off to disable (not recommended).", err, commit, repoDir)
 	}
 	return nil
 }
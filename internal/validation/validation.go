@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 var SafeNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9@._\-\+]*$`)
@@ -27,3 +28,27 @@ func ValidateSHA256(s string) error {
 	}
 	return nil
 }
+
+// FamilyName returns the family a formula name belongs to: the part
+// before "@", or the name itself when it is unversioned.
+func FamilyName(name string) string {
+	if base, ver, ok := strings.Cut(name, "@"); ok && ver != "" {
+		return base
+	}
+	return name
+}
+
+// FamilyVersion returns the version part of a versioned formula name, or
+// "" when the name is unversioned.
+func FamilyVersion(name string) string {
+	if _, ver, ok := strings.Cut(name, "@"); ok {
+		return ver
+	}
+	return ""
+}
+
+// SameFamily reports whether two different formula names are members of
+// the same family.
+func SameFamily(a, b string) bool {
+	return a != b && FamilyName(a) == FamilyName(b)
+}
@@ -0,0 +1,25 @@
+package validation
+
+import "testing"
+
+func TestFamilyName(t *testing.T) {
+	tests := []struct {
+		name, family, version string
+	}{
+		{"openssl@3", "openssl", "3"},
+		{"python@3.12", "python", "3.12"},
+		{"openssl", "openssl", ""},
+		{"weird@", "weird@", ""},
+	}
+	for _, tt := range tests {
+		if got := FamilyName(tt.name); got != tt.family {
+			t.Errorf("FamilyName(%q) = %q, want %q", tt.name, got, tt.family)
+		}
+		if got := FamilyVersion(tt.name); got != tt.version {
+			t.Errorf("FamilyVersion(%q) = %q, want %q", tt.name, got, tt.version)
+		}
+	}
+	if !SameFamily("openssl", "openssl@3") || SameFamily("openssl@3", "openssl@3") || SameFamily("openssl@3", "libressl") {
+		t.Error("SameFamily gave a wrong answer")
+	}
+}
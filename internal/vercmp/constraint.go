@@ -0,0 +1,147 @@
+package vercmp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraint is a set of version requirements that must all hold, written
+// as comma-separated terms such as ">= 3.1, < 4".
+//
+// Supported operators are =, ==, !=, >, >=, <, <= and the pessimistic
+// operator ~>, which allows the last given segment to grow: "~> 1.3" means
+// ">= 1.3, < 2" and "~> 1.3.2" means ">= 1.3.2, < 1.4". A bare version
+// is treated as "=".
+type Constraint struct {
+	raw   string
+	terms []term
+}
+
+type term struct {
+	op  string
+	ver Version
+}
+
+// ParseConstraint parses a constraint expression. The empty string yields
+// a constraint that every version satisfies.
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: strings.TrimSpace(s)}
+	if c.raw == "" {
+		return c, nil
+	}
+	for _, part := range strings.Split(c.raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return Constraint{}, fmt.Errorf("empty term in constraint %q", s)
+		}
+		op, ver := splitOp(part)
+		if ver == "" {
+			return Constraint{}, fmt.Errorf("constraint %q: operator %q has no version", s, op)
+		}
+		if strings.ContainsAny(ver, " \t<>=!~") {
+			return Constraint{}, fmt.Errorf("constraint %q: invalid version %q", s, ver)
+		}
+		if op == "~>" {
+			upper, err := pessimisticBound(ver)
+			if err != nil {
+				return Constraint{}, fmt.Errorf("constraint %q: %w", s, err)
+			}
+			c.terms = append(c.terms, term{op: ">=", ver: Parse(ver)}, term{op: "<", ver: Parse(upper)})
+			continue
+		}
+		c.terms = append(c.terms, term{op: op, ver: Parse(ver)})
+	}
+	return c, nil
+}
+
+// String returns the constraint as written.
+func (c Constraint) String() string {
+	return c.raw
+}
+
+// IsEmpty reports whether the constraint accepts every version.
+func (c Constraint) IsEmpty() bool {
+	return len(c.terms) == 0
+}
+
+// Check reports whether version, an upstream version, satisfies every
+// term of the constraint. Use Allows for keg versions.
+func (c Constraint) Check(version string) bool {
+	return c.Allows(Parse(version))
+}
+
+// Allows reports whether v satisfies every term of the constraint. The
+// formula revision and version_scheme of v are ignored, since constraints
+// are on upstream versions.
+func (c Constraint) Allows(v Version) bool {
+	v.Revision, v.Scheme = 0, 0
+	for _, t := range c.terms {
+		cmp := v.Compare(t.ver)
+		var ok bool
+		switch t.op {
+		case "=", "==":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// splitOp separates a leading comparison operator from the version.
+func splitOp(s string) (op, ver string) {
+	for _, candidate := range []string{"~>", ">=", "<=", "==", "!=", ">", "<", "="} {
+		if strings.HasPrefix(s, candidate) {
+			return candidate, strings.TrimSpace(s[len(candidate):])
+		}
+	}
+	return "=", s
+}
+
+// pessimisticBound returns the exclusive upper bound for "~> ver". The
+// last segment is dropped and the one before it bumped, so ~> 1.3 allows
+// up to 2. With a pre-release tag the segment carrying the tag is bumped
+// instead, so ~> 1.3-rc allows up to 1.4.
+func pessimisticBound(ver string) (string, error) {
+	fields := strings.Split(ver, ".")
+	nums := make([]int, 0, len(fields))
+	tagged := false
+	for _, f := range fields {
+		digits := len(f) - len(strings.TrimLeft(f, "0123456789"))
+		if digits > 0 {
+			n, err := strconv.Atoi(f[:digits])
+			if err != nil {
+				return "", fmt.Errorf("~> needs a numeric version, got %q", ver)
+			}
+			nums = append(nums, n)
+		}
+		if digits < len(f) {
+			tagged = true
+			break
+		}
+	}
+	if len(nums) == 0 {
+		return "", fmt.Errorf("~> needs a numeric version, got %q", ver)
+	}
+	if len(nums) > 1 && !tagged {
+		nums = nums[:len(nums)-1]
+	}
+	nums[len(nums)-1]++
+	parts := make([]string, len(nums))
+	for i, n := range nums {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, "."), nil
+}
@@ -0,0 +1,63 @@
+package vercmp
+
+import "testing"
+
+func TestConstraint_Check(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"", "0.1", true},
+		{">= 3.1", "3.1", true},
+		{">= 3.1", "3.0.9", false},
+		{">= 3.1, < 4", "3.9", true},
+		{">= 3.1, < 4", "4.0", false},
+		{"~> 1.3", "1.3.0", true},
+		{"~> 1.3", "1.9", true},
+		{"~> 1.3", "2.0", false},
+		{"~> 1.3.2", "1.3.9", true},
+		{"~> 1.3.2", "1.4", false},
+		{"~> 2", "2.9", true},
+		{"~> 2", "3.0", false},
+		{"~> 1.3-rc", "1.3", true},
+		{"~> 1.3-rc", "1.3.9", true},
+		{"~> 1.3-rc", "1.4", false},
+		{"~> 1.3.beta", "1.4", false},
+		{"1.2", "1.2.0", true},
+		{"= 1.2", "1.3", false},
+		{"!= 1.2", "1.3", true},
+		{"> 1.2", "1.2", false},
+		{"<= 1.2", "1.2-rc1", true},
+		{">= 1.84.1", "1_84_1", true},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraint(%q): %v", tt.constraint, err)
+		}
+		if got := c.Check(tt.version); got != tt.want {
+			t.Errorf("%q.Check(%q) = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestConstraint_AllowsIgnoresRevision(t *testing.T) {
+	eq, _ := ParseConstraint("= 1.2")
+	gt, _ := ParseConstraint("> 1.2")
+	v := ParseKeg("1.2_3", 3).WithScheme(1)
+	if !eq.Allows(v) {
+		t.Errorf("= 1.2 should allow 1.2 at revision 3")
+	}
+	if gt.Allows(v) {
+		t.Errorf("> 1.2 should not allow 1.2 at revision 3")
+	}
+}
+
+func TestParseConstraint_Invalid(t *testing.T) {
+	for _, s := range []string{">=", ">= 1,", "~> beta", ">= 1 2"} {
+		if _, err := ParseConstraint(s); err == nil {
+			t.Errorf("ParseConstraint(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// Package vercmp orders package version strings.
+//
+// Versions are split into alternating numeric and alphabetic segments;
+// '.', '-' and '_' only separate segments. The rules, in order:
+//
+//   - An optional "N:" prefix is an epoch and outranks everything else.
+//   - Numeric segments compare numerically, so 1.10 > 1.9.
+//   - Pre-release words (dev, alpha, beta, pre, preview, rc) sort before
+//     the release they precede: 1.0-rc1 < 1.0.
+//   - Any other letters are post-release suffixes: 1.1.1a > 1.1.1.
+//   - A '~' sorts before anything, including the end of the version,
+//     as in Debian: 1.0~beta1 < 1.0.
+//   - Everything after the first '+' is build metadata. It only breaks
+//     ties, and a version with metadata sorts after one without:
+//     1.0+2 > 1.0+1 > 1.0.
+//   - Trailing zero segments are insignificant: 1.0 == 1.0.0.
+package vercmp
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokTilde tokenKind = iota
+	tokPre
+	tokAlpha
+	tokNum
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// preRank orders the recognised pre-release words.
+var preRank = map[string]int{
+	"dev":     0,
+	"alpha":   1,
+	"beta":    2,
+	"pre":     3,
+	"preview": 3,
+	"rc":      4,
+}
+
+// Version is a parsed version string. The zero value sorts before every
+// non-empty version.
+type Version struct {
+	Scheme   int // formula version_scheme; outranks the epoch
+	Epoch    int
+	Revision int // formula revision; only breaks ties
+	raw      string
+	main     []token
+	build    []token
+	hasTag   bool // true if a '+' build suffix was present
+}
+
+// Parse parses s. It never fails: unrecognised characters act as
+// separators, so any string yields a usable ordering.
+func Parse(s string) Version {
+	v := Version{raw: s}
+	rest := s
+	if i := strings.IndexByte(rest, ':'); i > 0 {
+		if n, err := strconv.Atoi(rest[:i]); err == nil && n >= 0 {
+			v.Epoch = n
+			rest = rest[i+1:]
+		}
+	}
+	if i := strings.IndexByte(rest, '+'); i >= 0 {
+		v.build = tokenize(rest[i+1:])
+		v.hasTag = true
+		rest = rest[:i]
+	}
+	v.main = tokenize(rest)
+	return v
+}
+
+// ParsePkg parses a keg version: a version optionally followed by "_" and
+// the formula revision, e.g. "1.7.1_2". The revision is guessed from the
+// name (see SplitRevision); use ParseKeg when it is known.
+func ParsePkg(s string) Version {
+	version, revision := SplitRevision(s)
+	v := Parse(version)
+	v.Revision = revision
+	v.raw = s
+	return v
+}
+
+// ParseKeg parses a keg version whose formula revision is known, e.g.
+// from the keg manifest. Only a "_<revision>" suffix is stripped, so a
+// version such as "1_84_1" at revision 0 keeps all its segments.
+func ParseKeg(s string, revision int) Version {
+	v := Parse(TrimRevision(s, revision))
+	v.Revision = revision
+	v.raw = s
+	return v
+}
+
+// TrimRevision returns the version of a keg built at the given formula
+// revision, removing the "_<revision>" suffix the keg name carries.
+func TrimRevision(s string, revision int) string {
+	if revision <= 0 {
+		return s
+	}
+	return strings.TrimSuffix(s, "_"+strconv.Itoa(revision))
+}
+
+// SplitRevision splits a keg version into the version and the formula
+// revision. A version without a "_N" suffix (N a positive number without
+// leading zeros) has revision 0. Versions may themselves contain '_', so
+// this is only a guess for kegs whose revision is not recorded.
+func SplitRevision(s string) (string, int) {
+	i := strings.LastIndexByte(s, '_')
+	if i <= 0 || i == len(s)-1 || s[i+1] == '0' {
+		return s, 0
+	}
+	for j := i + 1; j < len(s); j++ {
+		if !isDigit(s[j]) {
+			return s, 0
+		}
+	}
+	n, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return s, 0
+	}
+	return s[:i], n
+}
+
+// WithRevision returns a copy of v carrying the given formula revision.
+func (v Version) WithRevision(revision int) Version {
+	v.Revision = revision
+	return v
+}
+
+// WithScheme returns a copy of v carrying the given version_scheme.
+func (v Version) WithScheme(scheme int) Version {
+	v.Scheme = scheme
+	return v
+}
+
+// String returns the original version string.
+func (v Version) String() string {
+	return v.raw
+}
+
+// Compare returns -1, 0 or 1 depending on whether v sorts before, equal
+// to, or after o.
+func (v Version) Compare(o Version) int {
+	if c := cmpInt(v.Scheme, o.Scheme); c != 0 {
+		return c
+	}
+	if c := cmpInt(v.Epoch, o.Epoch); c != 0 {
+		return c
+	}
+	if c := compareTokens(v.main, o.main); c != 0 {
+		return c
+	}
+	switch {
+	case v.hasTag && !o.hasTag:
+		return 1
+	case !v.hasTag && o.hasTag:
+		return -1
+	}
+	if c := compareTokens(v.build, o.build); c != 0 {
+		return c
+	}
+	return cmpInt(v.Revision, o.Revision)
+}
+
+// Compare parses and compares two version strings.
+func Compare(a, b string) int {
+	return Parse(a).Compare(Parse(b))
+}
+
+// Less reports whether a sorts before b.
+func Less(a, b string) bool {
+	return Compare(a, b) < 0
+}
+
+// Sort sorts versions in ascending order. Ties keep their input order.
+func Sort(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return Less(versions[i], versions[j])
+	})
+}
+
+// SortPkg sorts keg versions (see ParsePkg) in ascending order. Ties keep
+// their input order.
+func SortPkg(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return ParsePkg(versions[i]).Compare(ParsePkg(versions[j])) < 0
+	})
+}
+
+// Latest returns the highest version in versions, or "" if it is empty.
+func Latest(versions []string) string {
+	var best string
+	for i, v := range versions {
+		if i == 0 || Less(best, v) {
+			best = v
+		}
+	}
+	return best
+}
+
+func tokenize(s string) []token {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '~':
+			toks = append(toks, token{kind: tokTilde})
+			i++
+		case isDigit(c):
+			j := i
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			toks = append(toks, token{kind: tokNum, text: strings.TrimLeft(s[i:j], "0")})
+			i = j
+		case isLetter(c):
+			j := i
+			for j < len(s) && isLetter(s[j]) {
+				j++
+			}
+			word := strings.ToLower(s[i:j])
+			kind := tokAlpha
+			if _, ok := preRank[word]; ok {
+				kind = tokPre
+			}
+			toks = append(toks, token{kind: kind, text: word})
+			i = j
+		default:
+			i++
+		}
+	}
+	return toks
+}
+
+func compareTokens(a, b []token) int {
+	for i := 0; ; i++ {
+		switch {
+		case i >= len(a) && i >= len(b):
+			return 0
+		case i >= len(a):
+			if isZero(b[i]) {
+				continue
+			}
+			return -endVersus(b[i])
+		case i >= len(b):
+			if isZero(a[i]) {
+				continue
+			}
+			return endVersus(a[i])
+		}
+		if c := compareToken(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+}
+
+// endVersus reports how a version that continues with t compares to one
+// that has already ended: extra numbers or suffix letters make it newer,
+// while a pre-release word or '~' makes it older.
+func endVersus(t token) int {
+	switch t.kind {
+	case tokTilde, tokPre:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// isZero reports whether t is a numeric zero, which is insignificant at
+// the end of a version (1.0 == 1.0.0).
+func isZero(t token) bool {
+	return t.kind == tokNum && t.text == ""
+}
+
+func compareToken(a, b token) int {
+	if a.kind != b.kind {
+		return cmpInt(int(a.kind), int(b.kind))
+	}
+	switch a.kind {
+	case tokNum:
+		if c := cmpInt(len(a.text), len(b.text)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.text, b.text)
+	case tokPre:
+		return cmpInt(preRank[a.text], preRank[b.text])
+	case tokAlpha:
+		return strings.Compare(a.text, b.text)
+	}
+	return 0
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,142 @@
+package vercmp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.0.0", 0},
+		{"1.9", "1.10", -1},
+		{"1.10", "1.9", 1},
+		{"2.0", "10.0", -1},
+		{"1.0.1", "1.0", 1},
+		{"01.2", "1.2", 0},
+		{"1.0-rc1", "1.0", -1},
+		{"1.0rc2", "1.0rc10", -1},
+		{"1.0-alpha", "1.0-beta", -1},
+		{"1.0-beta.2", "1.0-rc1", -1},
+		{"1.0-dev", "1.0-alpha", -1},
+		{"1.0-rc1", "0.9", 1},
+		{"1.1.1a", "1.1.1", 1},
+		{"1.1.1b", "1.1.1a", 1},
+		{"1.0~beta1", "1.0", -1},
+		{"1.0~beta1", "1.0~beta2", -1},
+		{"1.0~rc1", "1.0-rc1", -1},
+		{"1.0+1", "1.0", 1},
+		{"1.0+2", "1.0+1", 1},
+		{"1.0+10", "1.0.1", -1},
+		{"1:0.5", "2.0", 1},
+		{"1:0.5", "2:0.1", -1},
+		{"2.0", "2.0-RC1", 1},
+	}
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := Compare(tt.b, tt.a); got != -tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestCompare_Scheme(t *testing.T) {
+	older := Parse("2024.01").WithScheme(0)
+	newer := Parse("1.0").WithScheme(1)
+	if older.Compare(newer) != -1 {
+		t.Error("higher version_scheme should outrank the version string")
+	}
+	if newer.String() != "1.0" {
+		t.Errorf("String() = %q, want %q", newer.String(), "1.0")
+	}
+}
+
+func TestParsePkg(t *testing.T) {
+	tests := []struct {
+		in       string
+		version  string
+		revision int
+	}{
+		{"1.7.1_2", "1.7.1", 2},
+		{"1.7.1", "1.7.1", 0},
+		{"1_84_0", "1_84_0", 0},
+		{"1.0_rc1", "1.0_rc1", 0},
+		{"1.0_", "1.0_", 0},
+		{"HEAD-abc1234", "HEAD-abc1234", 0},
+	}
+	for _, tt := range tests {
+		v, r := SplitRevision(tt.in)
+		if v != tt.version || r != tt.revision {
+			t.Errorf("SplitRevision(%q) = %q, %d; want %q, %d", tt.in, v, r, tt.version, tt.revision)
+		}
+	}
+
+	if got := ParsePkg("1.0_1").Compare(ParsePkg("1.0")); got != 1 {
+		t.Errorf("1.0_1 vs 1.0 = %d, want 1", got)
+	}
+	if got := ParsePkg("1.0_1").Compare(ParsePkg("1.0.1")); got != -1 {
+		t.Errorf("1.0_1 vs 1.0.1 = %d, want -1", got)
+	}
+	if got := ParsePkg("1.0_2").Compare(Parse("1.0").WithRevision(2)); got != 0 {
+		t.Errorf("1.0_2 vs 1.0 revision 2 = %d, want 0", got)
+	}
+	if s := ParsePkg("1.0_2").String(); s != "1.0_2" {
+		t.Errorf("String() = %q, want 1.0_2", s)
+	}
+}
+
+func TestParseKeg(t *testing.T) {
+	// A version containing '_' is not mistaken for a revision when the
+	// revision is known.
+	if got := ParseKeg("1_84_1", 0).Compare(Parse("1_84_1")); got != 0 {
+		t.Errorf("1_84_1 revision 0 vs 1_84_1 = %d, want 0", got)
+	}
+	if got := ParseKeg("1_84_1", 0).Compare(Parse("1.84.1")); got != 0 {
+		t.Errorf("1_84_1 revision 0 vs 1.84.1 = %d, want 0", got)
+	}
+	v := ParseKeg("1_84_1_2", 2)
+	if got := v.Compare(Parse("1.84.1").WithRevision(2)); got != 0 {
+		t.Errorf("1_84_1_2 revision 2 vs 1.84.1 revision 2 = %d, want 0", got)
+	}
+	if s := v.String(); s != "1_84_1_2" {
+		t.Errorf("String() = %q, want 1_84_1_2", s)
+	}
+	if got := TrimRevision("1_84_1", 0); got != "1_84_1" {
+		t.Errorf("TrimRevision(1_84_1, 0) = %q", got)
+	}
+	if got := TrimRevision("1.7.1_2", 2); got != "1.7.1" {
+		t.Errorf("TrimRevision(1.7.1_2, 2) = %q", got)
+	}
+}
+
+func TestSortPkg(t *testing.T) {
+	got := []string{"1.0.1", "1.0_1", "1.0", "1.0_10", "1.0_2"}
+	SortPkg(got)
+	want := []string{"1.0", "1.0_1", "1.0_2", "1.0_10", "1.0.1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortPkg = %v, want %v", got, want)
+	}
+}
+
+func TestSort(t *testing.T) {
+	got := []string{"1.10", "1.9", "1.9-rc1", "1.2", "1.10+1"}
+	Sort(got)
+	want := []string{"1.2", "1.9-rc1", "1.9", "1.10", "1.10+1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Sort = %v, want %v", got, want)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	if got := Latest([]string{"1.9", "1.10", "1.10-rc1"}); got != "1.10" {
+		t.Errorf("Latest = %q, want %q", got, "1.10")
+	}
+	if got := Latest(nil); got != "" {
+		t.Errorf("Latest(nil) = %q, want empty", got)
+	}
+}